@@ -1,118 +1,707 @@
 package client
 
 import (
-    "bufio"
-    "encoding/json"
-    "fmt"
-    "net"
-    "time"
-    "go-ssh/proto"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ssh/proto"
 )
 
+// errTimeout 等待响应超时
+var errTimeout = errors.New("response timeout")
+
 // APIClient 轻量级后端通信客户端。
+// 所有请求复用同一条持久连接：每个请求携带递增 ID，多个 goroutine 可并发调用，
+// 响应按 ID 分发；连接断开后下次调用自动重连，仅幂等消息会被自动重试。
 type APIClient struct {
-    Addr         string
-    DialTimeout  time.Duration
-    ReadTimeout  time.Duration
-    WriteTimeout time.Duration
-    Retries      int
+	Addr         string // TCP 地址或 "unix:/path/to/sock"
+	Token        string // IPC 共享令牌；为空时从 TokenPath 读取
+	TokenPath    string // 令牌文件，默认为数据目录下的 proto.TokenFileName
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration // 单个请求等待响应的超时，默认比 proto.RequestTimeout 多 1s
+	WriteTimeout time.Duration
+	Retries      int
+
+	mu        sync.Mutex
+	mc        *muxConn
+	subs      map[*EventStream]struct{}
+	restoring bool // 后台正在恢复订阅
+	retry     bool // 恢复进行期间又有订阅失败，成功后需再订阅一轮
+	seq       atomic.Uint64
+	server    *proto.HelloResponse // 最近一次 hello 握手得到的后端能力
 }
 
 // Send 发送统一的协议消息并返回标准响应，包含超时与重试机制。
 func (c *APIClient) Send(msg proto.Message) (proto.Response, error) {
-    start := time.Now()
-    // 默认参数
-    dialTimeout := c.DialTimeout
-    if dialTimeout == 0 {
-        dialTimeout = 2 * time.Second
-    }
-    readTimeout := c.ReadTimeout
-    if readTimeout == 0 {
-        readTimeout = 3 * time.Second
-    }
-    writeTimeout := c.WriteTimeout
-    if writeTimeout == 0 {
-        writeTimeout = 3 * time.Second
-    }
-    retries := c.Retries
-    if retries <= 0 {
-        retries = 3
-    }
-
-    var lastErr error
-    var respBytes []byte
-    for attempt := 0; attempt < retries; attempt++ {
-        conn, err := net.DialTimeout("tcp", c.Addr, dialTimeout)
-        if err != nil {
-            lastErr = err
-            time.Sleep(backoff(attempt))
-            continue
-        }
-        // 设置读写超时
-        _ = conn.SetDeadline(time.Now().Add(readTimeout))
-
-        // 编码并发送
-        b, err := json.Marshal(msg)
-        if err != nil {
-            _ = conn.Close()
-            return proto.Response{}, err
-        }
-        b = append(b, '\n')
-        if err := conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
-            _ = conn.Close()
-            return proto.Response{}, err
-        }
-        if _, err := conn.Write(b); err != nil {
-            lastErr = err
-            _ = conn.Close()
-            time.Sleep(backoff(attempt))
-            continue
-        }
-
-        // 读取响应
-        r := bufio.NewReader(conn)
-        respBytes, err = r.ReadBytes('\n')
-        _ = conn.Close()
-        if err != nil {
-            lastErr = err
-            time.Sleep(backoff(attempt))
-            continue
-        }
-        // 成功读到响应，跳出重试
-        break
-    }
-    if respBytes == nil {
-        return proto.Response{}, fmt.Errorf("request failed after %d retries: %w", retries, lastErr)
-    }
-
-    var out proto.Response
-    if err := json.Unmarshal(respBytes, &out); err != nil {
-        return proto.Response{}, err
-    }
-
-    // 统一日志输出
-    elapsed := time.Since(start)
-    if out.Ok {
-        fmt.Printf("[API] ok code=%d elapsed=%s type=%s\n", out.Code, elapsed, msg.Type)
-    } else {
-        fmt.Printf("[API] err code=%d msg=%s elapsed=%s type=%s\n", out.Code, out.Message, elapsed, msg.Type)
-    }
-    return out, nil
+	readTimeout := c.ReadTimeout
+	if readTimeout == 0 {
+		// 略长于后端的处理超时，使慢请求收到后端的 504 而不是在本地超时后被重试
+		readTimeout = proto.RequestTimeout + time.Second
+	}
+	return c.send(msg, readTimeout)
+}
+
+// send 在持久连接上完成一次请求，timeout 为等待响应的超时。
+// 拨号失败与写失败时请求未被后端处理，总是可以重试；
+// 请求已发出后连接中断或超时，仅幂等消息重试。
+func (c *APIClient) send(msg proto.Message, timeout time.Duration) (proto.Response, error) {
+	start := time.Now()
+	writeTimeout := c.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = 3 * time.Second
+	}
+	retries := c.Retries
+	if retries <= 0 {
+		retries = 3
+	}
+	msg.ID = strconv.FormatUint(c.seq.Add(1), 10)
+
+	var lastErr error
+	var f proto.Frame
+	attempt := 0
+	for ; attempt < retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff(attempt - 1))
+		}
+		mc, err := c.conn()
+		if err != nil {
+			lastErr = err
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				// 握手被拒（如令牌错误），重试无意义
+				attempt++
+				break
+			}
+			continue
+		}
+		var sent bool
+		f, sent, err = mc.roundTrip(msg, writeTimeout, timeout)
+		if err == nil {
+			lastErr = nil
+			break
+		}
+		lastErr = err
+		if sent && !proto.IsIdempotent(msg.Type) {
+			// 非幂等请求可能已被执行，不再重放
+			attempt++
+			break
+		}
+	}
+	if lastErr != nil {
+		return proto.Response{}, fmt.Errorf("request %s failed after %d attempts: %w", msg.Type, attempt, lastErr)
+	}
+	out := proto.Response{ID: f.ID, Code: f.Code, Ok: f.Ok, Message: f.Message}
+	if len(f.Data) > 0 {
+		out.Data = f.Data
+	}
+
+	// 统一日志输出
+	elapsed := time.Since(start)
+	if out.Ok {
+		fmt.Printf("[API] ok code=%d elapsed=%s type=%s id=%s\n", out.Code, elapsed, msg.Type, msg.ID)
+	} else {
+		fmt.Printf("[API] err code=%d msg=%s elapsed=%s type=%s id=%s\n", out.Code, out.Message, elapsed, msg.Type, msg.ID)
+	}
+	return out, nil
+}
+
+// Close 关闭持久连接（事件订阅随之中断，下次调用会重新建立）
+func (c *APIClient) Close() error {
+	c.mu.Lock()
+	mc := c.mc
+	c.mc = nil
+	c.mu.Unlock()
+	if mc == nil {
+		return nil
+	}
+	return mc.conn.Close()
+}
+
+// conn 返回可用的持久连接，必要时重新拨号
+func (c *APIClient) conn() (*muxConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mc != nil && !c.mc.closed() {
+		return c.mc, nil
+	}
+	conn, r, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	hello, err := c.hello(conn, r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c.server = hello
+	fmt.Printf("[API] connected to %s protocol=v%d\n", c.Addr, hello.ProtocolVersion)
+	mc := &muxConn{conn: conn, pending: make(map[string]chan proto.Frame), done: make(chan struct{})}
+	c.mc = mc
+	go func() {
+		mc.readLoop(r, c.dispatchEvent)
+		c.connLost(mc)
+	}()
+	return mc, nil
+}
+
+// dial 建立到后端的新连接并完成 auth 握手，返回连接及其读缓冲
+func (c *APIClient) dial() (net.Conn, *bufio.Reader, error) {
+	tok, err := c.token()
+	if err != nil {
+		return nil, nil, err
+	}
+	dialTimeout := c.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = 2 * time.Second
+	}
+	network, address := proto.SplitAddr(c.Addr)
+	conn, err := net.DialTimeout(network, address, dialTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, _ := json.Marshal(proto.AuthRequest{Token: tok})
+	b, _ := json.Marshal(proto.Message{Type: "auth", Data: data})
+	_ = conn.SetDeadline(time.Now().Add(dialTimeout))
+	if _, err := conn.Write(append(b, '\n')); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	r := bufio.NewReader(conn)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("auth handshake: %w", err)
+	}
+	var f proto.Frame
+	if err := json.Unmarshal(line, &f); err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	if !f.Ok {
+		conn.Close()
+		return nil, nil, &APIError{Code: f.Code, Message: f.Message}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return conn, r, nil
+}
+
+// hello 在读协程启动前同步完成版本协商，后端版本过旧时返回 CodeVersion 错误
+func (c *APIClient) hello(conn net.Conn, r *bufio.Reader) (*proto.HelloResponse, error) {
+	data, _ := json.Marshal(proto.HelloRequest{ProtocolVersion: proto.ProtocolVersion, Client: "go-ssh-client"})
+	b, _ := json.Marshal(proto.Message{Type: "hello", Data: data})
+	timeout := c.DialTimeout
+	if timeout == 0 {
+		timeout = 2 * time.Second
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	defer conn.SetDeadline(time.Time{})
+	if _, err := conn.Write(append(b, '\n')); err != nil {
+		return nil, err
+	}
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, fmt.Errorf("hello handshake: %w", err)
+	}
+	var f proto.Frame
+	if err := json.Unmarshal(line, &f); err != nil {
+		return nil, err
+	}
+	if !f.Ok {
+		return nil, &APIError{Code: f.Code, Message: f.Message}
+	}
+	var out proto.HelloResponse
+	if err := json.Unmarshal(f.Data, &out); err != nil {
+		return nil, err
+	}
+	if out.ProtocolVersion < proto.MinProtocolVersion {
+		msg := fmt.Sprintf("service protocol v%d is too old, client requires >= v%d", out.ProtocolVersion, proto.MinProtocolVersion)
+		return nil, &APIError{Code: proto.CodeVersion, Message: msg}
+	}
+	return &out, nil
+}
+
+// Supports 判断后端是否支持某消息类型；尚未连接时先建立连接以完成握手
+func (c *APIClient) Supports(msgType string) bool {
+	if _, err := c.conn(); err != nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.server == nil {
+		return false
+	}
+	for _, t := range c.server.Types {
+		if t == msgType {
+			return true
+		}
+	}
+	return false
+}
+
+// token 返回 IPC 令牌：优先 Token 字段，否则每次从令牌文件读取（后端可能稍后才生成）
+func (c *APIClient) token() (string, error) {
+	if c.Token != "" {
+		return c.Token, nil
+	}
+	path := c.TokenPath
+	if path == "" {
+		path = filepath.Join(proto.DefaultDataDir(), proto.TokenFileName)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read ipc token (is the service running?): %w", err)
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// muxConn 一条被多个请求共享的连接
+type muxConn struct {
+	conn    net.Conn
+	wmu     sync.Mutex // 串行化写
+	mu      sync.Mutex
+	pending map[string]chan proto.Frame
+	done    chan struct{}
+	err     error
+}
+
+// roundTrip 发送请求并等待同 ID 的响应；sent 表示请求是否已完整写出
+func (m *muxConn) roundTrip(msg proto.Message, writeTimeout, readTimeout time.Duration) (f proto.Frame, sent bool, err error) {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return f, false, err
+	}
+	ch := make(chan proto.Frame, 1)
+	m.mu.Lock()
+	if m.closed() {
+		m.mu.Unlock()
+		return f, false, m.err
+	}
+	m.pending[msg.ID] = ch
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.pending, msg.ID)
+		m.mu.Unlock()
+	}()
+
+	m.wmu.Lock()
+	_ = m.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	_, err = m.conn.Write(append(b, '\n'))
+	m.wmu.Unlock()
+	if err != nil {
+		// 半行数据后端无法解析，关闭连接后视为未发送
+		_ = m.conn.Close()
+		return f, false, err
+	}
+
+	timer := time.NewTimer(readTimeout)
+	defer timer.Stop()
+	select {
+	case f = <-ch:
+		return f, true, nil
+	case <-m.done:
+		return f, true, m.err
+	case <-timer.C:
+		return f, true, errTimeout
+	}
+}
+
+// readLoop 读取响应与事件：响应按 ID 投递给等待者，事件交给 onEvent
+func (m *muxConn) readLoop(r *bufio.Reader, onEvent func(proto.Frame)) {
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			m.mu.Lock()
+			m.err = fmt.Errorf("connection lost: %w", err)
+			close(m.done)
+			m.mu.Unlock()
+			_ = m.conn.Close()
+			return
+		}
+		var f proto.Frame
+		if err := json.Unmarshal(line, &f); err != nil {
+			fmt.Printf("[API] decode frame error: %v\n", err)
+			continue
+		}
+		if f.IsEvent() {
+			onEvent(f)
+			continue
+		}
+		m.mu.Lock()
+		ch, ok := m.pending[f.ID]
+		m.mu.Unlock()
+		if ok {
+			ch <- f
+		}
+	}
+}
+
+func (m *muxConn) closed() bool {
+	select {
+	case <-m.done:
+		return true
+	default:
+		return false
+	}
 }
 
 // Ping 封装的测试接口
 func (c *APIClient) Ping() (proto.Response, error) {
-    return c.Send(proto.Message{Type: "ping"})
+	return c.Send(proto.Message{Type: "ping"})
+}
+
+// APIError 后端返回 ok=false 时的错误，保留错误码便于前端区分处理
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("code=%d: %s", e.Code, e.Message)
+}
+
+// call 编码请求负载、发送并将响应 data 解码到 out（out 可为 nil）
+func (c *APIClient) call(msgType string, req any, out any) error {
+	return c.callTimeout(msgType, req, out, 0)
+}
+
+// callTimeout 同 call，timeout 为 0 时使用 ReadTimeout
+func (c *APIClient) callTimeout(msgType string, req any, out any, timeout time.Duration) error {
+	msg := proto.Message{Type: msgType}
+	if req != nil {
+		b, err := json.Marshal(req)
+		if err != nil {
+			return err
+		}
+		msg.Data = b
+	}
+	var resp proto.Response
+	var err error
+	if timeout > 0 {
+		resp, err = c.send(msg, timeout)
+	} else {
+		resp, err = c.Send(msg)
+	}
+	if err != nil {
+		return err
+	}
+	if !resp.Ok {
+		return &APIError{Code: resp.Code, Message: resp.Message}
+	}
+	raw, ok := resp.Data.(json.RawMessage)
+	if out == nil || !ok {
+		return nil
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// Call 按协议登记表发送类型化请求：Resp 必须与 msgType 登记的响应类型一致，
+// 无响应负载的类型使用 struct{}。类型不匹配属于调用方编码错误，在发送前返回。
+func Call[Resp any](c *APIClient, msgType string, req any) (Resp, error) {
+	return CallTimeout[Resp](c, msgType, req, 0)
+}
+
+// CallTimeout 同 Call，timeout 为 0 时使用 ReadTimeout
+func CallTimeout[Resp any](c *APIClient, msgType string, req any, timeout time.Duration) (Resp, error) {
+	var out Resp
+	spec, ok := proto.Lookup(msgType)
+	if !ok {
+		return out, fmt.Errorf("unregistered message type %q", msgType)
+	}
+	want := reflect.TypeFor[Resp]()
+	if spec.Response == nil {
+		if want != reflect.TypeFor[struct{}]() {
+			return out, fmt.Errorf("%s has no response payload, got %s", msgType, want)
+		}
+		return out, c.callTimeout(msgType, req, nil, timeout)
+	}
+	if want != spec.Response {
+		return out, fmt.Errorf("%s responds with %s, got %s", msgType, spec.Response, want)
+	}
+	err := c.callTimeout(msgType, req, &out, timeout)
+	return out, err
+}
+
+// ListProfiles 获取后端保存的全部连接配置（不含密码）
+func (c *APIClient) ListProfiles() ([]proto.Profile, error) {
+	out, err := Call[proto.ListProfilesResponse](c, "list_profiles", nil)
+	return out.Profiles, err
+}
+
+// GetConnection 获取指定 ID 的完整连接配置
+func (c *APIClient) GetConnection(id string) (proto.Profile, error) {
+	return Call[proto.Profile](c, "get_connection", proto.ProfileRequest{ID: id})
+}
+
+// SaveConnection 新增或更新连接配置，返回带 ID 的配置
+func (c *APIClient) SaveConnection(p proto.Profile) (proto.Profile, error) {
+	return Call[proto.Profile](c, "save_connection", p)
+}
+
+// DeleteConnection 删除指定 ID 的连接配置
+func (c *APIClient) DeleteConnection(id string) error {
+	_, err := Call[struct{}](c, "delete_connection", proto.ProfileRequest{ID: id})
+	return err
+}
+
+// Connect 请求后端建立（或复用）SSH 连接。
+// SSH 拨号与认证可能较慢，读超时至少放宽到 15s；交互认证需等待用户输入，放宽到 10 分钟。
+func (c *APIClient) Connect(req proto.ConnectRequest) (proto.ConnectResponse, error) {
+	timeout := c.ReadTimeout
+	if timeout < 15*time.Second {
+		timeout = 15 * time.Second
+	}
+	if req.Interactive {
+		timeout = 10 * time.Minute
+	}
+	return CallTimeout[proto.ConnectResponse](c, "connect", req, timeout)
+}
+
+// AuthAnswer 应答后端推送的 auth_prompt（口令、密码或 keyboard-interactive 问题）
+func (c *APIClient) AuthAnswer(req proto.AuthAnswerRequest) error {
+	_, err := Call[struct{}](c, "auth_answer", req)
+	return err
+}
+
+// Disconnect 请求后端关闭指定连接
+func (c *APIClient) Disconnect(id string) error {
+	_, err := Call[proto.ConnectResponse](c, "disconnect", proto.DisconnectRequest{ID: id})
+	return err
+}
+
+// ListConnections 获取后端当前持有的连接及状态
+func (c *APIClient) ListConnections() ([]proto.ConnectResponse, error) {
+	out, err := Call[proto.ListConnectionsResponse](c, "list_connections", nil)
+	return out.Connections, err
+}
+
+// CreateTunnel 在已建立的连接上创建 -L / -R / -D 端口转发，返回实际监听端口
+func (c *APIClient) CreateTunnel(req proto.TunnelRequest) (proto.TunnelInfo, error) {
+	return Call[proto.TunnelInfo](c, "create_tunnel", req)
+}
+
+// ListTunnels 获取端口转发及其统计；connID 为空时返回全部
+func (c *APIClient) ListTunnels(connID string) ([]proto.TunnelInfo, error) {
+	out, err := Call[proto.ListTunnelsResponse](c, "list_tunnels", proto.ListTunnelsRequest{ConnID: connID})
+	return out.Tunnels, err
+}
+
+// CloseTunnel 关闭端口转发，返回关闭时的统计
+func (c *APIClient) CloseTunnel(id string) (proto.TunnelInfo, error) {
+	return Call[proto.TunnelInfo](c, "close_tunnel", proto.TunnelIDRequest{ID: id})
+}
+
+// SFTPList 列出远端目录；path 为空时为用户主目录
+func (c *APIClient) SFTPList(connID, path string) (proto.SFTPListResponse, error) {
+	return Call[proto.SFTPListResponse](c, "sftp_list", proto.SFTPPathRequest{ConnID: connID, Path: path})
+}
+
+// SFTPStat 获取单个远端路径的信息（符号链接不跟随）
+func (c *APIClient) SFTPStat(connID, path string) (proto.FileEntry, error) {
+	return Call[proto.FileEntry](c, "sftp_stat", proto.SFTPPathRequest{ConnID: connID, Path: path})
+}
+
+// SFTPMkdir 创建远端目录
+func (c *APIClient) SFTPMkdir(req proto.SFTPMkdirRequest) (proto.FileEntry, error) {
+	return Call[proto.FileEntry](c, "sftp_mkdir", req)
+}
+
+// SFTPRename 重命名或移动远端文件
+func (c *APIClient) SFTPRename(req proto.SFTPRenameRequest) (proto.FileEntry, error) {
+	return Call[proto.FileEntry](c, "sftp_rename", req)
+}
+
+// SFTPRemove 删除远端文件或目录
+func (c *APIClient) SFTPRemove(req proto.SFTPRemoveRequest) error {
+	_, err := Call[struct{}](c, "sftp_remove", req)
+	return err
+}
+
+// SFTPChmod 修改远端文件权限，mode 为八进制字符串
+func (c *APIClient) SFTPChmod(connID, path, mode string) (proto.FileEntry, error) {
+	return Call[proto.FileEntry](c, "sftp_chmod", proto.SFTPChmodRequest{ConnID: connID, Path: path, Mode: mode})
+}
+
+// SFTPSymlink 在远端创建符号链接 link -> target
+func (c *APIClient) SFTPSymlink(connID, target, link string) (proto.FileEntry, error) {
+	return Call[proto.FileEntry](c, "sftp_symlink", proto.SFTPSymlinkRequest{ConnID: connID, Target: target, Link: link})
+}
+
+// StartTransfer 将上传或下载加入后端传输队列，进度经 transfer_progress 事件推送
+func (c *APIClient) StartTransfer(req proto.TransferRequest) (proto.TransferInfo, error) {
+	return Call[proto.TransferInfo](c, "start_transfer", req)
+}
+
+// ListTransfers 获取排队中、进行中与最近结束的传输；connID 为空时返回全部
+func (c *APIClient) ListTransfers(connID string) ([]proto.TransferInfo, error) {
+	out, err := Call[proto.ListTransfersResponse](c, "list_transfers", proto.ListTransfersRequest{ConnID: connID})
+	return out.Transfers, err
+}
+
+// CancelTransfer 取消传输，已传输的部分保留在目标处
+func (c *APIClient) CancelTransfer(id string) (proto.TransferInfo, error) {
+	return Call[proto.TransferInfo](c, "cancel_transfer", proto.TransferIDRequest{ID: id})
+}
+
+// ListProcesses 列出远端进程，排序与过滤由后端完成
+func (c *APIClient) ListProcesses(req proto.ListProcessesRequest) (proto.ListProcessesResponse, error) {
+	return Call[proto.ListProcessesResponse](c, "list_processes", req)
+}
+
+// SignalProcess 向远端进程发送信号，逐个返回结果
+func (c *APIClient) SignalProcess(connID string, pids []int, signal string) ([]proto.ProcessActionResult, error) {
+	out, err := Call[proto.ProcessActionResponse](c, "signal_process", proto.SignalProcessRequest{ConnID: connID, PIDs: pids, Signal: signal})
+	return out.Results, err
+}
+
+// ReniceProcess 调整远端进程的 nice 值，逐个返回结果
+func (c *APIClient) ReniceProcess(connID string, pids []int, nice int) ([]proto.ProcessActionResult, error) {
+	out, err := Call[proto.ProcessActionResponse](c, "renice_process", proto.ReniceRequest{ConnID: connID, PIDs: pids, Nice: nice})
+	return out.Results, err
+}
+
+// Execute 在远端执行一条命令并等待结束；等待时间随 req.TimeoutMs 放宽
+func (c *APIClient) Execute(req proto.ExecuteRequest) (proto.ExecuteResponse, error) {
+	timeout := proto.DefaultExecuteTimeout
+	if req.TimeoutMs > 0 {
+		timeout = min(time.Duration(req.TimeoutMs)*time.Millisecond, proto.MaxExecuteTimeout)
+	}
+	return CallTimeout[proto.ExecuteResponse](c, "execute", req, timeout+10*time.Second)
+}
+
+// MultiExecute 在多台主机上执行同一命令，立即返回任务 ID；结果经 execute_result 事件推送
+func (c *APIClient) MultiExecute(req proto.MultiExecuteRequest) (proto.MultiExecuteResponse, error) {
+	return Call[proto.MultiExecuteResponse](c, "multi_execute", req)
+}
+
+// CancelMultiExecute 取消进行中的批量执行任务
+func (c *APIClient) CancelMultiExecute(id string) (proto.MultiExecuteResponse, error) {
+	return Call[proto.MultiExecuteResponse](c, "cancel_multi_execute", proto.CancelMultiExecuteRequest{ID: id})
+}
+
+// ListRecordings 列出会话录像，connID 为空时返回全部
+func (c *APIClient) ListRecordings(connID string) ([]proto.RecordingInfo, error) {
+	out, err := Call[proto.ListRecordingsResponse](c, "list_recordings", proto.ListRecordingsRequest{ConnID: connID})
+	return out.Recordings, err
+}
+
+// GetRecording 分块读取整个录像文件（asciicast v2 文本）
+func (c *APIClient) GetRecording(id string) (proto.RecordingInfo, []byte, error) {
+	var data []byte
+	for {
+		out, err := CallTimeout[proto.GetRecordingResponse](c, "get_recording",
+			proto.GetRecordingRequest{ID: id, Offset: int64(len(data))}, 30*time.Second)
+		if err != nil {
+			return proto.RecordingInfo{}, nil, err
+		}
+		data = append(data, out.Data...)
+		if out.EOF || len(out.Data) == 0 {
+			return out.Recording, data, nil
+		}
+	}
+}
+
+// ListHostKeys 获取后端管理的与只读的 known_hosts 条目
+func (c *APIClient) ListHostKeys() ([]proto.HostKeyEntry, error) {
+	out, err := Call[proto.ListHostKeysResponse](c, "list_host_keys", nil)
+	return out.Keys, err
+}
+
+// TrustHostKey 将主机密钥写入后端管理的 known_hosts，返回写入的条目
+func (c *APIClient) TrustHostKey(req proto.TrustHostKeyRequest) (proto.HostKeyEntry, error) {
+	return Call[proto.HostKeyEntry](c, "trust_host_key", req)
+}
+
+// RemoveHostKey 删除后端管理的 known_hosts 条目，返回删除数量
+func (c *APIClient) RemoveHostKey(req proto.RemoveHostKeyRequest) (int, error) {
+	out, err := Call[proto.RemoveHostKeyResponse](c, "remove_host_key", req)
+	return out.Removed, err
+}
+
+// VaultStatus 获取凭据库状态
+func (c *APIClient) VaultStatus() (proto.VaultStatus, error) {
+	return Call[proto.VaultStatus](c, "vault_status", nil)
+}
+
+// VaultInit 设置主密码并创建凭据库
+func (c *APIClient) VaultInit(password string) (proto.VaultStatus, error) {
+	return Call[proto.VaultStatus](c, "vault_init", proto.VaultPasswordRequest{Password: password})
+}
+
+// VaultUnlock 以主密码解锁凭据库，密码错误时返回 code=403 的 APIError
+func (c *APIClient) VaultUnlock(password string) (proto.VaultStatus, error) {
+	return Call[proto.VaultStatus](c, "vault_unlock", proto.VaultPasswordRequest{Password: password})
+}
+
+// VaultLock 立即锁定凭据库
+func (c *APIClient) VaultLock() (proto.VaultStatus, error) {
+	return Call[proto.VaultStatus](c, "vault_lock", nil)
+}
+
+// VaultChangeMaster 修改主密码
+func (c *APIClient) VaultChangeMaster(oldPassword, newPassword string) (proto.VaultStatus, error) {
+	return Call[proto.VaultStatus](c, "vault_change_master", proto.VaultChangeMasterRequest{Old: oldPassword, New: newPassword})
+}
+
+// ListKeys 获取受管密钥目录中的密钥
+func (c *APIClient) ListKeys() ([]proto.KeyInfo, error) {
+	out, err := Call[proto.ListKeysResponse](c, "list_keys", nil)
+	return out.Keys, err
+}
+
+// GenerateKey 生成密钥对；大位数 RSA 较慢，读超时放宽到 2 分钟
+func (c *APIClient) GenerateKey(req proto.GenerateKeyRequest) (proto.KeyInfo, error) {
+	return CallTimeout[proto.KeyInfo](c, "generate_key", req, 2*time.Minute)
+}
+
+// ImportKey 导入已有私钥（PEM 内容或本机路径）
+func (c *APIClient) ImportKey(req proto.ImportKeyRequest) (proto.KeyInfo, error) {
+	return Call[proto.KeyInfo](c, "import_key", req)
+}
+
+// ExportPublicKey 导出 authorized_keys 格式的公钥
+func (c *APIClient) ExportPublicKey(name string) (string, error) {
+	out, err := Call[proto.PublicKeyResponse](c, "export_public_key", proto.KeyRequest{Name: name})
+	return out.PublicKey, err
+}
+
+// DeleteKey 删除受管密钥（私钥与公钥文件）
+func (c *APIClient) DeleteKey(name string) error {
+	_, err := Call[struct{}](c, "delete_key", proto.KeyRequest{Name: name})
+	return err
+}
+
+// InstallPublicKey 经已建立的连接把公钥追加到远端 authorized_keys，已存在时返回 false
+func (c *APIClient) InstallPublicKey(connID, name string) (bool, error) {
+	out, err := Call[proto.InstallKeyResponse](c, "install_public_key", proto.InstallKeyRequest{ConnID: connID, Name: name})
+	return out.Installed, err
+}
+
+// Shutdown 请求后端优雅退出：后端先应答，再停止接受连接并关闭全部会话
+func (c *APIClient) Shutdown(reason string) error {
+	_, err := Call[struct{}](c, "shutdown", proto.ShutdownRequest{Reason: reason})
+	return err
 }
 
 // 简单指数退避
 func backoff(attempt int) time.Duration {
-    if attempt <= 0 {
-        return 100 * time.Millisecond
-    }
-    d := time.Duration(1<<attempt) * 100 * time.Millisecond
-    if d > 2*time.Second {
-        d = 2 * time.Second
-    }
-    return d
-}
\ No newline at end of file
+	if attempt <= 0 {
+		return 100 * time.Millisecond
+	}
+	d := time.Duration(1<<attempt) * 100 * time.Millisecond
+	if d > 2*time.Second {
+		d = 2 * time.Second
+	}
+	return d
+}
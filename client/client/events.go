@@ -0,0 +1,172 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// EventStream 一个事件订阅：后端推送的事件通过回调分发。
+// 所有订阅共享 APIClient 的持久连接，连接断开后自动重连并重新订阅。
+type EventStream struct {
+	c       *APIClient
+	names   map[string]bool // 为空表示全部
+	onEvent func(proto.Frame)
+	done    chan struct{}
+	once    sync.Once
+}
+
+// Subscribe 订阅事件（names 为空表示全部）。
+// onEvent 在读协程中依次调用，不可阻塞，UI 更新需自行切回主线程。
+// 返回前向后端订阅一次；后端尚未就绪（如冷启动时仍在拉起）时订阅保持登记，
+// 由后台按退避重试直至成功，期间的事件不会补发。
+func (c *APIClient) Subscribe(names []string, onEvent func(proto.Frame)) *EventStream {
+	s := &EventStream{c: c, onEvent: onEvent, done: make(chan struct{})}
+	if len(names) > 0 {
+		s.names = make(map[string]bool, len(names))
+		for _, n := range names {
+			s.names[n] = true
+		}
+	}
+	c.mu.Lock()
+	if c.subs == nil {
+		c.subs = make(map[*EventStream]struct{})
+	}
+	c.subs[s] = struct{}{}
+	c.mu.Unlock()
+
+	if err := c.resubscribe(); err != nil {
+		fmt.Printf("[API] subscribe events=%v failed, retrying in background: %v\n", names, err)
+		go c.restore()
+		return s
+	}
+	fmt.Printf("[API] subscribed events=%v\n", names)
+	return s
+}
+
+// Close 取消订阅
+func (s *EventStream) Close() error {
+	var err error
+	s.once.Do(func() {
+		s.c.mu.Lock()
+		delete(s.c.subs, s)
+		s.c.mu.Unlock()
+		close(s.done)
+		err = s.c.resubscribe()
+	})
+	return err
+}
+
+// Done 在订阅被取消时关闭
+func (s *EventStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// resubscribe 按当前全部订阅的并集向后端（重新）订阅，无订阅时取消订阅
+func (c *APIClient) resubscribe() error {
+	c.mu.Lock()
+	if len(c.subs) == 0 {
+		c.mu.Unlock()
+		return c.call("unsubscribe", nil, nil)
+	}
+	all := false
+	union := map[string]bool{}
+	for s := range c.subs {
+		if s.names == nil {
+			all = true
+		}
+		for n := range s.names {
+			union[n] = true
+		}
+	}
+	c.mu.Unlock()
+
+	var req proto.SubscribeRequest
+	if !all {
+		for n := range union {
+			req.Events = append(req.Events, n)
+		}
+		sort.Strings(req.Events)
+	}
+	return c.call("subscribe", req, nil)
+}
+
+// dispatchEvent 将事件分发给匹配的订阅者
+func (c *APIClient) dispatchEvent(f proto.Frame) {
+	c.mu.Lock()
+	subs := make([]*EventStream, 0, len(c.subs))
+	for s := range c.subs {
+		if s.names == nil || s.names[f.Event] {
+			subs = append(subs, s)
+		}
+	}
+	c.mu.Unlock()
+	for _, s := range subs {
+		if s.onEvent != nil {
+			s.onEvent(f)
+		}
+	}
+}
+
+// connLost 在持久连接 mc 断开时调用：存在订阅时后台重连并恢复订阅。
+// 主动 Close 或连接已被替换时不做处理。
+func (c *APIClient) connLost(mc *muxConn) {
+	c.mu.Lock()
+	current := c.mc == mc
+	c.mu.Unlock()
+	if !current {
+		return
+	}
+	fmt.Println("[API] connection lost, restoring event subscriptions...")
+	c.restore()
+}
+
+// restore 按退避重试 resubscribe，直至成功或订阅全部取消。
+// 已有恢复在进行时只做标记，由进行中的恢复在成功后再订阅一轮
+func (c *APIClient) restore() {
+	c.mu.Lock()
+	n := len(c.subs)
+	busy := c.restoring
+	if busy {
+		c.retry = true
+	} else if n > 0 {
+		c.restoring = true
+	}
+	c.mu.Unlock()
+	if n == 0 || busy {
+		return
+	}
+	defer func() {
+		c.mu.Lock()
+		c.restoring = false
+		c.mu.Unlock()
+	}()
+	for attempt := 0; ; attempt++ {
+		c.mu.Lock()
+		n = len(c.subs)
+		c.retry = false
+		c.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if err := c.resubscribe(); err == nil {
+			c.mu.Lock()
+			again := c.retry
+			c.mu.Unlock()
+			if !again {
+				fmt.Println("[API] event subscriptions restored")
+				return
+			}
+			attempt = -1
+			continue
+		}
+		d := backoff(attempt)
+		if attempt > 5 {
+			d = 5 * time.Second
+		}
+		time.Sleep(d)
+	}
+}
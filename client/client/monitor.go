@@ -0,0 +1,129 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// MonitorStream 为后端 monitor_subscribe 会话流的前端句柄，独占一条 IPC 连接，
+// 每次采样在读协程中回调 onSample
+type MonitorStream struct {
+	ConnID   string
+	Interval time.Duration
+
+	conn net.Conn
+	wmu  sync.Mutex
+
+	done   chan struct{}
+	status proto.ExitStatus
+}
+
+// Monitor 订阅 req.ConnID 对应连接的系统监控；onSample 在读协程中调用，更新界面需经 fyne.Do
+func (c *APIClient) Monitor(req proto.MonitorRequest, onSample func(proto.MonitorSample)) (*MonitorStream, error) {
+	conn, r, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	b, _ := json.Marshal(proto.Message{Type: "monitor_subscribe", Data: data})
+	_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	if _, err := conn.Write(append(b, '\n')); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// 首行为标准响应，之后连接切换为会话流
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var resp struct {
+		proto.Response
+		Data proto.MonitorResponse `json:"data"`
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !resp.Ok {
+		conn.Close()
+		return nil, &APIError{Code: resp.Code, Message: resp.Message}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	fmt.Printf("[API] monitor subscribed conn=%s interval=%dms\n", req.ConnID, resp.Data.IntervalMs)
+
+	s := &MonitorStream{
+		ConnID:   resp.Data.ConnID,
+		Interval: time.Duration(resp.Data.IntervalMs) * time.Millisecond,
+		conn:     conn,
+		done:     make(chan struct{}),
+	}
+	go s.readLoop(r, onSample)
+	return s, nil
+}
+
+// readLoop 分发后端推送的采样与结束消息
+func (s *MonitorStream) readLoop(r *bufio.Reader, onSample func(proto.MonitorSample)) {
+	defer close(s.done)
+	defer s.conn.Close()
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			s.status = proto.ExitStatus{Code: -1, Error: err.Error()}
+			return
+		}
+		var msg proto.Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case proto.StreamSample:
+			var sample proto.MonitorSample
+			if json.Unmarshal(msg.Data, &sample) == nil && onSample != nil {
+				onSample(sample)
+			}
+		case proto.StreamExit:
+			_ = json.Unmarshal(msg.Data, &s.status)
+			fmt.Printf("[API] monitor ended conn=%s code=%d %s\n", s.ConnID, s.status.Code, s.status.Error)
+			return
+		}
+	}
+}
+
+// Close 请求后端停止采样并关闭连接
+func (s *MonitorStream) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	if b, err := json.Marshal(proto.Message{Type: proto.StreamClose}); err == nil {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+		_, _ = s.conn.Write(append(b, '\n'))
+	}
+	return s.conn.Close()
+}
+
+// Done 在订阅结束（主动关闭、连接断开或后端结束）时关闭
+func (s *MonitorStream) Done() <-chan struct{} {
+	return s.done
+}
+
+// ExitStatus 返回结束原因，仅在 Done 关闭后有效
+func (s *MonitorStream) ExitStatus() proto.ExitStatus {
+	<-s.done
+	return s.status
+}
@@ -0,0 +1,8 @@
+//go:build !unix && !windows
+
+package client
+
+// processAlive 在不支持进程探测的平台上总是返回 false
+func processAlive(pid int) bool {
+	return false
+}
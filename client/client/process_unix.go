@@ -0,0 +1,14 @@
+//go:build unix
+
+package client
+
+import "syscall"
+
+// processAlive 判断 pid 对应的进程是否存在
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
@@ -0,0 +1,25 @@
+//go:build windows
+
+package client
+
+import "golang.org/x/sys/windows"
+
+// stillActive 为 GetExitCodeProcess 对运行中进程返回的值（STILL_ACTIVE）
+const stillActive = 259
+
+// processAlive 判断 pid 对应的进程是否存在
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return false
+	}
+	defer windows.CloseHandle(h)
+	var code uint32
+	if err := windows.GetExitCodeProcess(h, &code); err != nil {
+		return false
+	}
+	return code == stillActive
+}
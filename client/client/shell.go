@@ -0,0 +1,168 @@
+package client
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// RemoteShell 为后端 open_shell 会话流的前端句柄。
+// 独占一条 IPC 连接：Read 返回远端 stdout/stderr，Write 发送 stdin，
+// 满足 io.ReadWriteCloser，可直接接入 fyne-io/terminal。
+type RemoteShell struct {
+	SessionID string
+
+	conn net.Conn
+	outR *io.PipeReader
+	outW *io.PipeWriter
+	wmu  sync.Mutex
+
+	done   chan struct{}
+	status proto.ExitStatus
+}
+
+// OpenShell 建立会话流并在 req.ConnID 对应的连接上打开远端 shell
+func (c *APIClient) OpenShell(req proto.OpenShellRequest) (*RemoteShell, error) {
+	conn, r, err := c.dial()
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	b, _ := json.Marshal(proto.Message{Type: "open_shell", Data: data})
+	_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	if _, err := conn.Write(append(b, '\n')); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// 首行为标准响应，之后连接切换为双向流
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var resp struct {
+		proto.Response
+		Data proto.OpenShellResponse `json:"data"`
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !resp.Ok {
+		conn.Close()
+		return nil, &APIError{Code: resp.Code, Message: resp.Message}
+	}
+	_ = conn.SetDeadline(time.Time{})
+	fmt.Printf("[API] shell opened session=%s conn=%s\n", resp.Data.SessionID, req.ConnID)
+
+	s := &RemoteShell{SessionID: resp.Data.SessionID, conn: conn, done: make(chan struct{})}
+	s.outR, s.outW = io.Pipe()
+	go s.readLoop(r)
+	return s, nil
+}
+
+// readLoop 分发后端推送的输出与退出消息
+func (s *RemoteShell) readLoop(r *bufio.Reader) {
+	defer close(s.done)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			s.status = proto.ExitStatus{Code: -1, Error: err.Error()}
+			s.outW.CloseWithError(err)
+			return
+		}
+		var msg proto.Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			continue
+		}
+		switch msg.Type {
+		case proto.StreamStdout, proto.StreamStderr:
+			var chunk proto.StreamChunk
+			if json.Unmarshal(msg.Data, &chunk) == nil {
+				if _, err := s.outW.Write(chunk.Data); err != nil {
+					return
+				}
+			}
+		case proto.StreamExit:
+			_ = json.Unmarshal(msg.Data, &s.status)
+			fmt.Printf("[API] shell exited session=%s code=%d\n", s.SessionID, s.status.Code)
+			s.outW.Close()
+			_ = s.conn.Close()
+			return
+		}
+	}
+}
+
+// Read 读取远端输出（stdout 与 stderr 合并）
+func (s *RemoteShell) Read(p []byte) (int, error) {
+	return s.outR.Read(p)
+}
+
+// Write 将输入发送到远端 stdin
+func (s *RemoteShell) Write(p []byte) (int, error) {
+	if err := s.send(proto.StreamStdin, proto.StreamChunk{Data: p}); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Resize 通知远端调整 PTY 尺寸
+func (s *RemoteShell) Resize(cols, rows int) error {
+	return s.send(proto.StreamResize, proto.ResizeRequest{Cols: cols, Rows: rows})
+}
+
+// Close 请求后端结束会话并关闭连接
+func (s *RemoteShell) Close() error {
+	select {
+	case <-s.done:
+		return nil
+	default:
+	}
+	_ = s.send(proto.StreamClose, struct{}{})
+	// 同时关闭读端，解除 readLoop 在无人读取时的阻塞
+	_ = s.outR.Close()
+	return s.conn.Close()
+}
+
+// Done 在会话结束（远端退出或连接断开）时关闭
+func (s *RemoteShell) Done() <-chan struct{} {
+	return s.done
+}
+
+// ExitStatus 返回退出状态，仅在 Done 关闭后有效
+func (s *RemoteShell) ExitStatus() proto.ExitStatus {
+	<-s.done
+	return s.status
+}
+
+func (s *RemoteShell) send(msgType string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(proto.Message{Type: msgType, Data: data})
+	if err != nil {
+		return err
+	}
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	select {
+	case <-s.done:
+		return errors.New("shell closed")
+	default:
+	}
+	_, err = s.conn.Write(append(b, '\n'))
+	return err
+}
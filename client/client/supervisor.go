@@ -0,0 +1,355 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// 后端健康状态
+const (
+	HealthChecking   = "checking"   // 正在探测
+	HealthStarting   = "starting"   // 已拉起子进程，等待其应答
+	HealthUp         = "up"         // 后端可用
+	HealthDown       = "down"       // 后端不可用
+	HealthRestarting = "restarting" // 子进程退出，退避后重启
+)
+
+// Health 后端健康状态快照
+type Health struct {
+	State  string
+	Detail string
+	Owned  bool // 后端是否为本进程拉起的子进程
+	PID    int
+}
+
+// Supervisor 确保后端可用：探测 API 地址，无应答时附着到已运行的实例（PID 文件）
+// 或拉起后端子进程；子进程崩溃后按指数退避重启。
+type Supervisor struct {
+	API *APIClient
+	// Binary 后端可执行文件；为空时依次尝试 GO_SSH_SERVICE、客户端同目录的 go-ssh-service、
+	// PATH 中的 go-ssh-service，最后在源码目录下使用 go run ./service（开发模式）
+	Binary  string
+	DataDir string // 为空时使用 proto.DefaultDataDir()
+	// OnHealth 状态变化时在监控协程中调用，UI 更新需自行切回主线程
+	OnHealth func(Health)
+	// Interval 健康检查周期，默认 5s
+	Interval time.Duration
+
+	mu      sync.Mutex
+	health  Health
+	cmd     *exec.Cmd
+	started time.Time
+	exited  chan struct{} // 当前子进程退出时关闭
+	cancel  context.CancelFunc
+	done    chan struct{}
+}
+
+// Start 启动后台监控，立即返回
+func (s *Supervisor) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(ctx)
+}
+
+// Health 返回当前状态
+func (s *Supervisor) Health() Health {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.health
+}
+
+// Stop 停止监控；后端为本进程拉起时请求其优雅退出，超时后强制结束
+func (s *Supervisor) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	<-s.done
+
+	s.mu.Lock()
+	cmd, exited := s.cmd, s.exited
+	s.mu.Unlock()
+	if cmd == nil {
+		return
+	}
+	if err := s.API.Shutdown("client exit"); err != nil {
+		fmt.Printf("[SUPERVISOR] shutdown request failed: %v\n", err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(10 * time.Second):
+		fmt.Println("[SUPERVISOR] service did not exit in time, killing")
+		_ = cmd.Process.Kill()
+		<-exited
+	}
+}
+
+// foreignWait 等待其它进程拉起的后端应答的最长时间，与 waitForOwned 对自身子进程的容忍一致
+const foreignWait = time.Minute
+
+// run 监控循环：每个周期探测一次，必要时附着或拉起后端
+func (s *Supervisor) run(ctx context.Context) {
+	defer close(s.done)
+	interval := s.Interval
+	if interval == 0 {
+		interval = 5 * time.Second
+	}
+	s.set(Health{State: HealthChecking})
+	restarts := 0
+	var lastSpawn time.Time
+	// 等待中的外部后端及开始等待的时间
+	foreignPID, foreignSince := 0, time.Time{}
+	for {
+		if s.ping() {
+			s.mu.Lock()
+			owned := s.cmd != nil
+			pid := 0
+			if owned {
+				pid = s.cmd.Process.Pid
+			}
+			s.mu.Unlock()
+			if !owned {
+				if info, err := s.readInstance(); err == nil {
+					pid = info.PID
+				}
+			}
+			s.set(Health{State: HealthUp, Detail: s.API.Addr, Owned: owned, PID: pid})
+			foreignPID = 0
+			// 稳定运行一段时间后重置退避
+			if restarts > 0 && time.Since(lastSpawn) > time.Minute {
+				restarts = 0
+			}
+		} else if !s.waitForOwned() {
+			info, err := s.readInstance()
+			alive := err == nil && processAlive(info.PID)
+			if alive && info.PID != foreignPID {
+				foreignPID, foreignSince = info.PID, time.Now()
+			}
+			if alive && time.Since(foreignSince) < foreignWait {
+				// 其它进程拉起的后端仍在运行（可能正在启动），等待其就绪
+				s.set(Health{State: HealthStarting, Detail: fmt.Sprintf("等待已运行的后端 (pid %d)", info.PID), PID: info.PID})
+			} else {
+				// PID 文件过期（进程已退出、PID 被复用或后端卡住），自行拉起；
+				// 若旧后端仍持有数据目录锁，新进程以退出码 3 结束并按退避重试
+				foreignPID = 0
+				if restarts > 0 {
+					d := restartBackoff(restarts)
+					s.set(Health{State: HealthRestarting, Detail: fmt.Sprintf("%s 后重启", d)})
+					if !sleepCtx(ctx, d) {
+						return
+					}
+				}
+				restarts++
+				lastSpawn = time.Now()
+				if err := s.spawn(); err != nil {
+					s.set(Health{State: HealthDown, Detail: err.Error()})
+				} else {
+					s.waitReady(ctx)
+					continue
+				}
+			}
+		}
+		if !sleepCtx(ctx, interval) {
+			return
+		}
+	}
+}
+
+// waitForOwned 子进程仍在运行时返回 true（无应答但进程存活，视为启动中或卡住）
+func (s *Supervisor) waitForOwned() bool {
+	s.mu.Lock()
+	exited := s.exited
+	cmd := s.cmd
+	started := s.started
+	s.mu.Unlock()
+	if cmd == nil {
+		return false
+	}
+	select {
+	case <-exited:
+		return false
+	default:
+	}
+	if time.Since(started) < time.Minute {
+		// go run 首次编译可能较慢
+		s.set(Health{State: HealthStarting, Detail: "正在启动后端", Owned: true, PID: cmd.Process.Pid})
+	} else {
+		s.set(Health{State: HealthDown, Detail: "后端无应答", Owned: true, PID: cmd.Process.Pid})
+	}
+	return true
+}
+
+// waitReady 等待新拉起的子进程应答，最长 10s 或子进程提前退出
+func (s *Supervisor) waitReady(ctx context.Context) {
+	s.mu.Lock()
+	exited := s.exited
+	s.mu.Unlock()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if s.ping() {
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-exited:
+			return
+		case <-time.After(300 * time.Millisecond):
+		}
+	}
+}
+
+// spawn 拉起后端子进程，输出追加到数据目录下的 service.log
+func (s *Supervisor) spawn() error {
+	name, args, err := s.command()
+	if err != nil {
+		return err
+	}
+	dataDir := s.dataDir()
+	if err := os.MkdirAll(dataDir, 0o700); err != nil {
+		return err
+	}
+	args = append(args, "-data", dataDir)
+	network, address := proto.SplitAddr(s.API.Addr)
+	if network == "unix" {
+		args = append(args, "-addr", "", "-socket", address)
+	} else {
+		args = append(args, "-addr", address)
+	}
+
+	logFile, err := os.OpenFile(filepath.Join(dataDir, "service.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = logFile
+	cmd.Stderr = logFile
+	cmd.Env = append(os.Environ(), "GO_SSH_DATA_DIR="+dataDir)
+	if err := cmd.Start(); err != nil {
+		logFile.Close()
+		return fmt.Errorf("start service: %w", err)
+	}
+	fmt.Printf("[SUPERVISOR] started service pid=%d: %s %v\n", cmd.Process.Pid, name, args)
+	s.set(Health{State: HealthStarting, Detail: "正在启动后端", Owned: true, PID: cmd.Process.Pid})
+
+	exited := make(chan struct{})
+	s.mu.Lock()
+	s.cmd, s.exited, s.started = cmd, exited, time.Now()
+	s.mu.Unlock()
+	go func() {
+		err := cmd.Wait()
+		logFile.Close()
+		code := cmd.ProcessState.ExitCode()
+		fmt.Printf("[SUPERVISOR] service pid=%d exited: code=%d err=%v\n", cmd.Process.Pid, code, err)
+		s.mu.Lock()
+		if s.cmd == cmd {
+			s.cmd = nil
+		}
+		s.mu.Unlock()
+		close(exited)
+		if code == proto.ExitAlreadyRunning {
+			// 并发启动时另一个实例抢先拿到锁，下个周期附着即可
+			return
+		}
+		s.set(Health{State: HealthDown, Detail: fmt.Sprintf("后端退出 (code %d)", code)})
+	}()
+	return nil
+}
+
+// command 解析后端启动命令
+func (s *Supervisor) command() (string, []string, error) {
+	if s.Binary != "" {
+		return s.Binary, nil, nil
+	}
+	if p := os.Getenv("GO_SSH_SERVICE"); p != "" {
+		return p, nil, nil
+	}
+	bin := "go-ssh-service"
+	if runtime.GOOS == "windows" {
+		bin += ".exe"
+	}
+	if exe, err := os.Executable(); err == nil {
+		p := filepath.Join(filepath.Dir(exe), bin)
+		if _, err := os.Stat(p); err == nil {
+			return p, nil, nil
+		}
+	}
+	if p, err := exec.LookPath(bin); err == nil {
+		return p, nil, nil
+	}
+	// 开发模式：在仓库根目录以 go run 启动
+	if _, err := os.Stat(filepath.Join("service", "main.go")); err == nil {
+		if goBin, err := exec.LookPath("go"); err == nil {
+			return goBin, []string{"run", "./service"}, nil
+		}
+	}
+	return "", nil, errors.New("service binary not found (set GO_SSH_SERVICE)")
+}
+
+func (s *Supervisor) dataDir() string {
+	if s.DataDir != "" {
+		return s.DataDir
+	}
+	return proto.DefaultDataDir()
+}
+
+func (s *Supervisor) readInstance() (proto.ServiceInfo, error) {
+	var info proto.ServiceInfo
+	b, err := os.ReadFile(filepath.Join(s.dataDir(), proto.PIDFileName))
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
+
+// ping 经持久连接探测后端，连接断开时 APIClient 会自动重连
+func (s *Supervisor) ping() bool {
+	resp, err := s.API.Ping()
+	return err == nil && resp.Ok
+}
+
+// set 更新状态，仅在变化时回调
+func (s *Supervisor) set(h Health) {
+	s.mu.Lock()
+	changed := h != s.health
+	s.health = h
+	s.mu.Unlock()
+	if changed {
+		fmt.Printf("[SUPERVISOR] %s %s\n", h.State, h.Detail)
+		if s.OnHealth != nil {
+			s.OnHealth(h)
+		}
+	}
+}
+
+// restartBackoff 重启退避：1s 起翻倍，上限 30s
+func restartBackoff(restarts int) time.Duration {
+	d := time.Second << (restarts - 1)
+	if d > 30*time.Second || d <= 0 {
+		d = 30 * time.Second
+	}
+	return d
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -1,206 +1,1980 @@
 package main
 
 import (
-    "fmt"
-    "fyne.io/fyne/v2"
-    "fyne.io/fyne/v2/app"
-    "fyne.io/fyne/v2/container"
-    "fyne.io/fyne/v2/widget"
-    "go-ssh/client/client"
-    ui "go-ssh/client/ui"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ssh/client/client"
+	ui "go-ssh/client/ui"
+	"go-ssh/proto"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/app"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
 )
+
 // main 启动 Fyne 原生桌面应用客户端。
 // 前端仅负责 UI 展现与轻量 API 调用，数据与连接由后端统一管理。
 func main() {
-    fmt.Println("[INFO] 启动SSH客户端程序...")
-    
-    a := app.New()
-    // 使用白底黑字的主题，贴近 Figma 设计
-    a.Settings().SetTheme(ui.NewLightTheme())
-    w := a.NewWindow("Go-SSH Client")
-    w.Resize(fyne.NewSize(1200, 800))
-    w.CenterOnScreen()
-    
-    fmt.Println("[INFO] 创建主窗口完成，尺寸: 1200x800")
-
-    api := &client.APIClient{Addr: "localhost:8089"}
-
-    // 1. 顶部菜单栏（Figma Header复刻）
-    header := ui.NewHeader(ui.HeaderProps{
-        OnNewConnection: func(){ showConnectDialog(w, api) },
-        OnOpenConnectionMgr: func(){
-            // TODO: replace mock connections with service/ListConnections()
-            conns := []ui.Connection{
-                {ID:"1", Name:"WSL", Type:"connection"},
-                {ID:"2", Name:"服务器A", Type:"connection"},
-                {ID:"f1", Name:"上海（用户ID7）", Type:"folder"},
-            }
-            dlg := ui.NewConnectionManagerModal(ui.ConnectionManagerProps{
-                Window: w,
-                Connections: conns,
-                OnConnect: func(c ui.Connection){
-                    fmt.Printf("[UI] 选择连接: %s (%s)\n", c.Name, c.ID)
-                    // TODO: 调用后端 Connect 协议并在右侧创建终端标签
-                },
-            })
-            dlg.Show()
-        },
-        OnOpenTerminal: func(){ /* 可切换到终端区域 */ },
-        OnPing: func() (bool, string, error) {
-            fmt.Println("[USER] 点击了测试连接按钮，开始请求后端 Ping...")
-            resp, err := api.Ping()
-            fmt.Printf("[DEBUG] Ping 返回: resp=%+v, err=%v\n", resp, err)
-            if err != nil { return false, "", err }
-            if resp.Ok { return true, "pong", nil }
-            return false, resp.Message, nil
-        },
-    }, w)
-    
-    // 2. 左侧设备信息区
-    deviceInfoPanel := createDeviceInfoPanel(api)
-    
-    // 右侧终端面板（TabBar封装）
-    tabbar := ui.NewTabBar(nil, func(title string){ fmt.Println("[UI] 关闭标签:", title) })
-    // 设置添加终端按钮逻辑（避免自引用初始化）
-    tabbar.AddBtn.OnTapped = func(){
-        tabbar.AddTerminalTab("终端", ui.NewLocalTerminal())
-    }
-    tabbar.DebugPopulate()
-
-    // 主布局：顶部菜单 + 下方左右可拖动分区
-    rightPane := container.NewBorder(tabbar.HeaderBar(), nil, nil, nil, tabbar.Tabs)
-    split := container.NewHSplit(deviceInfoPanel, rightPane)
-    split.Offset = 0.25 // 初始左侧占比 25%
-    mainContent := container.NewBorder(
-        header, // 顶部
-        nil,    // 底部
-        nil,    // 左侧（由 split 承载）
-        nil,    // 右侧
-        split,  // 中间区域为可拖动分割
-    )
-
-    fmt.Println("[INFO] 创建UI组件完成，准备显示主界面")
-    
-    w.SetContent(mainContent)
-    fmt.Println("[INFO] 主界面设置完成，准备显示窗口")
-    
-    w.ShowAndRun()
+	fmt.Println("[INFO] 启动SSH客户端程序...")
+
+	a := app.New()
+	// 使用白底黑字的主题，贴近 Figma 设计
+	a.Settings().SetTheme(ui.NewLightTheme())
+	w := a.NewWindow("Go-SSH Client")
+	w.Resize(fyne.NewSize(1200, 800))
+	w.CenterOnScreen()
+
+	fmt.Println("[INFO] 创建主窗口完成，尺寸: 1200x800")
+
+	// 后端地址可用 GO_SSH_ADDR 覆盖（支持 "unix:/path"），令牌从数据目录读取
+	addr := os.Getenv("GO_SSH_ADDR")
+	if addr == "" {
+		addr = proto.DefaultAddr
+	}
+	api := &client.APIClient{Addr: addr}
+
+	// 后端监控：无应答时附着到已运行实例或拉起子进程，崩溃后退避重启
+	health := ui.NewHealthIndicator()
+	sup := &client.Supervisor{
+		API: api,
+		OnHealth: func(h client.Health) {
+			level, text := healthView(h)
+			fyne.Do(func() { health.Set(level, text) })
+		},
+	}
+	sup.Start()
+	defer sup.Stop()
+
+	// 右侧终端面板（TabBar封装）
+	tabbar := ui.NewTabBar(nil, func(title string) { fmt.Println("[UI] 关闭标签:", title) })
+	// 设置添加终端按钮逻辑（避免自引用初始化）
+	tabbar.AddBtn.OnTapped = func() {
+		tabbar.AddTerminalTab("终端", ui.NewLocalTerminal())
+	}
+	tabbar.DebugPopulate()
+
+	// 1. 顶部菜单栏（Figma Header复刻）
+	header := ui.NewHeader(ui.HeaderProps{
+		OnNewConnection: func() { showConnectDialog(w, api, tabbar) },
+		OnOpenConnectionMgr: func() {
+			// 异步拉取后端保存的连接配置，避免阻塞 UI
+			go func() {
+				profiles, err := api.ListProfiles()
+				fyne.Do(func() {
+					if err != nil {
+						ui.ShowError(w, fmt.Errorf("获取连接列表失败: %w", err))
+						return
+					}
+					dlg := ui.NewConnectionManagerModal(ui.ConnectionManagerProps{
+						Window:      w,
+						Connections: profilesToConnections(profiles),
+						OnConnect: func(c ui.Connection) {
+							fmt.Printf("[UI] 选择连接: %s (%s)\n", c.Name, c.ID)
+							if c.Type != "connection" {
+								return
+							}
+							go func() {
+								p, err := api.GetConnection(c.ID)
+								if err != nil {
+									fyne.Do(func() { ui.ShowError(w, fmt.Errorf("读取连接配置失败: %w", err)) })
+									return
+								}
+								openRemoteTab(w, api, tabbar, p.Name, proto.ConnectRequest{
+									ID: p.ID, Host: p.Host, Port: p.Port, User: p.User,
+									Password: p.Password, KeyPath: p.KeyPath, AuthMethods: p.AuthMethods,
+									PasswordRef: p.PasswordRef, PassphraseRef: p.PassphraseRef,
+									Jumps: p.Jumps,
+								})
+							}()
+						},
+					})
+					dlg.Show()
+				})
+			}()
+		},
+		OnOpenTerminal:   func() { /* 可切换到终端区域 */ },
+		OnOpenVault:      func() { showVaultDialog(w, api) },
+		OnOpenKeys:       func() { showKeysDialog(w, api) },
+		OnOpenBatchExec:  func() { showBatchExec(a, api) },
+		OnOpenRecordings: func() { showRecordings(a, api) },
+		OnPing: func() (bool, string, error) {
+			fmt.Println("[USER] 点击了测试连接按钮，开始请求后端 Ping...")
+			resp, err := api.Ping()
+			fmt.Printf("[DEBUG] Ping 返回: resp=%+v, err=%v\n", resp, err)
+			if err != nil {
+				return false, "", err
+			}
+			if resp.Ok {
+				return true, "pong", nil
+			}
+			return false, resp.Message, nil
+		},
+		Status: health.Object(),
+	}, w)
+
+	// 订阅后端推送：连接状态、会话退出与凭据库锁定状态（后端尚未就绪时 APIClient 在后台重试订阅）
+	go func() {
+		api.Subscribe([]string{proto.EventConnectionState, proto.EventSessionExit, proto.EventVaultState}, func(ev proto.Frame) {
+			fmt.Printf("[EVENT] %s seq=%d data=%s\n", ev.Event, ev.Seq, string(ev.Data))
+		})
+	}()
+
+	// 需要用户参与的事件：主机密钥未受信任时确认指纹后重试连接；认证输入（口令/二次验证）弹窗应答
+	go func() {
+		api.Subscribe([]string{proto.EventHostKeyUnknown, proto.EventHostKeyChanged, proto.EventAuthPrompt}, func(ev proto.Frame) {
+			switch ev.Event {
+			case proto.EventAuthPrompt:
+				var p proto.AuthPromptEvent
+				if err := json.Unmarshal(ev.Data, &p); err != nil {
+					fmt.Printf("[WARN] 解析认证提示事件失败: %v\n", err)
+					return
+				}
+				fyne.Do(func() { showAuthPrompt(w, api, p) })
+			default:
+				var hk proto.HostKeyEvent
+				if err := json.Unmarshal(ev.Data, &hk); err != nil {
+					fmt.Printf("[WARN] 解析主机密钥事件失败: %v\n", err)
+					return
+				}
+				changed := ev.Event == proto.EventHostKeyChanged
+				fyne.Do(func() { showHostKeyPrompt(w, api, hk, changed) })
+			}
+		})
+	}()
+
+	// 2. 左侧设备信息区：跟随当前标签所连主机实时显示系统监控
+	deviceInfoPanel, followDevice := createDeviceInfoPanel(api, tabbar)
+
+	// 端口转发面板：位于标签页右侧，由 TabBar 的按钮切换显示；统计由 tunnel_stats 事件实时更新
+	var tunnels *ui.TunnelsPanel
+	reloadTunnels := func() {
+		go func() {
+			list, err := api.ListTunnels("")
+			if err != nil {
+				fmt.Printf("[WARN] 获取端口转发失败: %v\n", err)
+				return
+			}
+			items := make([]ui.Tunnel, len(list))
+			for i, t := range list {
+				items[i] = tunnelView(t)
+			}
+			fyne.Do(func() { tunnels.Set(items) })
+		}()
+	}
+	tunnels = ui.NewTunnelsPanel(ui.TunnelsPanelProps{
+		OnCreate: func() { showCreateTunnel(w, api) },
+		OnClose: func(id string) {
+			go func() {
+				if _, err := api.CloseTunnel(id); err != nil {
+					fyne.Do(func() { ui.ShowError(w, fmt.Errorf("关闭转发失败: %w", err)) })
+				}
+			}()
+		},
+		OnRefresh: reloadTunnels,
+	})
+	tunnels.Object().Hide()
+	tabbar.ToggleTunnelsBtn.OnTapped = func() {
+		if tunnels.Object().Visible() {
+			tunnels.Object().Hide()
+		} else {
+			tunnels.Object().Show()
+			reloadTunnels()
+		}
+	}
+	go func() {
+		api.Subscribe([]string{proto.EventTunnelStats}, func(ev proto.Frame) {
+			var t proto.TunnelInfo
+			if err := json.Unmarshal(ev.Data, &t); err != nil {
+				fmt.Printf("[WARN] 解析转发统计事件失败: %v\n", err)
+				return
+			}
+			fyne.Do(func() { tunnels.Update(tunnelView(t), t.State == proto.TunnelClosed) })
+		})
+	}()
+
+	// 文件传输面板：位于标签页下方，由 TabBar 的按钮切换显示；进度由 transfer_progress 事件实时更新
+	var transfers *ui.TransfersPanel
+	reloadTransfers := func() {
+		go func() {
+			list, err := api.ListTransfers("")
+			if err != nil {
+				fmt.Printf("[WARN] 获取传输列表失败: %v\n", err)
+				return
+			}
+			items := make([]ui.Transfer, len(list))
+			for i, t := range list {
+				items[i] = transferView(t)
+			}
+			fyne.Do(func() { transfers.Set(items) })
+		}()
+	}
+	transfers = ui.NewTransfersPanel(ui.TransfersPanelProps{
+		OnCancel: func(id string) {
+			go func() {
+				if _, err := api.CancelTransfer(id); err != nil {
+					fyne.Do(func() { ui.ShowError(w, fmt.Errorf("取消传输失败: %w", err)) })
+				}
+			}()
+		},
+		OnRefresh: reloadTransfers,
+	})
+	transfers.Object().Hide()
+	showTransfers := func() {
+		if !transfers.Object().Visible() {
+			transfers.Object().Show()
+			reloadTransfers()
+		}
+	}
+	tabbar.ToggleTransfersBtn.OnTapped = func() {
+		if transfers.Object().Visible() {
+			transfers.Object().Hide()
+		} else {
+			showTransfers()
+		}
+	}
+
+	// 进程管理：为当前标签所连主机打开独立窗口
+	tabbar.ProcessesBtn.OnTapped = func() {
+		id := tabbar.CurrentConn()
+		if id == "" {
+			ui.ShowInfo(w, "进程管理", "当前标签未连接远程主机")
+			return
+		}
+		showProcessManager(a, api, id, tabbar.CurrentTitle())
+	}
+
+	// 导出会话：将当前标签的输出保存为纯文本或 HTML
+	tabbar.ExportBtn.OnTapped = func() { showExportSession(w, tabbar) }
+
+	// SFTP 面板：位于终端标签右侧，浏览当前标签所连主机的文件；切换标签时跟随切换主机，
+	// 并记住每个连接最后所在的目录
+	var sftpPanel *ui.SFTPPanel
+	sftpConn := ""                  // 面板当前对应的连接 ID，仅在 UI 线程读写
+	sftpDirs := map[string]string{} // 连接 ID -> 最后浏览的目录
+	listSFTP := func(dir string) {
+		connID := sftpConn
+		if connID == "" {
+			return
+		}
+		go func() {
+			res, err := api.SFTPList(connID, dir)
+			fyne.Do(func() {
+				if connID != sftpConn {
+					return
+				}
+				if err != nil {
+					if sftpPanel.Path() == "" {
+						sftpPanel.SetError(fmt.Errorf("无法打开 %s: %w", dir, err))
+					} else {
+						ui.ShowError(w, fmt.Errorf("无法打开 %s: %w", dir, err))
+					}
+					return
+				}
+				sftpDirs[connID] = res.Path
+				items := make([]ui.RemoteFile, len(res.Entries))
+				for i, e := range res.Entries {
+					items[i] = remoteFileView(e)
+				}
+				sftpPanel.Set(res.Path, items)
+			})
+		}()
+	}
+	syncSFTP := func() {
+		if !sftpPanel.Object().Visible() {
+			return
+		}
+		id := tabbar.CurrentConn()
+		if id == sftpConn {
+			return
+		}
+		sftpConn = id
+		if id == "" {
+			sftpPanel.Clear("当前标签未连接远程主机")
+			return
+		}
+		sftpPanel.SetHost(tabbar.CurrentTitle())
+		listSFTP(sftpDirs[id])
+	}
+	// sftpDo 在后台执行文件操作，成功后刷新当前目录
+	sftpDo := func(what string, op func(connID string) error) {
+		connID := sftpConn
+		go func() {
+			err := op(connID)
+			fyne.Do(func() {
+				if err != nil {
+					ui.ShowError(w, fmt.Errorf("%s失败: %w", what, err))
+				}
+				if connID == sftpConn {
+					listSFTP(sftpPanel.Path())
+				}
+			})
+		}()
+	}
+	sftpPanel = ui.NewSFTPPanel(ui.SFTPPanelProps{
+		OnNavigate: listSFTP,
+		OnUpload: func(dir string) {
+			showStartTransfer(w, api, proto.TransferRequest{
+				ConnID: sftpConn, Direction: proto.TransferUpload, RemotePath: dir,
+			}, showTransfers)
+		},
+		OnDownload: func(f ui.RemoteFile) {
+			showStartTransfer(w, api, proto.TransferRequest{
+				ConnID: sftpConn, Direction: proto.TransferDownload, RemotePath: f.Path,
+				LocalPath: filepath.Join(downloadDir(), f.Name),
+			}, showTransfers)
+		},
+		OnMkdir: func(dir string) {
+			showSFTPPrompt(w, "新建文件夹", "名称", "", func(name string) {
+				sftpDo("新建文件夹", func(connID string) error {
+					_, err := api.SFTPMkdir(proto.SFTPMkdirRequest{ConnID: connID, Path: path.Join(dir, name)})
+					return err
+				})
+			})
+		},
+		OnSymlink: func(dir string, target *ui.RemoteFile) {
+			showSFTPSymlink(w, dir, target, func(targetPath, link string) {
+				sftpDo("创建链接", func(connID string) error {
+					_, err := api.SFTPSymlink(connID, targetPath, link)
+					return err
+				})
+			})
+		},
+		OnRename: func(f ui.RemoteFile) {
+			showSFTPPrompt(w, "重命名 "+f.Name, "新名称或路径", f.Name, func(to string) {
+				if !path.IsAbs(to) {
+					to = path.Join(path.Dir(f.Path), to)
+				}
+				sftpDo("重命名", func(connID string) error {
+					_, err := api.SFTPRename(proto.SFTPRenameRequest{ConnID: connID, From: f.Path, To: to})
+					return err
+				})
+			})
+		},
+		OnChmod: func(f ui.RemoteFile) {
+			showSFTPPrompt(w, "修改权限 "+f.Name, "八进制权限", f.Perm, func(mode string) {
+				sftpDo("修改权限", func(connID string) error {
+					_, err := api.SFTPChmod(connID, f.Path, mode)
+					return err
+				})
+			})
+		},
+		OnDelete: func(f ui.RemoteFile) {
+			msg := fmt.Sprintf("确定删除 %s 吗？此操作不可撤销。", f.Path)
+			recursive := f.IsDir && !f.IsLink
+			if recursive {
+				msg = fmt.Sprintf("确定删除目录 %s 及其全部内容吗？此操作不可撤销。", f.Path)
+			}
+			ui.ShowConfirm(w, "删除", widget.NewLabel(msg), "删除", "取消", func(ok bool) {
+				if !ok {
+					return
+				}
+				sftpDo("删除", func(connID string) error {
+					return api.SFTPRemove(proto.SFTPRemoveRequest{ConnID: connID, Path: f.Path, Recursive: recursive})
+				})
+			})
+		},
+	})
+	sftpPanel.Object().Hide()
+	tabbar.OnSelect = func() {
+		syncSFTP()
+		followDevice(false)
+	}
+	tabbar.ToggleSFTPBtn.OnTapped = func() {
+		if sftpPanel.Object().Visible() {
+			sftpPanel.Object().Hide()
+			tabbar.ToggleSFTPBtn.SetText("显示SFTP")
+			return
+		}
+		sftpPanel.Object().Show()
+		tabbar.ToggleSFTPBtn.SetText("隐藏SFTP")
+		sftpConn = ""
+		syncSFTP()
+	}
+	go func() {
+		api.Subscribe([]string{proto.EventTransferProgress}, func(ev proto.Frame) {
+			var t proto.TransferInfo
+			if err := json.Unmarshal(ev.Data, &t); err != nil {
+				fmt.Printf("[WARN] 解析传输进度事件失败: %v\n", err)
+				return
+			}
+			fyne.Do(func() {
+				transfers.Update(transferView(t))
+				// 上传完成后刷新正在浏览该主机的 SFTP 面板
+				if t.State == proto.TransferCompleted && t.Direction == proto.TransferUpload && t.ConnID == sftpConn {
+					listSFTP(sftpPanel.Path())
+				}
+			})
+		})
+	}()
+
+	// 主布局：顶部菜单 + 下方左右可拖动分区
+	terminals := container.NewBorder(nil, nil, nil, sftpPanel.Object(), tabbar.Tabs)
+	rightPane := container.NewBorder(tabbar.HeaderBar(), transfers.Object(), nil, tunnels.Object(), terminals)
+	split := container.NewHSplit(deviceInfoPanel.Object(), rightPane)
+	split.Offset = 0.25 // 初始左侧占比 25%
+	mainContent := container.NewBorder(
+		header, // 顶部
+		nil,    // 底部
+		nil,    // 左侧（由 split 承载）
+		nil,    // 右侧
+		split,  // 中间区域为可拖动分割
+	)
+
+	fmt.Println("[INFO] 创建UI组件完成，准备显示主界面")
+
+	w.SetContent(mainContent)
+	fmt.Println("[INFO] 主界面设置完成，准备显示窗口")
+
+	w.ShowAndRun()
+}
+
+// healthView 将后端健康状态映射为 Header 状态区的颜色与文字
+func healthView(h client.Health) (ui.HealthLevel, string) {
+	owner := "外部"
+	if h.Owned {
+		owner = "托管"
+	}
+	switch h.State {
+	case client.HealthUp:
+		return ui.HealthOK, fmt.Sprintf("后端: 在线（%s, pid %d）", owner, h.PID)
+	case client.HealthStarting, client.HealthRestarting:
+		return ui.HealthWarn, "后端: " + h.Detail
+	case client.HealthDown:
+		return ui.HealthError, "后端: 离线 " + h.Detail
+	default:
+		return ui.HealthUnknown, "后端: 检测中"
+	}
 }
 
 // remove old menuBar; replaced by Figma-like header component
 
-// createDeviceInfoPanel 创建设备信息面板
-func createDeviceInfoPanel(api *client.APIClient) *fyne.Container {
-    // 标题
-    title := widget.NewLabel("设备信息")
-    title.TextStyle = fyne.TextStyle{Bold: true}
-    
-    // 硬件信息组
-    hwGroup := widget.NewCard("硬件信息", "",
-        container.NewVBox(
-            widget.NewLabel("CPU: 待获取"),
-            widget.NewLabel("内存: 待获取"),
-            widget.NewLabel("磁盘: 待获取"),
-            widget.NewLabel("系统: 待获取"),
-        ),
-    )
-    
-    // 网络信息组
-    netGroup := widget.NewCard("网络信息", "",
-        container.NewVBox(
-            widget.NewLabel("主机名: 待获取"),
-            widget.NewLabel("IP地址: 待获取"),
-            widget.NewLabel("MAC地址: 待获取"),
-            widget.NewLabel("连接状态: 未连接"),
-        ),
-    )
-    
-    // 刷新按钮
-    refreshBtn := widget.NewButton("刷新信息", func() {
-        // TODO: 实现获取设备信息逻辑
-    })
-    
-    content := container.NewVBox(
-        title,
-        hwGroup,
-        netGroup,
-        refreshBtn,
-    )
-    
-    // 设置固定宽度
-    scroll := container.NewScroll(content)
-    scroll.SetMinSize(fyne.NewSize(200, 0))
-    
-    return container.NewBorder(nil, nil, nil, nil, scroll)
+// createDeviceInfoPanel 创建设备信息面板，返回面板与跟随函数：切换标签时调用，
+// 关闭旧的监控订阅并订阅当前标签的连接；force 为 true 时即使连接未变也重新订阅（刷新信息）
+func createDeviceInfoPanel(api *client.APIClient, tabbar *ui.TabBar) (*ui.DeviceInfoPanel, func(force bool)) {
+	var panel *ui.DeviceInfoPanel
+	var stream *client.MonitorStream
+	connID := "" // 面板当前对应的连接 ID，仅在 UI 线程读写
+	gen := 0     // 每次重新订阅递增，丢弃旧订阅迟到的采样
+
+	follow := func(force bool) {
+		id := tabbar.CurrentConn()
+		if id == connID && !force {
+			return
+		}
+		if stream != nil {
+			go stream.Close()
+			stream = nil
+		}
+		connID = id
+		gen++
+		if id == "" {
+			panel.Clear("当前标签未连接远程主机")
+			return
+		}
+		host := tabbar.CurrentTitle()
+		panel.Set(ui.DeviceInfo{Host: host, Status: "正在获取..."})
+		cur := gen
+		go func() {
+			s, err := api.Monitor(proto.MonitorRequest{ConnID: id}, func(sample proto.MonitorSample) {
+				fyne.Do(func() {
+					if cur != gen {
+						return
+					}
+					if sample.Error != "" {
+						panel.SetStatus("采集失败: " + sample.Error)
+						return
+					}
+					panel.Set(deviceInfoView(host, sample))
+				})
+			})
+			fyne.Do(func() {
+				if cur != gen {
+					if s != nil {
+						go s.Close()
+					}
+					return
+				}
+				if err != nil {
+					panel.SetStatus("获取失败: " + err.Error())
+					return
+				}
+				stream = s
+			})
+			if err != nil {
+				return
+			}
+			st := s.ExitStatus()
+			fyne.Do(func() {
+				if cur != gen {
+					return
+				}
+				stream = nil
+				panel.SetStatus(strings.TrimSpace("已断开 " + st.Error))
+			})
+		}()
+	}
+	panel = ui.NewDeviceInfoPanel(ui.DeviceInfoPanelProps{
+		OnRefresh: func() { follow(true) },
+	})
+	return panel, follow
+}
+
+// deviceInfoView 将监控采样转换为设备信息面板的显示模型
+func deviceInfoView(host string, s proto.MonitorSample) ui.DeviceInfo {
+	v := ui.DeviceInfo{
+		Host:      host,
+		CPUUsage:  s.CPU.Usage / 100,
+		Load:      fmt.Sprintf("%.2f  %.2f  %.2f", s.Load[0], s.Load[1], s.Load[2]),
+		System:    strings.TrimSpace(s.OS + " " + s.Kernel),
+		Uptime:    formatUptime(s.Uptime),
+		Hostname:  s.Hostname,
+		Addresses: strings.Join(s.Addresses, ", "),
+		Status:    "已连接",
+	}
+	cpu := []string{}
+	if s.CPU.Model != "" {
+		cpu = append(cpu, s.CPU.Model)
+	}
+	cpu = append(cpu, fmt.Sprintf("%d 核", s.CPU.Cores))
+	if s.CPU.MHz > 0 {
+		cpu = append(cpu, fmt.Sprintf("%.2f GHz", s.CPU.MHz/1000))
+	}
+	v.CPU = strings.Join(append(cpu, fmt.Sprintf("%.0f%%", s.CPU.Usage)), " · ")
+	v.Memory, v.MemoryUsage = usageView(s.Memory.Used, s.Memory.Total)
+	v.Swap, v.SwapUsage = usageView(s.Swap.Used, s.Swap.Total)
+	if s.Swap.Total == 0 {
+		v.Swap = "未启用"
+	}
+	v.Disk, v.DiskUsage = usageView(s.Disk.Used, s.Disk.Total)
+	if s.Disk.Mount != "" && v.Disk != "" {
+		v.Disk += " · " + s.Disk.Mount
+	}
+	var macs []string
+	for _, n := range s.Net {
+		if n.MAC != "" {
+			macs = append(macs, n.MAC)
+		}
+		v.Network = append(v.Network, fmt.Sprintf("%s ↓%s/s ↑%s/s", n.Name, ui.FormatBytes(int64(n.RxRate)), ui.FormatBytes(int64(n.TxRate))))
+	}
+	v.MAC = strings.Join(macs, ", ")
+	return v
+}
+
+// usageView 返回 "已用 / 总量 (百分比)" 及占比，总量为 0 时为空
+func usageView(used, total uint64) (string, float64) {
+	if total == 0 {
+		return "", 0
+	}
+	ratio := float64(used) / float64(total)
+	return fmt.Sprintf("%s / %s (%.0f%%)", ui.FormatBytes(int64(used)), ui.FormatBytes(int64(total)), ratio*100), ratio
+}
+
+// formatUptime 将秒数格式化为 "3天 4小时 5分钟"
+func formatUptime(sec int64) string {
+	if sec <= 0 {
+		return ""
+	}
+	d, h, m := sec/86400, sec%86400/3600, sec%3600/60
+	switch {
+	case d > 0:
+		return fmt.Sprintf("%d天 %d小时 %d分钟", d, h, m)
+	case h > 0:
+		return fmt.Sprintf("%d小时 %d分钟", h, m)
+	}
+	return fmt.Sprintf("%d分钟", m)
 }
 
 // createTerminalPanel 创建终端面板
 func createTerminalPanel() *fyne.Container {
-    // 标签页容器
-    tabs := container.NewAppTabs()
-    
-    // 默认标签页
-    welcomeTab := container.NewTabItem("欢迎", 
-        container.NewCenter(
-            widget.NewLabel("请新建连接以开始使用终端"),
-        ),
-    )
-    tabs.Append(welcomeTab)
-    
-    // 添加新标签页按钮
-    addTabBtn := widget.NewButton("+ 新终端", func() {
-        // TODO: 实现新建终端标签页逻辑
-    })
-    
-    // 终端控制栏
-    controlBar := container.NewBorder(
-        nil, nil,
-        addTabBtn, nil,
-        nil,
-    )
-    
-    return container.NewBorder(
-        controlBar, // 顶部控制栏
-        nil, nil, nil,
-        tabs,       // 终端内容区
-    )
+	// 标签页容器
+	tabs := container.NewAppTabs()
+
+	// 默认标签页
+	welcomeTab := container.NewTabItem("欢迎",
+		container.NewCenter(
+			widget.NewLabel("请新建连接以开始使用终端"),
+		),
+	)
+	tabs.Append(welcomeTab)
+
+	// 添加新标签页按钮
+	addTabBtn := widget.NewButton("+ 新终端", func() {
+		// TODO: 实现新建终端标签页逻辑
+	})
+
+	// 终端控制栏
+	controlBar := container.NewBorder(
+		nil, nil,
+		addTabBtn, nil,
+		nil,
+	)
+
+	return container.NewBorder(
+		controlBar, // 顶部控制栏
+		nil, nil, nil,
+		tabs, // 终端内容区
+	)
+}
+
+// profilesToConnections 将后端连接配置转换为连接管理器列表项：分组在前，连接在后
+func profilesToConnections(profiles []proto.Profile) []ui.Connection {
+	var folders, conns []ui.Connection
+	seen := map[string]bool{}
+	for _, p := range profiles {
+		if p.Folder != "" && !seen[p.Folder] {
+			seen[p.Folder] = true
+			folders = append(folders, ui.Connection{ID: "folder:" + p.Folder, Name: p.Folder, Type: "folder"})
+		}
+		name := p.Name
+		if p.Folder != "" {
+			name = p.Folder + " / " + p.Name
+		}
+		conns = append(conns, ui.Connection{ID: p.ID, Name: name, Type: "connection"})
+	}
+	return append(folders, conns...)
+}
+
+// openRemoteTab 建立连接并打开远端 shell，成功后在右侧新增终端标签。
+// 需在后台 goroutine 中调用，UI 更新通过 fyne.Do 回到主线程。
+func openRemoteTab(window fyne.Window, api *client.APIClient, tabbar *ui.TabBar, title string, req proto.ConnectRequest) {
+	// 口令、密码与二次验证经 auth_prompt 事件弹窗询问
+	req.Interactive = true
+	st, err := api.Connect(req)
+	var apiErr *client.APIError
+	if errors.As(err, &apiErr) && apiErr.Code == proto.CodeHostKey {
+		// 主机密钥待确认：由 host_key_* 事件弹窗，用户信任后重试
+		pendingConnects.Store(req.ID, func() { openRemoteTab(window, api, tabbar, title, req) })
+		fmt.Printf("[UI] 连接 %s 等待确认主机密钥\n", req.ID)
+		return
+	}
+	if errors.As(err, &apiErr) && apiErr.Code == proto.CodeLocked {
+		// 保存的密码在凭据库中：解锁后重试
+		fyne.Do(func() {
+			showVaultUnlock(window, api, func(unlocked bool) {
+				if unlocked {
+					openRemoteTab(window, api, tabbar, title, req)
+				}
+			})
+		})
+		return
+	}
+	if err != nil {
+		fyne.Do(func() { ui.ShowError(window, fmt.Errorf("连接 %s 失败: %w", req.Host, err)) })
+		return
+	}
+	fmt.Printf("[UI] 连接状态: id=%s state=%s\n", st.ID, st.State)
+	sh, err := api.OpenShell(proto.OpenShellRequest{ConnID: req.ID})
+	if err != nil {
+		fyne.Do(func() { ui.ShowError(window, fmt.Errorf("打开终端失败: %w", err)) })
+		return
+	}
+	fyne.Do(func() {
+		var tab *container.TabItem
+		tab = tabbar.AddRemoteTerminalTab(title, sh, func() {
+			tabbar.SetTabTitle(tab, title+"（已断开）")
+		})
+		tabbar.SetTabCloser(tab, func() { _ = sh.Close() })
+		tabbar.SetTabConn(tab, req.ID)
+	})
+}
+
+// pendingConnects 因主机密钥未受信任而暂停的连接，键为连接 ID，值为重试函数
+var pendingConnects sync.Map
+
+// showHostKeyPrompt 展示主机密钥指纹并请求用户确认；密钥变更时给出醒目警告与原指纹。
+// 确认后写入 known_hosts（哈希主机名），并重试等待中的连接；取消则放弃该连接。
+func showHostKeyPrompt(window fyne.Window, api *client.APIClient, ev proto.HostKeyEvent, changed bool) {
+	hostPort := fmt.Sprintf("%s:%d", ev.Host, ev.Port)
+	title := "未知主机"
+	text := fmt.Sprintf("无法确认主机 %s 的真实性。\n\n%s 密钥指纹:\n%s\n\n确认信任并继续连接？", hostPort, ev.KeyType, ev.Fingerprint)
+	if changed {
+		title = "警告：主机密钥已变更"
+		var old []string
+		for _, k := range ev.Known {
+			old = append(old, fmt.Sprintf("%s %s（%s:%d）", k.KeyType, k.Fingerprint, k.File, k.Line))
+		}
+		text = fmt.Sprintf("主机 %s 的密钥与已记录的不一致！\n可能有人正在进行中间人攻击，也可能是主机重装了系统。\n\n已记录:\n%s\n\n当前 %s 密钥指纹:\n%s\n\n仅在确认密钥变更合法时才替换。",
+			hostPort, strings.Join(old, "\n"), ev.KeyType, ev.Fingerprint)
+	}
+	label := widget.NewLabel(text)
+	label.Wrapping = fyne.TextWrapWord
+	confirmLabel := "信任并连接"
+	if changed {
+		confirmLabel = "替换并连接"
+	}
+	ui.ShowConfirm(window, title, label, confirmLabel, "取消", func(ok bool) {
+		retry, pending := pendingConnects.LoadAndDelete(ev.ConnID)
+		if !ok {
+			return
+		}
+		go func() {
+			_, err := api.TrustHostKey(proto.TrustHostKeyRequest{
+				Host: ev.Host, Port: ev.Port, Key: ev.Key, Hash: true, Replace: changed,
+			})
+			if err != nil {
+				fyne.Do(func() { ui.ShowError(window, fmt.Errorf("信任主机密钥失败: %w", err)) })
+				return
+			}
+			if pending {
+				retry.(func())()
+			}
+		}()
+	})
+}
+
+// showAuthPrompt 展示认证过程中后端下发的问题（私钥口令、密码或 keyboard-interactive），
+// 确认后以 auth_answer 应答；取消则中止本次认证。
+func showAuthPrompt(window fyne.Window, api *client.APIClient, ev proto.AuthPromptEvent) {
+	title := fmt.Sprintf("%s@%s 认证", ev.User, ev.Host)
+	var intro string
+	switch ev.Kind {
+	case proto.PromptPassphrase:
+		title = "私钥口令"
+		intro = "私钥已加密: " + ev.Instruction
+	case proto.PromptPassword:
+		intro = "请输入登录密码"
+	default:
+		if ev.Name != "" {
+			title = ev.Name
+		}
+		intro = ev.Instruction
+	}
+	if ev.Retry {
+		intro = "口令错误，请重试。\n" + intro
+	}
+
+	form := widget.NewForm()
+	entries := make([]*widget.Entry, len(ev.Prompts))
+	for i, p := range ev.Prompts {
+		e := widget.NewEntry()
+		if !p.Echo {
+			e = widget.NewPasswordEntry()
+		}
+		entries[i] = e
+		form.Append(strings.TrimSpace(p.Text), e)
+	}
+	content := container.NewVBox()
+	if intro != "" {
+		label := widget.NewLabel(intro)
+		label.Wrapping = fyne.TextWrapWord
+		content.Add(label)
+	}
+	content.Add(form)
+
+	ui.ShowConfirm(window, title, content, "确定", "取消", func(ok bool) {
+		req := proto.AuthAnswerRequest{PromptID: ev.PromptID, Cancel: !ok}
+		if ok {
+			for _, e := range entries {
+				req.Answers = append(req.Answers, e.Text)
+			}
+		}
+		go func() {
+			if err := api.AuthAnswer(req); err != nil {
+				fyne.Do(func() { ui.ShowError(window, fmt.Errorf("提交认证信息失败: %w", err)) })
+			}
+		}()
+	})
+	if len(entries) > 0 {
+		window.Canvas().Focus(entries[0])
+	}
 }
 
 // showConnectDialog 显示连接对话框
-func showConnectDialog(window fyne.Window, api *client.APIClient) {
-    fmt.Println("[UI] 打开连接对话框")
-
-    // 表单输入（确保遵循主题的白底黑字）
-    hostEntry := widget.NewEntry()
-    hostEntry.SetPlaceHolder("例如: 192.168.1.100")
-
-    portEntry := widget.NewEntry()
-    portEntry.SetText("22")
-
-    userEntry := widget.NewEntry()
-    userEntry.SetPlaceHolder("用户名")
-
-    passEntry := widget.NewPasswordEntry()
-    passEntry.SetPlaceHolder("密码")
-
-    keyEntry := widget.NewEntry()
-    keyEntry.SetPlaceHolder("私钥文件路径（可选）")
-
-    form := widget.NewForm(
-        widget.NewFormItem("主机", hostEntry),
-        widget.NewFormItem("端口", portEntry),
-        widget.NewFormItem("用户名", userEntry),
-        widget.NewFormItem("密码", passEntry),
-        widget.NewFormItem("私钥路径", keyEntry),
-    )
-
-    // 使用 Fyne 原生对话框，自动渲染白色面板背景与可读文本
-    ui.ShowConfirm(window, "新建连接", form, "连接", "取消", func(confirmed bool){
-        if !confirmed {
-            fmt.Println("[UI] 取消连接")
-            return
-        }
-        fmt.Printf("[UI] 尝试连接: host=%s port=%s user=%s key=%s\n", hostEntry.Text, portEntry.Text, userEntry.Text, keyEntry.Text)
-        // TODO: 实现连接逻辑，调用后端 Connect 协议
-    })
+func showConnectDialog(window fyne.Window, api *client.APIClient, tabbar *ui.TabBar) {
+	fmt.Println("[UI] 打开连接对话框")
+
+	// 表单输入（确保遵循主题的白底黑字）
+	hostEntry := widget.NewEntry()
+	hostEntry.SetPlaceHolder("例如: 192.168.1.100")
+
+	portEntry := widget.NewEntry()
+	portEntry.SetText("22")
+
+	userEntry := widget.NewEntry()
+	userEntry.SetPlaceHolder("用户名")
+
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetPlaceHolder("密码")
+
+	keyEntry := widget.NewEntry()
+	keyEntry.SetPlaceHolder("私钥文件路径（可选）")
+
+	// 受管密钥：选择后填入私钥路径
+	managedKey := widget.NewSelect(nil, nil)
+	managedKey.PlaceHolder = "从受管密钥选择（可选）"
+	go func() {
+		keys, err := api.ListKeys()
+		if err != nil {
+			fmt.Printf("[WARN] 读取受管密钥失败: %v\n", err)
+			return
+		}
+		names := make([]string, len(keys))
+		for i, k := range keys {
+			names[i] = k.Name
+		}
+		fyne.Do(func() {
+			managedKey.SetOptions(names)
+			managedKey.OnChanged = func(name string) {
+				if i := slices.Index(names, name); i >= 0 {
+					keyEntry.SetText(keys[i].Path)
+				}
+			}
+		})
+	}()
+
+	passphraseEntry := widget.NewPasswordEntry()
+	passphraseEntry.SetPlaceHolder("私钥口令（可选，加密私钥未填时连接时询问）")
+
+	// 认证方式按列出的顺序尝试；全部勾选时由后端按可用性自动选择
+	authOptions := []string{proto.AuthAgent, proto.AuthPublicKey, proto.AuthKeyboardInteractive, proto.AuthPassword}
+	authCheck := widget.NewCheckGroup(authOptions, nil)
+	authCheck.Horizontal = true
+	authCheck.SetSelected(authOptions)
+
+	// 跳板机按 ssh -J 的写法填写，各跳的密码、口令与二次验证在连接时询问
+	jumpEntry := widget.NewEntry()
+	jumpEntry.SetPlaceHolder("可选，按顺序: user@bastion:22, host2")
+
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("显示名称（默认为主机）")
+
+	saveCheck := widget.NewCheck("保存到连接管理器", nil)
+	saveCheck.SetChecked(true)
+
+	// 会话日志由后端按连接配置记录，只对保存的连接生效
+	sessionLogCheck := widget.NewCheck("记录会话日志（纯文本）", nil)
+
+	form := widget.NewForm(
+		widget.NewFormItem("名称", nameEntry),
+		widget.NewFormItem("主机", hostEntry),
+		widget.NewFormItem("端口", portEntry),
+		widget.NewFormItem("用户名", userEntry),
+		widget.NewFormItem("密码", passEntry),
+		widget.NewFormItem("受管密钥", managedKey),
+		widget.NewFormItem("私钥路径", keyEntry),
+		widget.NewFormItem("私钥口令", passphraseEntry),
+		widget.NewFormItem("认证方式", authCheck),
+		widget.NewFormItem("跳板机", jumpEntry),
+		widget.NewFormItem("", saveCheck),
+		widget.NewFormItem("", sessionLogCheck),
+	)
+
+	// 使用 Fyne 原生对话框，自动渲染白色面板背景与可读文本
+	ui.ShowConfirm(window, "新建连接", form, "连接", "取消", func(confirmed bool) {
+		if !confirmed {
+			fmt.Println("[UI] 取消连接")
+			return
+		}
+		fmt.Printf("[UI] 尝试连接: host=%s port=%s user=%s key=%s\n", hostEntry.Text, portEntry.Text, userEntry.Text, keyEntry.Text)
+		port, err := strconv.Atoi(portEntry.Text)
+		if err != nil {
+			ui.ShowError(window, fmt.Errorf("端口无效: %s", portEntry.Text))
+			return
+		}
+		profile := proto.Profile{
+			Name:       nameEntry.Text,
+			Host:       hostEntry.Text,
+			Port:       port,
+			User:       userEntry.Text,
+			Password:   passEntry.Text,
+			KeyPath:    keyEntry.Text,
+			SessionLog: sessionLogCheck.Checked,
+		}
+		jumps, err := parseJumps(jumpEntry.Text)
+		if err != nil {
+			ui.ShowError(window, err)
+			return
+		}
+		profile.Jumps = jumps
+		if len(authCheck.Selected) == 0 {
+			ui.ShowError(window, errors.New("请至少选择一种认证方式"))
+			return
+		}
+		if len(authCheck.Selected) < len(authOptions) {
+			for _, m := range authOptions {
+				if slices.Contains(authCheck.Selected, m) {
+					profile.AuthMethods = append(profile.AuthMethods, m)
+				}
+			}
+		}
+		passphrase := passphraseEntry.Text
+		var submit func(save bool)
+		submit = func(save bool) {
+			// 未保存的临时连接以 user@host:port 作为连接 ID
+			id := fmt.Sprintf("%s@%s:%d", profile.User, profile.Host, profile.Port)
+			title := profile.Name
+			if title == "" {
+				title = profile.Host
+			}
+			if save {
+				// 密码与口令由后端存入凭据库，凭据库锁定时先解锁再重试保存
+				p := profile
+				p.Passphrase = passphrase
+				saved, err := api.SaveConnection(p)
+				var apiErr *client.APIError
+				if errors.As(err, &apiErr) && apiErr.Code == proto.CodeLocked {
+					fyne.Do(func() {
+						showVaultUnlock(window, api, func(unlocked bool) {
+							// 取消解锁时仍然连接，只是不保存
+							go submit(unlocked)
+						})
+					})
+					return
+				}
+				if err != nil {
+					fyne.Do(func() { ui.ShowError(window, fmt.Errorf("保存连接失败: %w", err)) })
+				} else {
+					fmt.Printf("[UI] 已保存连接: %s (%s)\n", saved.Name, saved.ID)
+					id, title = saved.ID, saved.Name
+				}
+			}
+			openRemoteTab(window, api, tabbar, title, proto.ConnectRequest{
+				ID: id, Host: profile.Host, Port: profile.Port, User: profile.User,
+				Password: profile.Password, KeyPath: profile.KeyPath,
+				Passphrase: passphrase, AuthMethods: profile.AuthMethods,
+				Jumps: profile.Jumps,
+			})
+		}
+		go submit(saveCheck.Checked)
+	})
+}
+
+// parseJumps 解析 ssh -J 风格的跳板机列表：逗号分隔的 [user@]host[:port]，
+// 用户名与端口留空时由后端沿用目标主机的用户名与 22 端口
+func parseJumps(text string) ([]proto.JumpHost, error) {
+	var out []proto.JumpHost
+	for _, item := range strings.Split(text, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var j proto.JumpHost
+		if at := strings.LastIndex(item, "@"); at >= 0 {
+			j.User, item = item[:at], item[at+1:]
+		}
+		j.Host = strings.Trim(item, "[]")
+		if host, port, err := net.SplitHostPort(item); err == nil {
+			n, err := strconv.Atoi(port)
+			if err != nil {
+				return nil, fmt.Errorf("跳板机端口无效: %s", item)
+			}
+			j.Host, j.Port = host, n
+		}
+		if j.Host == "" {
+			return nil, fmt.Errorf("跳板机地址无效: %q", item)
+		}
+		out = append(out, j)
+	}
+	if len(out) > proto.MaxJumps {
+		return nil, fmt.Errorf("跳板机最多 %d 个", proto.MaxJumps)
+	}
+	return out, nil
+}
+
+// tunnelView 将后端转发信息转换为面板展示模型
+func tunnelView(t proto.TunnelInfo) ui.Tunnel {
+	bind := net.JoinHostPort(t.BindHost, strconv.Itoa(t.BindPort))
+	v := ui.Tunnel{
+		ID: t.ID, ConnID: t.ConnID, Active: t.Active, Total: t.Total, Failed: t.Failed,
+		BytesOut: t.BytesOut, BytesIn: t.BytesIn,
+	}
+	target := net.JoinHostPort(t.TargetHost, strconv.Itoa(t.TargetPort))
+	switch t.Kind {
+	case proto.TunnelLocal:
+		v.Kind, v.Spec = "L", bind+" → "+target
+	case proto.TunnelRemote:
+		v.Kind, v.Spec = "R", "远端 "+bind+" → "+target
+	default:
+		v.Kind, v.Spec = "D", "SOCKS5 "+bind
+	}
+	return v
+}
+
+// remoteFileView 将后端文件信息转换为 SFTP 面板的展示模型
+func remoteFileView(e proto.FileEntry) ui.RemoteFile {
+	return ui.RemoteFile{
+		Name: e.Name, Path: e.Path, IsDir: e.IsDir, IsLink: e.Type == proto.FileTypeSymlink,
+		LinkTarget: e.LinkTarget, Size: e.Size, Mode: e.Mode, Perm: e.Perm, ModTime: e.ModTime,
+	}
+}
+
+// showProcessManager 打开远端进程管理窗口：按面板选择的间隔自动刷新，关闭窗口时停止；
+// 发送信号与调整优先级前经 ShowConfirm 确认
+func showProcessManager(a fyne.App, api *client.APIClient, connID, host string) {
+	win := a.NewWindow("进程管理 · " + host)
+	win.Resize(fyne.NewSize(1000, 600))
+	var panel *ui.ProcessesPanel
+	load := func() {
+		req := proto.ListProcessesRequest{ConnID: connID, Sort: panel.Query().Sort, Filter: panel.Query().Filter}
+		go func() {
+			res, err := api.ListProcesses(req)
+			fyne.Do(func() {
+				if err != nil {
+					panel.SetError(err)
+					return
+				}
+				items := make([]ui.Process, len(res.Processes))
+				for i, p := range res.Processes {
+					items[i] = ui.Process{
+						PID: p.PID, User: p.User, CPU: p.CPU, Mem: p.Mem, RSS: int64(p.RSS),
+						Nice: p.Nice, State: p.State, Command: p.Command, Started: p.StartTime,
+					}
+				}
+				panel.Set(items, fmt.Sprintf("共 %d 个进程 · 更新于 %s", res.Total, time.Now().Format("15:04:05")))
+			})
+		}()
+	}
+	// act 执行信号或优先级调整，逐个报告失败后刷新列表
+	act := func(what string, op func() ([]proto.ProcessActionResult, error)) {
+		go func() {
+			results, err := op()
+			var failed []string
+			for _, r := range results {
+				if r.Error != "" {
+					failed = append(failed, fmt.Sprintf("%d: %s", r.PID, r.Error))
+				}
+			}
+			if err == nil && len(failed) > 0 {
+				err = errors.New(strings.Join(failed, "\n"))
+			}
+			fyne.Do(func() {
+				if err != nil {
+					ui.ShowError(win, fmt.Errorf("%s失败: %w", what, err))
+				}
+				load()
+			})
+		}()
+	}
+	interval := make(chan time.Duration, 1)
+	panel = ui.NewProcessesPanel(ui.ProcessesPanelProps{
+		Signals:    proto.ProcessSignals,
+		OnQuery:    func(ui.ProcessQuery) { load() },
+		OnInterval: func(d time.Duration) { interval <- d },
+		OnSignal: func(p ui.Process, sig string) {
+			msg := fmt.Sprintf("确定向进程 %d（%s）发送 SIG%s 吗？", p.PID, p.Command, sig)
+			content := widget.NewLabel(msg)
+			content.Wrapping = fyne.TextWrapWord
+			ui.ShowConfirm(win, "发送信号", content, "发送", "取消", func(ok bool) {
+				if ok {
+					act("发送信号", func() ([]proto.ProcessActionResult, error) {
+						return api.SignalProcess(connID, []int{p.PID}, sig)
+					})
+				}
+			})
+		},
+		OnRenice: func(p ui.Process) {
+			entry := widget.NewEntry()
+			entry.SetText(strconv.Itoa(p.Nice))
+			hint := widget.NewLabel(fmt.Sprintf("进程 %d（%s）\n范围 -20（最高）至 19（最低），降低 nice 值通常需要 root 权限。", p.PID, p.Command))
+			hint.Wrapping = fyne.TextWrapWord
+			form := container.NewVBox(hint, widget.NewForm(widget.NewFormItem("nice", entry)))
+			ui.ShowConfirm(win, "调整优先级", form, "确定", "取消", func(ok bool) {
+				if !ok {
+					return
+				}
+				nice, err := strconv.Atoi(strings.TrimSpace(entry.Text))
+				if err != nil || nice < -20 || nice > 19 {
+					ui.ShowError(win, errors.New("nice 值须为 -20 至 19 的整数"))
+					return
+				}
+				act("调整优先级", func() ([]proto.ProcessActionResult, error) {
+					return api.ReniceProcess(connID, []int{p.PID}, nice)
+				})
+			})
+		},
+	})
+
+	// 自动刷新：间隔变化时重置定时器，窗口关闭时退出
+	closed := make(chan struct{})
+	go func() {
+		t := time.NewTicker(time.Hour)
+		t.Stop()
+		defer t.Stop()
+		if d := panel.Interval(); d > 0 {
+			t.Reset(d)
+		}
+		for {
+			select {
+			case <-closed:
+				return
+			case d := <-interval:
+				t.Stop()
+				if d > 0 {
+					t.Reset(d)
+				}
+			case <-t.C:
+				fyne.Do(load)
+			}
+		}
+	}()
+	win.SetOnClosed(func() { close(closed) })
+	win.SetContent(panel.Object())
+	win.Show()
+	load()
+}
+
+// showBatchExec 打开批量执行窗口：目标取自保存的连接配置，任务 ID 由前端生成，
+// 以便在 multi_execute 响应到达前就能按 ID 认领 execute_result 事件；关闭窗口时取消订阅
+func showBatchExec(a fyne.App, api *client.APIClient) {
+	win := a.NewWindow("批量执行")
+	win.Resize(fyne.NewSize(1100, 700))
+	var (
+		panel *ui.BatchExecPanel
+		mu    sync.Mutex
+		jobID string
+	)
+	panel = ui.NewBatchExecPanel(ui.BatchExecPanelProps{
+		OnRun: func(r ui.BatchRun) {
+			id := fmt.Sprintf("batch-%d", time.Now().UnixNano())
+			mu.Lock()
+			jobID = id
+			mu.Unlock()
+			req := proto.MultiExecuteRequest{
+				ID: id, ProfileIDs: r.ProfileIDs, Command: r.Command,
+				TimeoutMs: int(r.Timeout.Milliseconds()), Concurrency: r.Concurrency,
+			}
+			go func() {
+				resp, err := api.MultiExecute(req)
+				fyne.Do(func() {
+					if err != nil {
+						panel.Fail(err)
+						return
+					}
+					fmt.Printf("[UI] 批量执行 %s：%d 台主机\n", resp.ID, len(resp.Hosts))
+				})
+			}()
+		},
+		OnCancel: func() {
+			mu.Lock()
+			id := jobID
+			mu.Unlock()
+			go cancelBatch(api, id)
+		},
+	})
+	stream := api.Subscribe([]string{proto.EventExecuteResult}, func(ev proto.Frame) {
+		var r proto.ExecuteResult
+		if err := json.Unmarshal(ev.Data, &r); err != nil {
+			fmt.Printf("[WARN] 解析批量执行事件失败: %v\n", err)
+			return
+		}
+		mu.Lock()
+		mine := r.JobID == jobID
+		mu.Unlock()
+		if mine {
+			fyne.Do(func() { panel.Update(batchResultView(r)) })
+		}
+	})
+	win.SetOnClosed(func() {
+		stream.Close()
+		// 关闭窗口后无法再查看结果，取消仍在进行的任务
+		mu.Lock()
+		id := jobID
+		mu.Unlock()
+		go cancelBatch(api, id)
+	})
+	go func() {
+		profiles, err := api.ListProfiles()
+		fyne.Do(func() {
+			if err != nil {
+				panel.Fail(fmt.Errorf("获取连接列表失败: %w", err))
+				return
+			}
+			targets := make([]ui.BatchTarget, len(profiles))
+			for i, p := range profiles {
+				port := p.Port
+				if port == 0 {
+					port = 22
+				}
+				targets[i] = ui.BatchTarget{ID: p.ID, Name: p.Name, Folder: p.Folder, Host: fmt.Sprintf("%s@%s:%d", p.User, p.Host, port)}
+			}
+			panel.SetTargets(targets, nil)
+		})
+	}()
+	win.SetContent(panel.Object())
+	win.Show()
+}
+
+// cancelBatch 取消批量执行任务；任务已结束时后端返回 400，忽略即可
+func cancelBatch(api *client.APIClient, id string) {
+	if id == "" {
+		return
+	}
+	if _, err := api.CancelMultiExecute(id); err == nil {
+		fmt.Printf("[UI] 已取消批量执行 %s\n", id)
+	}
+}
+
+// outputText 还原 execute 返回的输出：base64 编码的二进制输出解码后把非法字节替换为 U+FFFD
+func outputText(s, encoding string) string {
+	if encoding != proto.EncodingBase64 {
+		return s
+	}
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return s
+	}
+	return strings.ToValidUTF8(string(b), "\uFFFD")
+}
+
+// batchResultView 将 execute_result 事件转换为批量执行面板的展示模型
+func batchResultView(r proto.ExecuteResult) ui.BatchResult {
+	v := ui.BatchResult{ID: r.ProfileID, Name: r.Name, Host: r.Host, State: r.State, Error: r.Error}
+	if res := r.Result; res != nil {
+		v.ExitCode = res.ExitCode
+		v.Stdout, v.Stderr = outputText(res.Stdout, res.StdoutEncoding), outputText(res.Stderr, res.StderrEncoding)
+		v.Duration = time.Duration(res.DurationMs) * time.Millisecond
+		switch {
+		case res.Error != "":
+			v.Error = res.Error
+		case res.Signal != "":
+			v.Error = "signal " + res.Signal
+		}
+	}
+	return v
+}
+
+// showRecordings 打开会话录像列表窗口，回放时下载整个 .cast 文件并在新窗口中播放
+func showRecordings(a fyne.App, api *client.APIClient) {
+	win := a.NewWindow("会话录像")
+	win.Resize(fyne.NewSize(760, 480))
+	var panel *ui.RecordingsPanel
+	load := func() {
+		go func() {
+			list, err := api.ListRecordings("")
+			fyne.Do(func() {
+				if err != nil {
+					panel.SetError(err)
+					return
+				}
+				items := make([]ui.Recording, len(list))
+				for i, r := range list {
+					items[i] = ui.Recording{
+						ID: r.ID, Host: r.Host, Started: r.StartedAt, Duration: r.Duration,
+						Size: r.Size, Input: r.Input, Active: r.Active,
+					}
+				}
+				panel.Set(items)
+			})
+		}()
+	}
+	panel = ui.NewRecordingsPanel(ui.RecordingsPanelProps{
+		OnRefresh: load,
+		OnPlay: func(r ui.Recording) {
+			go func() {
+				_, data, err := api.GetRecording(r.ID)
+				var cast ui.Cast
+				if err == nil {
+					cast, err = ui.ParseCast(data)
+				}
+				fyne.Do(func() {
+					if err != nil {
+						ui.ShowError(win, fmt.Errorf("读取录像失败: %w", err))
+						return
+					}
+					replay := ui.NewReplayPanel(cast)
+					rw := a.NewWindow("回放 · " + r.Host + " · " + r.Started.Local().Format("2006-01-02 15:04:05"))
+					rw.Resize(fyne.NewSize(900, 600))
+					rw.SetOnClosed(replay.Close)
+					rw.SetContent(replay.Object())
+					rw.Show()
+				})
+			}()
+		},
+	})
+	win.SetContent(panel.Object())
+	win.Show()
+	load()
+}
+
+// showExportSession 选择格式后将当前标签的会话另存为文件，默认文件名为 <标题>-<时间>
+func showExportSession(window fyne.Window, tabbar *ui.TabBar) {
+	title := tabbar.CurrentTitle()
+	if title == "" {
+		ui.ShowInfo(window, "导出会话", "没有打开的标签")
+		return
+	}
+	formats := []string{"纯文本", "HTML"}
+	format := widget.NewRadioGroup(formats, nil)
+	format.SetSelected(formats[0])
+	format.Required = true
+	ui.ShowConfirm(window, "导出会话", format, "导出", "取消", func(ok bool) {
+		if !ok {
+			return
+		}
+		html := format.Selected == "HTML"
+		content, err := tabbar.ExportCurrent(html)
+		if err != nil {
+			ui.ShowError(window, err)
+			return
+		}
+		ext := ".txt"
+		if html {
+			ext = ".html"
+		}
+		name := strings.Map(func(r rune) rune {
+			if strings.ContainsRune(`/\:*?"<>|`, r) {
+				return '_'
+			}
+			return r
+		}, title) + "-" + time.Now().Format("20060102-150405") + ext
+		d := dialog.NewFileSave(func(wc fyne.URIWriteCloser, err error) {
+			if err != nil {
+				ui.ShowError(window, err)
+				return
+			}
+			if wc == nil {
+				return
+			}
+			_, err = wc.Write([]byte(content))
+			if cerr := wc.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("导出会话失败: %w", err))
+				return
+			}
+			fmt.Printf("[UI] 会话已导出到 %s\n", wc.URI().Path())
+		}, window)
+		d.SetFileName(name)
+		d.Show()
+	})
+}
+
+// showSFTPPrompt 询问单行输入（文件名、权限等），输入为空时不回调
+func showSFTPPrompt(window fyne.Window, title, label, initial string, done func(value string)) {
+	entry := widget.NewEntry()
+	entry.SetText(initial)
+	form := widget.NewForm(widget.NewFormItem(label, entry))
+	ui.ShowConfirm(window, title, form, "确定", "取消", func(ok bool) {
+		if v := strings.TrimSpace(entry.Text); ok && v != "" {
+			done(v)
+		}
+	})
+}
+
+// showSFTPSymlink 在 dir 下创建符号链接；从文件上发起时以其为目标并预填链接名
+func showSFTPSymlink(window fyne.Window, dir string, target *ui.RemoteFile, done func(target, link string)) {
+	targetEntry := widget.NewEntry()
+	targetEntry.SetPlaceHolder("链接指向的路径，可为相对路径")
+	linkEntry := widget.NewEntry()
+	linkEntry.SetPlaceHolder("链接名称")
+	if target != nil {
+		targetEntry.SetText(target.Name)
+		linkEntry.SetText(target.Name + ".link")
+	}
+	form := widget.NewForm(
+		widget.NewFormItem("目标", targetEntry),
+		widget.NewFormItem("链接", linkEntry),
+	)
+	ui.ShowConfirm(window, "新建符号链接", form, "创建", "取消", func(ok bool) {
+		t, l := strings.TrimSpace(targetEntry.Text), strings.TrimSpace(linkEntry.Text)
+		if !ok || t == "" || l == "" {
+			return
+		}
+		if !path.IsAbs(l) {
+			l = path.Join(dir, l)
+		}
+		done(t, l)
+	})
+}
+
+// transferView 将后端传输状态转换为传输面板的展示模型
+func transferView(t proto.TransferInfo) ui.Transfer {
+	v := ui.Transfer{ID: t.ID, Active: t.State == proto.TransferQueued || t.State == proto.TransferRunning}
+	if t.Direction == proto.TransferUpload {
+		v.Title = fmt.Sprintf("↑ %s → %s", filepath.Base(t.LocalPath), t.RemotePath)
+	} else {
+		v.Title = fmt.Sprintf("↓ %s → %s", path.Base(t.RemotePath), t.LocalPath)
+	}
+	if t.Bytes > 0 {
+		v.Progress = float64(t.Done) / float64(t.Bytes)
+	}
+	size := fmt.Sprintf("%s / %s", ui.FormatBytes(t.Done), ui.FormatBytes(t.Bytes))
+	switch t.State {
+	case proto.TransferQueued:
+		v.Status = "排队中"
+	case proto.TransferRunning:
+		if t.Files == 0 {
+			v.Status = "正在扫描文件…"
+			break
+		}
+		v.Status = fmt.Sprintf("%s · %s/s · %d/%d 个文件", size, ui.FormatBytes(t.BytesPerSec), t.FilesDone, t.Files)
+		if t.Current != "" {
+			v.Status += " · " + t.Current
+		}
+	case proto.TransferCompleted:
+		v.Progress = 1
+		v.Status = fmt.Sprintf("已完成 · %d 个文件 · %s", t.Files, ui.FormatBytes(t.Bytes))
+		if t.Resumed > 0 {
+			v.Status += " · 续传 " + ui.FormatBytes(t.Resumed)
+		}
+		if t.Verify {
+			v.Status += " · SHA-256 已校验"
+		}
+	case proto.TransferCanceled:
+		v.Status = "已取消 · " + size + "，可勾选续传重新开始"
+	default:
+		v.Status = "失败: " + t.Error
+	}
+	return v
+}
+
+// downloadDir 下载的默认本机目录：存在时为 ~/Downloads，否则为主目录
+func downloadDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return os.TempDir()
+	}
+	if st, err := os.Stat(filepath.Join(home, "Downloads")); err == nil && st.IsDir() {
+		return filepath.Join(home, "Downloads")
+	}
+	return home
+}
+
+// showStartTransfer 补全本机与远端路径、过滤规则与续传/校验选项后加入传输队列。
+// 上传时选择本机文件夹会把远端路径改为其下的同名目录，下载时选择文件夹则放入其中
+func showStartTransfer(window fyne.Window, api *client.APIClient, req proto.TransferRequest, started func()) {
+	if req.ConnID == "" {
+		ui.ShowInfo(window, "文件传输", "当前标签未连接远程主机")
+		return
+	}
+	upload := req.Direction == proto.TransferUpload
+	baseRemote := req.RemotePath
+	localEntry := widget.NewEntry()
+	localEntry.SetText(req.LocalPath)
+	localEntry.SetPlaceHolder("本机绝对路径，文件或文件夹")
+	remoteEntry := widget.NewEntry()
+	remoteEntry.SetText(req.RemotePath)
+	pickFile := widget.NewButton("选择文件", func() {
+		dialog.ShowFileOpen(func(r fyne.URIReadCloser, err error) {
+			if err != nil || r == nil {
+				return
+			}
+			defer r.Close()
+			localEntry.SetText(r.URI().Path())
+		}, window)
+	})
+	pickFolder := widget.NewButton("选择文件夹", func() {
+		dialog.ShowFolderOpen(func(l fyne.ListableURI, err error) {
+			if err != nil || l == nil {
+				return
+			}
+			if upload {
+				localEntry.SetText(l.Path())
+				remoteEntry.SetText(path.Join(baseRemote, filepath.Base(l.Path())))
+			} else {
+				localEntry.SetText(filepath.Join(l.Path(), path.Base(req.RemotePath)))
+			}
+		}, window)
+	})
+	if !upload {
+		pickFile.Hide()
+	}
+	include := widget.NewEntry()
+	include.SetPlaceHolder("例如 *.go, src/*（逗号分隔，留空为全部）")
+	exclude := widget.NewEntry()
+	exclude.SetPlaceHolder("例如 .git, node_modules, *.tmp")
+	resume := widget.NewCheck("目标已有部分文件时续传", nil)
+	resume.SetChecked(true)
+	verify := widget.NewCheck("完成后校验 SHA-256", nil)
+	verify.SetChecked(true)
+	form := widget.NewForm(
+		widget.NewFormItem("本机路径", container.NewBorder(nil, nil, nil, container.NewHBox(pickFile, pickFolder), localEntry)),
+		widget.NewFormItem("远端路径", remoteEntry),
+		widget.NewFormItem("包含", include),
+		widget.NewFormItem("排除", exclude),
+		widget.NewFormItem("", resume),
+		widget.NewFormItem("", verify),
+	)
+	title := "上传到 " + req.RemotePath
+	if !upload {
+		title = "下载 " + req.RemotePath
+	}
+	dlg := ui.ShowConfirm(window, title, form, "开始", "取消", func(ok bool) {
+		if !ok {
+			return
+		}
+		req.LocalPath = strings.TrimSpace(localEntry.Text)
+		req.RemotePath = strings.TrimSpace(remoteEntry.Text)
+		req.Include = splitGlobs(include.Text)
+		req.Exclude = splitGlobs(exclude.Text)
+		req.Resume = resume.Checked
+		req.Verify = verify.Checked
+		go func() {
+			t, err := api.StartTransfer(req)
+			fyne.Do(func() {
+				if err != nil {
+					ui.ShowError(window, fmt.Errorf("开始传输失败: %w", err))
+					return
+				}
+				fmt.Printf("[UI] 已加入传输队列: %s\n", t.ID)
+				started()
+			})
+		}()
+	})
+	dlg.Resize(fyne.NewSize(620, 460))
+}
+
+// splitGlobs 解析逗号分隔的 glob 列表
+func splitGlobs(text string) []string {
+	var out []string
+	for _, g := range strings.Split(text, ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			out = append(out, g)
+		}
+	}
+	return out
+}
+
+// showCreateTunnel 选择已连接的主机并创建端口转发；监听端口填 0 时由系统分配
+func showCreateTunnel(window fyne.Window, api *client.APIClient) {
+	go func() {
+		conns, err := api.ListConnections()
+		fyne.Do(func() {
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("读取连接列表失败: %w", err))
+				return
+			}
+			var ids, labels []string
+			for _, c := range conns {
+				if c.State == proto.StateConnected {
+					ids = append(ids, c.ID)
+					labels = append(labels, fmt.Sprintf("%s@%s:%d (%s)", c.User, c.Host, c.Port, c.ID))
+				}
+			}
+			if len(ids) == 0 {
+				ui.ShowInfo(window, "端口转发", "没有已连接的主机，请先打开一个远程终端")
+				return
+			}
+			hostSelect := widget.NewSelect(labels, nil)
+			hostSelect.SetSelectedIndex(0)
+			kinds := []string{"本地转发 (-L)", "远程转发 (-R)", "动态转发 SOCKS5 (-D)"}
+			kindValues := []string{proto.TunnelLocal, proto.TunnelRemote, proto.TunnelDynamic}
+			bindHost := widget.NewEntry()
+			bindHost.SetText("127.0.0.1")
+			bindPort := widget.NewEntry()
+			bindPort.SetPlaceHolder("0 为自动分配")
+			targetHost := widget.NewEntry()
+			targetHost.SetPlaceHolder("例如 127.0.0.1 或 db.internal")
+			targetPort := widget.NewEntry()
+			kindSelect := widget.NewSelect(kinds, func(s string) {
+				if s == kinds[2] {
+					targetHost.Disable()
+					targetPort.Disable()
+				} else {
+					targetHost.Enable()
+					targetPort.Enable()
+				}
+			})
+			kindSelect.SetSelectedIndex(0)
+			form := widget.NewForm(
+				widget.NewFormItem("连接", hostSelect),
+				widget.NewFormItem("类型", kindSelect),
+				widget.NewFormItem("监听地址", bindHost),
+				widget.NewFormItem("监听端口", bindPort),
+				widget.NewFormItem("目标主机", targetHost),
+				widget.NewFormItem("目标端口", targetPort),
+			)
+			ui.ShowConfirm(window, "新建端口转发", form, "创建", "取消", func(ok bool) {
+				if !ok || hostSelect.SelectedIndex() < 0 || kindSelect.SelectedIndex() < 0 {
+					return
+				}
+				req := proto.TunnelRequest{
+					ConnID: ids[hostSelect.SelectedIndex()], Kind: kindValues[kindSelect.SelectedIndex()],
+					BindHost: bindHost.Text, TargetHost: targetHost.Text,
+				}
+				var err error
+				if bindPort.Text != "" {
+					if req.BindPort, err = strconv.Atoi(bindPort.Text); err != nil {
+						ui.ShowError(window, fmt.Errorf("监听端口无效: %s", bindPort.Text))
+						return
+					}
+				}
+				if req.Kind != proto.TunnelDynamic {
+					if req.TargetPort, err = strconv.Atoi(targetPort.Text); err != nil {
+						ui.ShowError(window, fmt.Errorf("目标端口无效: %s", targetPort.Text))
+						return
+					}
+				}
+				go func() {
+					t, err := api.CreateTunnel(req)
+					fyne.Do(func() {
+						if err != nil {
+							ui.ShowError(window, fmt.Errorf("创建转发失败: %w", err))
+							return
+						}
+						fmt.Printf("[UI] 已创建转发: %s %s:%d\n", t.ID, t.BindHost, t.BindPort)
+					})
+				}()
+			})
+		})
+	}()
+}
+
+// showVaultUnlock 凭据库锁定时询问主密码；尚未初始化时引导设置主密码（需输入两次）。
+// 主密码错误时重新询问；done 在主线程外调用，unlocked 表示是否已解锁。
+func showVaultUnlock(window fyne.Window, api *client.APIClient, done func(unlocked bool)) {
+	go func() {
+		st, err := api.VaultStatus()
+		fyne.Do(func() {
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("读取凭据库状态失败: %w", err))
+				go done(false)
+				return
+			}
+			if !st.Locked {
+				go done(true)
+				return
+			}
+			pass := widget.NewPasswordEntry()
+			confirm := widget.NewPasswordEntry()
+			form := widget.NewForm(widget.NewFormItem("主密码", pass))
+			title, intro := "解锁凭据库", "已保存的密码与私钥口令需要主密码解锁。"
+			if !st.Initialized {
+				title, intro = "设置主密码", "首次保存密码需要设置凭据库主密码，主密码遗忘后已保存的密码无法恢复。"
+				form.Append("确认主密码", confirm)
+			}
+			label := widget.NewLabel(intro)
+			label.Wrapping = fyne.TextWrapWord
+			ui.ShowConfirm(window, title, container.NewVBox(label, form), "确定", "取消", func(ok bool) {
+				if !ok {
+					go done(false)
+					return
+				}
+				if !st.Initialized && pass.Text != confirm.Text {
+					ui.ShowError(window, errors.New("两次输入的主密码不一致"))
+					showVaultUnlock(window, api, done)
+					return
+				}
+				go func() {
+					var err error
+					if st.Initialized {
+						_, err = api.VaultUnlock(pass.Text)
+					} else {
+						_, err = api.VaultInit(pass.Text)
+					}
+					if err != nil {
+						fyne.Do(func() {
+							ui.ShowError(window, fmt.Errorf("%s失败: %w", title, err))
+							showVaultUnlock(window, api, done)
+						})
+						return
+					}
+					done(true)
+				}()
+			})
+			window.Canvas().Focus(pass)
+		})
+	}()
+}
+
+// showVaultDialog 展示凭据库状态，提供解锁、锁定与修改主密码
+func showVaultDialog(window fyne.Window, api *client.APIClient) {
+	go func() {
+		st, err := api.VaultStatus()
+		fyne.Do(func() {
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("读取凭据库状态失败: %w", err))
+				return
+			}
+			state := "已锁定"
+			switch {
+			case !st.Initialized:
+				state = "未设置主密码"
+			case !st.Locked:
+				state = "已解锁"
+			}
+			idle := "不自动锁定"
+			if st.IdleSeconds > 0 {
+				idle = fmt.Sprintf("空闲 %d 分钟后自动锁定", st.IdleSeconds/60)
+			}
+			info := widget.NewLabel(fmt.Sprintf("状态: %s\n已保存凭据: %d 条\n%s", state, st.Secrets, idle))
+
+			var d dialog.Dialog
+			unlockBtn := widget.NewButton("解锁", func() {
+				d.Hide()
+				showVaultUnlock(window, api, func(bool) {})
+			})
+			if !st.Initialized {
+				unlockBtn.SetText("设置主密码")
+			}
+			lockBtn := widget.NewButton("立即锁定", func() {
+				d.Hide()
+				go func() {
+					if _, err := api.VaultLock(); err != nil {
+						fyne.Do(func() { ui.ShowError(window, fmt.Errorf("锁定凭据库失败: %w", err)) })
+					}
+				}()
+			})
+			changeBtn := widget.NewButton("修改主密码", func() {
+				d.Hide()
+				showVaultChangeMaster(window, api)
+			})
+			if st.Locked {
+				lockBtn.Disable()
+			} else {
+				unlockBtn.Disable()
+			}
+			if !st.Initialized {
+				changeBtn.Disable()
+			}
+			d = ui.ShowCustom(window, "凭据库", "关闭", container.NewVBox(info, container.NewHBox(unlockBtn, lockBtn, changeBtn)))
+		})
+	}()
+}
+
+// showVaultChangeMaster 修改主密码：校验旧密码，新密码需输入两次
+func showVaultChangeMaster(window fyne.Window, api *client.APIClient) {
+	oldEntry := widget.NewPasswordEntry()
+	newEntry := widget.NewPasswordEntry()
+	confirmEntry := widget.NewPasswordEntry()
+	form := widget.NewForm(
+		widget.NewFormItem("当前主密码", oldEntry),
+		widget.NewFormItem("新主密码", newEntry),
+		widget.NewFormItem("确认新主密码", confirmEntry),
+	)
+	ui.ShowConfirm(window, "修改主密码", form, "修改", "取消", func(ok bool) {
+		if !ok {
+			return
+		}
+		if newEntry.Text != confirmEntry.Text {
+			ui.ShowError(window, errors.New("两次输入的新主密码不一致"))
+			return
+		}
+		go func() {
+			if _, err := api.VaultChangeMaster(oldEntry.Text, newEntry.Text); err != nil {
+				fyne.Do(func() { ui.ShowError(window, fmt.Errorf("修改主密码失败: %w", err)) })
+				return
+			}
+			fyne.Do(func() { ui.ShowInfo(window, "凭据库", "主密码已修改") })
+		}()
+	})
+}
+
+// showKeysDialog 列出受管密钥，提供生成、导入、复制公钥、安装到主机与删除
+func showKeysDialog(window fyne.Window, api *client.APIClient) {
+	go func() {
+		keys, err := api.ListKeys()
+		fyne.Do(func() {
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("读取密钥列表失败: %w", err))
+				return
+			}
+			selected := -1
+			list := widget.NewList(
+				func() int { return len(keys) },
+				func() fyne.CanvasObject { return widget.NewLabel("") },
+				func(i widget.ListItemID, o fyne.CanvasObject) {
+					k := keys[i]
+					text := fmt.Sprintf("%s  %s %d  %s", k.Name, k.Type, k.Bits, k.Fingerprint)
+					if k.Encrypted {
+						text += "  [加密]"
+					}
+					if k.Comment != "" {
+						text += "  " + k.Comment
+					}
+					o.(*widget.Label).SetText(text)
+				},
+			)
+			var d dialog.Dialog
+			copyBtn := widget.NewButton("复制公钥", func() {
+				if selected < 0 {
+					return
+				}
+				window.Clipboard().SetContent(keys[selected].PublicKey)
+				ui.ShowInfo(window, "密钥", "公钥已复制到剪贴板")
+			})
+			installBtn := widget.NewButton("安装到主机", func() {
+				if selected < 0 {
+					return
+				}
+				showInstallKey(window, api, keys[selected].Name)
+			})
+			deleteBtn := widget.NewButton("删除", func() {
+				if selected < 0 {
+					return
+				}
+				name := keys[selected].Name
+				msg := widget.NewLabel(fmt.Sprintf("确定删除密钥 %s？私钥文件将被删除且无法恢复。", name))
+				ui.ShowConfirm(window, "删除密钥", msg, "删除", "取消", func(ok bool) {
+					if !ok {
+						return
+					}
+					go func() {
+						if err := api.DeleteKey(name); err != nil {
+							fyne.Do(func() { ui.ShowError(window, fmt.Errorf("删除密钥失败: %w", err)) })
+							return
+						}
+						fyne.Do(func() {
+							d.Hide()
+							showKeysDialog(window, api)
+						})
+					}()
+				})
+			})
+			selectionBtns := []*widget.Button{copyBtn, installBtn, deleteBtn}
+			for _, b := range selectionBtns {
+				b.Disable()
+			}
+			list.OnSelected = func(id widget.ListItemID) {
+				selected = id
+				for _, b := range selectionBtns {
+					b.Enable()
+				}
+			}
+			reopen := func() {
+				d.Hide()
+				showKeysDialog(window, api)
+			}
+			generateBtn := widget.NewButton("生成", func() { showGenerateKey(window, api, reopen) })
+			importBtn := widget.NewButton("导入", func() { showImportKey(window, api, reopen) })
+
+			var body fyne.CanvasObject = list
+			if len(keys) == 0 {
+				body = widget.NewLabel("暂无受管密钥")
+			}
+			buttons := container.NewHBox(generateBtn, importBtn, copyBtn, installBtn, deleteBtn)
+			content := container.NewBorder(nil, buttons, nil, nil, body)
+			d = ui.ShowCustom(window, "密钥管理", "关闭", content)
+			d.Resize(fyne.NewSize(760, 420))
+		})
+	}()
+}
+
+// showGenerateKey 生成密钥表单；位数仅对 ecdsa 与 rsa 有效，留空使用默认值
+func showGenerateKey(window fyne.Window, api *client.APIClient, done func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("文件名，例如 id_ed25519_work")
+	typeSelect := widget.NewSelect([]string{proto.KeyTypeEd25519, proto.KeyTypeECDSA, proto.KeyTypeRSA}, nil)
+	typeSelect.SetSelected(proto.KeyTypeEd25519)
+	bitsEntry := widget.NewEntry()
+	bitsEntry.SetPlaceHolder("默认：ecdsa 256 / rsa 3072")
+	commentEntry := widget.NewEntry()
+	commentEntry.SetPlaceHolder("注释（可选）")
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetPlaceHolder("私钥口令（可选）")
+	form := widget.NewForm(
+		widget.NewFormItem("名称", nameEntry),
+		widget.NewFormItem("类型", typeSelect),
+		widget.NewFormItem("位数", bitsEntry),
+		widget.NewFormItem("注释", commentEntry),
+		widget.NewFormItem("口令", passEntry),
+	)
+	ui.ShowConfirm(window, "生成密钥", form, "生成", "取消", func(ok bool) {
+		if !ok {
+			return
+		}
+		req := proto.GenerateKeyRequest{Name: nameEntry.Text, Type: typeSelect.Selected, Comment: commentEntry.Text, Passphrase: passEntry.Text}
+		if bitsEntry.Text != "" {
+			bits, err := strconv.Atoi(bitsEntry.Text)
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("位数无效: %s", bitsEntry.Text))
+				return
+			}
+			req.Bits = bits
+		}
+		go func() {
+			k, err := api.GenerateKey(req)
+			fyne.Do(func() {
+				if err != nil {
+					ui.ShowError(window, fmt.Errorf("生成密钥失败: %w", err))
+					return
+				}
+				fmt.Printf("[UI] 已生成密钥: %s %s\n", k.Name, k.Fingerprint)
+				done()
+			})
+		}()
+	})
+}
+
+// showImportKey 从本机私钥文件导入；旧式 PEM 加密私钥需要口令才能读取公钥
+func showImportKey(window fyne.Window, api *client.APIClient, done func()) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetPlaceHolder("受管名称")
+	pathEntry := widget.NewEntry()
+	pathEntry.SetPlaceHolder("例如 ~/.ssh/id_ed25519")
+	passEntry := widget.NewPasswordEntry()
+	passEntry.SetPlaceHolder("私钥口令（仅旧式 PEM 加密私钥需要）")
+	form := widget.NewForm(
+		widget.NewFormItem("名称", nameEntry),
+		widget.NewFormItem("私钥路径", pathEntry),
+		widget.NewFormItem("口令", passEntry),
+	)
+	ui.ShowConfirm(window, "导入密钥", form, "导入", "取消", func(ok bool) {
+		if !ok {
+			return
+		}
+		req := proto.ImportKeyRequest{Name: nameEntry.Text, Path: pathEntry.Text, Passphrase: passEntry.Text}
+		go func() {
+			k, err := api.ImportKey(req)
+			fyne.Do(func() {
+				if err != nil {
+					ui.ShowError(window, fmt.Errorf("导入密钥失败: %w", err))
+					return
+				}
+				fmt.Printf("[UI] 已导入密钥: %s %s\n", k.Name, k.Fingerprint)
+				done()
+			})
+		}()
+	})
+}
+
+// showInstallKey 选择一个已连接的主机，把公钥追加到其 ~/.ssh/authorized_keys
+func showInstallKey(window fyne.Window, api *client.APIClient, name string) {
+	go func() {
+		conns, err := api.ListConnections()
+		fyne.Do(func() {
+			if err != nil {
+				ui.ShowError(window, fmt.Errorf("读取连接列表失败: %w", err))
+				return
+			}
+			var ids, labels []string
+			for _, c := range conns {
+				if c.State == proto.StateConnected {
+					ids = append(ids, c.ID)
+					labels = append(labels, fmt.Sprintf("%s@%s:%d (%s)", c.User, c.Host, c.Port, c.ID))
+				}
+			}
+			if len(ids) == 0 {
+				ui.ShowInfo(window, "安装公钥", "没有已连接的主机，请先打开一个远程终端")
+				return
+			}
+			hostSelect := widget.NewSelect(labels, nil)
+			hostSelect.SetSelectedIndex(0)
+			form := widget.NewForm(widget.NewFormItem("主机", hostSelect))
+			ui.ShowConfirm(window, "安装公钥 "+name, form, "安装", "取消", func(ok bool) {
+				if !ok || hostSelect.SelectedIndex() < 0 {
+					return
+				}
+				connID := ids[hostSelect.SelectedIndex()]
+				go func() {
+					added, err := api.InstallPublicKey(connID, name)
+					fyne.Do(func() {
+						switch {
+						case err != nil:
+							ui.ShowError(window, fmt.Errorf("安装公钥失败: %w", err))
+						case added:
+							ui.ShowInfo(window, "安装公钥", "公钥已追加到远端 ~/.ssh/authorized_keys")
+						default:
+							ui.ShowInfo(window, "安装公钥", "远端 authorized_keys 中已存在该公钥")
+						}
+					})
+				}()
+			})
+		})
+	}()
 }
@@ -0,0 +1,333 @@
+package ui
+
+// ANSI rendering for session export: the raw output captured from a remote
+// terminal is replayed into a simple line model (no cursor addressing, only
+// carriage return, backspace, erase-to-end-of-line and SGR colours) and then
+// written out as plain text or as a self-contained HTML page.
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ansiStyle struct {
+	fg, bg                           string // CSS colours, "" for the default
+	bold, italic, underline, reverse bool
+}
+
+type ansiCell struct {
+	r  rune
+	st ansiStyle
+}
+
+// ansiDoc is the line model output is replayed into.
+type ansiDoc struct {
+	lines  [][]ansiCell
+	line   []ansiCell
+	col    int
+	st     ansiStyle
+	state  int
+	params []byte
+}
+
+const (
+	docGround = iota
+	docEsc
+	docEscInter
+	docCSI
+	docCSIDiscard // CSI with more than maxCSIParams bytes, dropped up to its final byte
+	docOSC
+	docString // DCS, SOS, PM and APC, terminated by ST
+	docStringEsc
+)
+
+// maxCSIParams bounds the parameter bytes kept for one CSI sequence.
+const maxCSIParams = 64
+
+// ansi16 is the xterm palette for colours 0-15.
+var ansi16 = []string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+func parseANSI(data []byte) *ansiDoc {
+	d := &ansiDoc{}
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch d.state {
+		case docGround:
+			switch {
+			case c == 0x1b:
+				d.state = docEsc
+			case c == '\n':
+				d.lines = append(d.lines, d.line)
+				d.line, d.col = nil, 0
+			case c == '\r':
+				d.col = 0
+			case c == '\b':
+				d.col = max(d.col-1, 0)
+			case c == '\t':
+				d.put(' ')
+				for d.col%8 != 0 {
+					d.put(' ')
+				}
+			case c < 0x20 || c == 0x7f:
+			default:
+				r, n := utf8.DecodeRune(data[i:])
+				d.put(r)
+				i += n
+				continue
+			}
+		case docEsc:
+			switch {
+			case c == '[':
+				d.state, d.params = docCSI, d.params[:0]
+			case c == ']':
+				d.state = docOSC
+			case c == 'P' || c == 'X' || c == '^' || c == '_':
+				d.state = docString
+			case c >= 0x20 && c <= 0x2f:
+				d.state = docEscInter
+			default:
+				d.state = docGround
+			}
+		case docEscInter:
+			if c < 0x20 || c > 0x2f {
+				d.state = docGround
+			}
+		case docCSI:
+			switch {
+			case c >= 0x40 && c <= 0x7e:
+				d.state = docGround
+				d.csi(c, string(d.params))
+			case c == 0x1b:
+				d.state = docEsc
+			case c < 0x20 || c > 0x7e:
+				// invalid byte: abandon the sequence
+				d.state = docGround
+			case len(d.params) >= maxCSIParams:
+				// runaway parameters: skip the rest up to the final byte
+				d.state = docCSIDiscard
+			default:
+				d.params = append(d.params, c)
+			}
+		case docCSIDiscard:
+			switch {
+			case c >= 0x40 && c <= 0x7e:
+				d.state = docGround
+			case c == 0x1b:
+				d.state = docEsc
+			case c < 0x20 || c > 0x7e:
+				d.state = docGround
+			}
+		case docOSC:
+			switch c {
+			case 0x07:
+				d.state = docGround
+			case 0x1b:
+				d.state = docStringEsc
+			}
+		case docString:
+			if c == 0x1b {
+				d.state = docStringEsc
+			}
+		case docStringEsc:
+			d.state = docGround
+		}
+		i++
+	}
+	if len(d.line) > 0 {
+		d.lines = append(d.lines, d.line)
+	}
+	return d
+}
+
+func (d *ansiDoc) put(r rune) {
+	cell := ansiCell{r: r, st: d.st}
+	if d.col < len(d.line) {
+		d.line[d.col] = cell
+	} else {
+		d.line = append(d.line, cell)
+	}
+	d.col++
+}
+
+func (d *ansiDoc) csi(final byte, params string) {
+	switch final {
+	case 'm':
+		d.sgr(params)
+	case 'K':
+		if params == "" || params == "0" {
+			d.line = d.line[:min(d.col, len(d.line))]
+		}
+	case 'D':
+		n, err := strconv.Atoi(params)
+		if err != nil || n < 1 {
+			n = 1
+		}
+		d.col = max(d.col-n, 0)
+	}
+}
+
+func (d *ansiDoc) sgr(params string) {
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		n, _ := strconv.Atoi(codes[i])
+		switch {
+		case n == 0:
+			d.st = ansiStyle{}
+		case n == 1:
+			d.st.bold = true
+		case n == 3:
+			d.st.italic = true
+		case n == 4:
+			d.st.underline = true
+		case n == 7:
+			d.st.reverse = true
+		case n == 22:
+			d.st.bold = false
+		case n == 23:
+			d.st.italic = false
+		case n == 24:
+			d.st.underline = false
+		case n == 27:
+			d.st.reverse = false
+		case n >= 30 && n <= 37:
+			d.st.fg = ansi16[n-30]
+		case n >= 90 && n <= 97:
+			d.st.fg = ansi16[n-90+8]
+		case n >= 40 && n <= 47:
+			d.st.bg = ansi16[n-40]
+		case n >= 100 && n <= 107:
+			d.st.bg = ansi16[n-100+8]
+		case n == 39:
+			d.st.fg = ""
+		case n == 49:
+			d.st.bg = ""
+		case n == 38 || n == 48:
+			color, used := extendedColor(codes[i+1:])
+			i += used
+			if n == 38 {
+				d.st.fg = color
+			} else {
+				d.st.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor parses the arguments of SGR 38/48: "5;n" or "2;r;g;b".
+// It returns the CSS colour and how many arguments were consumed.
+func extendedColor(args []string) (string, int) {
+	if len(args) >= 2 && args[0] == "5" {
+		n, _ := strconv.Atoi(args[1])
+		return color256(n), 2
+	}
+	if len(args) >= 4 && args[0] == "2" {
+		r, _ := strconv.Atoi(args[1])
+		g, _ := strconv.Atoi(args[2])
+		b, _ := strconv.Atoi(args[3])
+		return fmt.Sprintf("#%02x%02x%02x", r&0xff, g&0xff, b&0xff), 4
+	}
+	return "", len(args)
+}
+
+// color256 maps an xterm 256-colour index to CSS.
+func color256(n int) string {
+	switch {
+	case n < 0 || n > 255:
+		return ""
+	case n < 16:
+		return ansi16[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+	default:
+		v := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", v, v, v)
+	}
+}
+
+// ANSIToText strips escape sequences from raw terminal output and applies
+// carriage returns and backspaces, returning the text as it was displayed.
+func ANSIToText(data []byte) string {
+	var b strings.Builder
+	for _, line := range parseANSI(data).lines {
+		runes := make([]rune, len(line))
+		for i, c := range line {
+			runes[i] = c.r
+		}
+		b.WriteString(strings.TrimRight(string(runes), " "))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// ANSIToHTML renders raw terminal output as a standalone HTML page with the
+// SGR colours and attributes kept as inline styles.
+func ANSIToHTML(data []byte, title string) string {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>")
+	b.WriteString(html.EscapeString(title))
+	b.WriteString("</title>\n<style>\nbody { margin: 0; background: #1e1e1e; }\n" +
+		"pre { margin: 0; padding: 12px; color: #e5e5e5; font: 13px/1.35 Menlo, Consolas, \"DejaVu Sans Mono\", monospace; white-space: pre-wrap; }\n" +
+		"</style>\n</head>\n<body>\n<pre>")
+	for _, line := range parseANSI(data).lines {
+		writeHTMLLine(&b, line)
+		b.WriteByte('\n')
+	}
+	b.WriteString("</pre>\n</body>\n</html>\n")
+	return b.String()
+}
+
+func writeHTMLLine(b *strings.Builder, line []ansiCell) {
+	for i := 0; i < len(line); {
+		j := i
+		var run strings.Builder
+		for j < len(line) && line[j].st == line[i].st {
+			run.WriteRune(line[j].r)
+			j++
+		}
+		text := html.EscapeString(run.String())
+		if css := line[i].st.css(); css != "" {
+			fmt.Fprintf(b, `<span style="%s">%s</span>`, css, text)
+		} else {
+			b.WriteString(text)
+		}
+		i = j
+	}
+}
+
+func (s ansiStyle) css() string {
+	fg, bg := s.fg, s.bg
+	if s.reverse {
+		fg, bg = cmp.Or(bg, "#1e1e1e"), cmp.Or(fg, "#e5e5e5")
+	}
+	var parts []string
+	if fg != "" {
+		parts = append(parts, "color:"+fg)
+	}
+	if bg != "" {
+		parts = append(parts, "background:"+bg)
+	}
+	if s.bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if s.italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if s.underline {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
@@ -0,0 +1,408 @@
+package ui
+
+// BatchExecPanel runs one command on many saved hosts at once. The top half
+// picks the targets (a folder shortcut plus a checklist of hosts) and the
+// command; the bottom half shows the results either per host or grouped by
+// identical output, so the odd host out stands out. Running is delegated to
+// the OnRun callback and results are pushed back with Update.
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// BatchTarget is one saved host that can be selected for a batch run.
+type BatchTarget struct {
+	ID     string
+	Name   string
+	Folder string
+	Host   string // user@host:port
+}
+
+// Batch result states, mirroring the backend.
+const (
+	BatchPending   = "pending"
+	BatchRunning   = "running"
+	BatchCompleted = "completed"
+	BatchFailed    = "failed"
+)
+
+// BatchResult is the display model of one host in a batch run.
+type BatchResult struct {
+	ID       string
+	Name     string
+	Host     string
+	State    string
+	ExitCode int
+	Stdout   string
+	Stderr   string
+	Error    string // connection failure, timeout or signal
+	Duration time.Duration
+}
+
+// OK reports whether the host finished with exit code 0.
+func (r BatchResult) OK() bool {
+	return r.State == BatchCompleted && r.ExitCode == 0 && r.Error == ""
+}
+
+// BatchRun is what the user asked to run.
+type BatchRun struct {
+	ProfileIDs  []string
+	Command     string
+	Timeout     time.Duration
+	Concurrency int
+}
+
+// BatchExecPanelProps defines the callbacks for panel interactions.
+type BatchExecPanelProps struct {
+	OnRun    func(r BatchRun)
+	OnCancel func()
+}
+
+var batchColumns = []struct {
+	title string
+	width float32
+}{
+	{"主机", 160}, {"地址", 200}, {"状态", 90}, {"退出码", 70}, {"耗时", 80}, {"输出", 420},
+}
+
+// BatchExecPanel is meant to fill its own window.
+type BatchExecPanel struct {
+	props   BatchExecPanelProps
+	targets []BatchTarget
+	results []BatchResult
+
+	folder      *widget.Select
+	hosts       *widget.CheckGroup
+	command     *widget.Entry
+	timeout     *widget.Entry
+	concurrency *widget.Select
+	runBtn      *widget.Button
+	cancelBtn   *widget.Button
+	status      *widget.Label
+	table       *widget.Table
+	detail      *widget.Entry
+	groups      *fyne.Container
+	view        fyne.CanvasObject
+}
+
+// NewBatchExecPanel creates a panel without targets, see SetTargets.
+func NewBatchExecPanel(props BatchExecPanelProps) *BatchExecPanel {
+	p := &BatchExecPanel{props: props}
+
+	p.hosts = widget.NewCheckGroup(nil, nil)
+	p.folder = widget.NewSelect(nil, func(f string) {
+		// choosing a folder checks every host in it and its subfolders
+		var sel []string
+		for _, t := range p.targets {
+			if f == "全部" || t.Folder == f || strings.HasPrefix(t.Folder, f+"/") {
+				sel = append(sel, targetLabel(t))
+			}
+		}
+		p.hosts.SetSelected(sel)
+	})
+	p.folder.PlaceHolder = "按分组选择"
+	clearBtn := widget.NewButton("清空选择", func() {
+		p.folder.ClearSelected()
+		p.hosts.SetSelected(nil)
+	})
+	targets := container.NewBorder(container.NewHBox(widget.NewLabel("目标主机"), p.folder, clearBtn), nil, nil, nil,
+		container.NewVScroll(p.hosts))
+
+	p.command = widget.NewMultiLineEntry()
+	p.command.SetPlaceHolder("要在每台主机上执行的命令，例如 uptime")
+	p.command.SetMinRowsVisible(3)
+	p.timeout = widget.NewEntry()
+	p.timeout.SetText("60")
+	p.concurrency = widget.NewSelect([]string{"1", "4", "8", "16", "32", "64"}, nil)
+	p.concurrency.SetSelected("8")
+	p.runBtn = widget.NewButton("执行", p.run)
+	p.runBtn.Importance = widget.HighImportance
+	p.cancelBtn = widget.NewButton("取消", func() {
+		p.cancelBtn.Disable()
+		if p.props.OnCancel != nil {
+			p.props.OnCancel()
+		}
+	})
+	p.cancelBtn.Disable()
+	p.status = widget.NewLabel("")
+	options := container.NewHBox(
+		widget.NewLabel("超时（秒）"), p.timeout,
+		widget.NewLabel("并发"), p.concurrency,
+		p.runBtn, p.cancelBtn,
+	)
+	form := container.NewBorder(nil, container.NewBorder(nil, nil, nil, options, p.status), nil, nil, p.command)
+
+	p.table = widget.NewTableWithHeaders(
+		func() (int, int) { return len(p.results), len(batchColumns) },
+		func() fyne.CanvasObject {
+			l := widget.NewLabel("")
+			l.Truncation = fyne.TextTruncateEllipsis
+			return l
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			r := p.results[id.Row]
+			l := o.(*widget.Label)
+			l.Importance = widget.MediumImportance
+			if r.State == BatchCompleted || r.State == BatchFailed {
+				if !r.OK() {
+					l.Importance = widget.DangerImportance
+				}
+			}
+			l.SetText(batchCellText(r, id.Col))
+		},
+	)
+	p.table.ShowHeaderColumn = false
+	p.table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 {
+			o.(*widget.Label).SetText(batchColumns[id.Col].title)
+		}
+	}
+	for i, c := range batchColumns {
+		p.table.SetColumnWidth(i, c.width)
+	}
+	p.detail = widget.NewMultiLineEntry()
+	p.detail.TextStyle = fyne.TextStyle{Monospace: true}
+	p.detail.Wrapping = fyne.TextWrapOff
+	p.detail.SetPlaceHolder("选择一台主机查看完整输出")
+	p.table.OnSelected = func(id widget.TableCellID) {
+		if id.Row >= 0 && id.Row < len(p.results) {
+			p.detail.SetText(batchOutput(p.results[id.Row]))
+		}
+	}
+	byHost := container.NewVSplit(p.table, p.detail)
+	byHost.SetOffset(0.6)
+
+	p.groups = container.NewVBox()
+	results := container.NewAppTabs(
+		container.NewTabItem("按主机", byHost),
+		container.NewTabItem("按输出分组", container.NewVScroll(p.groups)),
+	)
+
+	top := container.NewHSplit(targets, form)
+	top.SetOffset(0.35)
+	split := container.NewVSplit(top, results)
+	split.SetOffset(0.35)
+	p.view = split
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *BatchExecPanel) Object() fyne.CanvasObject { return p.view }
+
+// SetTargets replaces the selectable hosts and checks the ones whose ID is in selected.
+func (p *BatchExecPanel) SetTargets(targets []BatchTarget, selected []string) {
+	p.targets = targets
+	folders := []string{"全部"}
+	labels := make([]string, len(targets))
+	var sel []string
+	for i, t := range targets {
+		labels[i] = targetLabel(t)
+		if slices.Contains(selected, t.ID) {
+			sel = append(sel, labels[i])
+		}
+		if t.Folder != "" && !slices.Contains(folders, t.Folder) {
+			folders = append(folders, t.Folder)
+		}
+	}
+	slices.Sort(folders[1:])
+	p.folder.SetOptions(folders)
+	p.hosts.Options = labels
+	p.hosts.SetSelected(sel)
+	p.hosts.Refresh()
+}
+
+// Update replaces the result of one host; the run button is re-enabled once
+// every host has finished. Must be called on the UI thread (use fyne.Do).
+func (p *BatchExecPanel) Update(r BatchResult) {
+	for i := range p.results {
+		if p.results[i].ID == r.ID {
+			p.results[i] = r
+		}
+	}
+	p.refresh()
+}
+
+// Fail reports that the run could not be started.
+func (p *BatchExecPanel) Fail(err error) {
+	p.runBtn.Enable()
+	p.cancelBtn.Disable()
+	p.status.SetText("执行失败: " + err.Error())
+}
+
+func (p *BatchExecPanel) run() {
+	var ids []string
+	var pending []BatchResult
+	for _, t := range p.targets {
+		if slices.Contains(p.hosts.Selected, targetLabel(t)) {
+			ids = append(ids, t.ID)
+			pending = append(pending, BatchResult{ID: t.ID, Name: t.Name, Host: t.Host, State: BatchPending})
+		}
+	}
+	cmd := strings.TrimSpace(p.command.Text)
+	if len(ids) == 0 || cmd == "" {
+		p.status.SetText("请选择目标主机并输入命令")
+		return
+	}
+	secs, err := strconv.Atoi(strings.TrimSpace(p.timeout.Text))
+	if err != nil || secs <= 0 {
+		p.status.SetText("超时须为正整数秒")
+		return
+	}
+	n, _ := strconv.Atoi(p.concurrency.Selected)
+	// list the hosts as pending before the request so early events find their row
+	p.results = pending
+	p.runBtn.Disable()
+	p.cancelBtn.Enable()
+	p.detail.SetText("")
+	p.table.UnselectAll()
+	p.refresh()
+	if p.props.OnRun != nil {
+		p.props.OnRun(BatchRun{ProfileIDs: ids, Command: cmd, Timeout: time.Duration(secs) * time.Second, Concurrency: n})
+	}
+}
+
+func (p *BatchExecPanel) refresh() {
+	var done, failed int
+	for _, r := range p.results {
+		switch r.State {
+		case BatchCompleted, BatchFailed:
+			done++
+			if !r.OK() {
+				failed++
+			}
+		}
+	}
+	status := fmt.Sprintf("已完成 %d / %d", done, len(p.results))
+	if failed > 0 {
+		status += fmt.Sprintf("，%d 台失败或退出码非零", failed)
+	}
+	p.status.SetText(status)
+	if done == len(p.results) {
+		p.runBtn.Enable()
+		p.cancelBtn.Disable()
+	}
+	p.table.Refresh()
+	p.refreshGroups()
+}
+
+// refreshGroups rebuilds the grouped view: finished hosts with the same exit
+// code, error and output share one entry, largest group first.
+func (p *BatchExecPanel) refreshGroups() {
+	type group struct {
+		sample BatchResult
+		names  []string
+	}
+	var groups []*group
+	index := map[string]*group{}
+	for _, r := range p.results {
+		if r.State != BatchCompleted && r.State != BatchFailed {
+			continue
+		}
+		key := fmt.Sprintf("%d\x00%s\x00%s\x00%s", r.ExitCode, r.Error, r.Stdout, r.Stderr)
+		g := index[key]
+		if g == nil {
+			g = &group{sample: r}
+			index[key] = g
+			groups = append(groups, g)
+		}
+		g.names = append(g.names, r.Name)
+	}
+	slices.SortStableFunc(groups, func(a, b *group) int { return len(b.names) - len(a.names) })
+
+	p.groups.RemoveAll()
+	for _, g := range groups {
+		head := fmt.Sprintf("%d 台主机 · %s", len(g.names), batchState(g.sample))
+		title := widget.NewLabel(head)
+		title.TextStyle = fyne.TextStyle{Bold: true}
+		if !g.sample.OK() {
+			title.Importance = widget.DangerImportance
+		}
+		names := widget.NewLabel(strings.Join(g.names, ", "))
+		names.Wrapping = fyne.TextWrapWord
+		out := widget.NewLabel(batchOutput(g.sample))
+		out.TextStyle = fyne.TextStyle{Monospace: true}
+		p.groups.Add(widget.NewCard("", "", container.NewVBox(title, names, out)))
+	}
+	p.groups.Refresh()
+}
+
+func targetLabel(t BatchTarget) string {
+	return fmt.Sprintf("%s (%s)", t.Name, t.Host)
+}
+
+func batchState(r BatchResult) string {
+	switch r.State {
+	case BatchPending:
+		return "等待中"
+	case BatchRunning:
+		return "执行中"
+	case BatchFailed:
+		return "失败"
+	}
+	if r.Error != "" {
+		return fmt.Sprintf("退出码 %d（%s）", r.ExitCode, r.Error)
+	}
+	return fmt.Sprintf("退出码 %d", r.ExitCode)
+}
+
+func batchCellText(r BatchResult, col int) string {
+	finished := r.State == BatchCompleted
+	switch col {
+	case 0:
+		return r.Name
+	case 1:
+		return r.Host
+	case 2:
+		if finished && !r.OK() {
+			return "非零退出"
+		}
+		if finished {
+			return "成功"
+		}
+		return batchState(r)
+	case 3:
+		if finished {
+			return strconv.Itoa(r.ExitCode)
+		}
+		return ""
+	case 4:
+		if finished {
+			return r.Duration.Round(time.Millisecond).String()
+		}
+		return ""
+	default:
+		if r.Error != "" {
+			return r.Error
+		}
+		line, _, _ := strings.Cut(strings.TrimSpace(r.Stdout+"\n"+r.Stderr), "\n")
+		return line
+	}
+}
+
+// batchOutput is the full text shown for one host: stdout, then stderr and
+// the error when present.
+func batchOutput(r BatchResult) string {
+	var b strings.Builder
+	b.WriteString(r.Stdout)
+	if r.Stderr != "" {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("[stderr]\n" + r.Stderr)
+	}
+	if r.Error != "" {
+		if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+			b.WriteString("\n")
+		}
+		b.WriteString("[error] " + r.Error)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
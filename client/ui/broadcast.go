@@ -0,0 +1,402 @@
+package ui
+
+// Broadcast input mirrors the keys typed into one remote terminal tab to the
+// other participating tabs, like tmux's synchronize-panes. The TabBar owns the
+// state: a row of checkboxes below its header bar picks the participating
+// tabs, and their titles carry a marker while broadcasting is on.
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+	terminal "github.com/fyne-io/terminal"
+)
+
+// broadcastMark prefixes the title of tabs that receive broadcast input.
+const broadcastMark = "[广播] "
+
+// mirrorQueue bounds the mirrored input waiting for one tab's PTY. A peer
+// that stops reading (stalled network, suspended host) drops the overflow
+// instead of holding up the tab being typed into.
+const mirrorQueue = 256
+
+// broadcast is the mirroring state shared by the tab inputs. Writes arrive
+// from the terminal widgets, so it is guarded by its own mutex.
+type broadcast struct {
+	mu      sync.Mutex
+	on      bool
+	members map[*container.TabItem]bool
+	inputs  map[*container.TabItem]*tabInput
+}
+
+// tabInput wraps the PTY of a remote tab. Writes made by the tab's terminal
+// go straight to the PTY; writes produced while the terminal replays a key
+// event mirrored from another tab are queued and written by pump, so that a
+// slow peer never blocks the UI thread.
+type tabInput struct {
+	RemotePTY
+	b         *broadcast
+	tab       *container.TabItem
+	scroll    *scrollback
+	term      *terminal.Terminal
+	mirroring atomic.Bool
+	queue     chan []byte
+	done      chan struct{}
+}
+
+func (in *tabInput) Write(p []byte) (int, error) {
+	if !in.mirroring.Load() {
+		return in.RemotePTY.Write(p)
+	}
+	select {
+	case in.queue <- bytes.Clone(p):
+	default:
+		// the peer is not keeping up; drop rather than block the source tab
+	}
+	return len(p), nil
+}
+
+// pump writes the queued mirrored input until the tab goes away.
+func (in *tabInput) pump() {
+	for {
+		select {
+		case p := <-in.queue:
+			if _, err := in.RemotePTY.Write(p); err != nil {
+				return
+			}
+		case <-in.done:
+			return
+		}
+	}
+}
+
+// targets returns the other participants when from participates.
+func (b *broadcast) targets(from *container.TabItem) []*tabInput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.on || !b.members[from] {
+		return nil
+	}
+	var out []*tabInput
+	for tab, in := range b.inputs {
+		if tab != from && b.members[tab] {
+			out = append(out, in)
+		}
+	}
+	return out
+}
+
+// mirror replays a key event of from on the other participants' terminals.
+// Each terminal encodes the event for its own mode (application cursor keys,
+// bracketed paste, ...), and only key events are mirrored: replies the
+// terminals send on their own, such as cursor position reports, never pass
+// through here. Runs on the UI thread.
+func (b *broadcast) mirror(from *container.TabItem, deliver func(fyne.Widget)) {
+	for _, in := range b.targets(from) {
+		in.mirroring.Store(true)
+		deliver(in.term)
+		in.mirroring.Store(false)
+	}
+}
+
+// keyLayer lies over a remote terminal and takes its focus, so that key
+// events can be mirrored before the terminal turns them into bytes. Pointer
+// events are passed through to the terminal unchanged.
+type keyLayer struct {
+	widget.BaseWidget
+	term fyne.Widget
+	in   *tabInput
+}
+
+func newKeyLayer(in *tabInput) *keyLayer {
+	l := &keyLayer{term: in.term, in: in}
+	l.ExtendBaseWidget(l)
+	return l
+}
+
+func (l *keyLayer) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(&canvas.Rectangle{})
+}
+
+// key delivers a key event to the own terminal and to the participants.
+func (l *keyLayer) key(deliver func(fyne.Widget)) {
+	deliver(l.term)
+	l.in.b.mirror(l.in.tab, deliver)
+}
+
+func (l *keyLayer) TypedRune(r rune) {
+	l.key(func(w fyne.Widget) {
+		if f, ok := w.(fyne.Focusable); ok {
+			f.TypedRune(r)
+		}
+	})
+}
+
+func (l *keyLayer) TypedKey(ev *fyne.KeyEvent) {
+	l.key(func(w fyne.Widget) {
+		if f, ok := w.(fyne.Focusable); ok {
+			f.TypedKey(ev)
+		}
+	})
+}
+
+func (l *keyLayer) TypedShortcut(s fyne.Shortcut) {
+	l.key(func(w fyne.Widget) {
+		if sc, ok := w.(fyne.Shortcutable); ok {
+			sc.TypedShortcut(s)
+		}
+	})
+}
+
+// KeyDown and KeyUp carry the modifier state the terminals consult when
+// encoding keys, so they are mirrored as well.
+func (l *keyLayer) KeyDown(ev *fyne.KeyEvent) {
+	l.key(func(w fyne.Widget) {
+		if k, ok := w.(desktop.Keyable); ok {
+			k.KeyDown(ev)
+		}
+	})
+}
+
+func (l *keyLayer) KeyUp(ev *fyne.KeyEvent) {
+	l.key(func(w fyne.Widget) {
+		if k, ok := w.(desktop.Keyable); ok {
+			k.KeyUp(ev)
+		}
+	})
+}
+
+func (l *keyLayer) FocusGained() {
+	if f, ok := l.term.(fyne.Focusable); ok {
+		f.FocusGained()
+	}
+}
+
+func (l *keyLayer) FocusLost() {
+	if f, ok := l.term.(fyne.Focusable); ok {
+		f.FocusLost()
+	}
+}
+
+// Tapped lets the terminal handle the tap, then takes the focus back from it
+// so that keys keep arriving here.
+func (l *keyLayer) Tapped(ev *fyne.PointEvent) {
+	if t, ok := l.term.(fyne.Tappable); ok {
+		t.Tapped(ev)
+	}
+	if c := fyne.CurrentApp().Driver().CanvasForObject(l); c != nil {
+		c.Focus(l)
+	}
+}
+
+func (l *keyLayer) TappedSecondary(ev *fyne.PointEvent) {
+	if t, ok := l.term.(fyne.SecondaryTappable); ok {
+		t.TappedSecondary(ev)
+	}
+}
+
+func (l *keyLayer) MouseDown(ev *desktop.MouseEvent) {
+	if m, ok := l.term.(desktop.Mouseable); ok {
+		m.MouseDown(ev)
+	}
+}
+
+func (l *keyLayer) MouseUp(ev *desktop.MouseEvent) {
+	if m, ok := l.term.(desktop.Mouseable); ok {
+		m.MouseUp(ev)
+	}
+}
+
+func (l *keyLayer) Dragged(ev *fyne.DragEvent) {
+	if d, ok := l.term.(fyne.Draggable); ok {
+		d.Dragged(ev)
+	}
+}
+
+func (l *keyLayer) DragEnd() {
+	if d, ok := l.term.(fyne.Draggable); ok {
+		d.DragEnd()
+	}
+}
+
+func (l *keyLayer) Scrolled(ev *fyne.ScrollEvent) {
+	if s, ok := l.term.(fyne.Scrollable); ok {
+		s.Scrolled(ev)
+	}
+}
+
+func (l *keyLayer) Cursor() desktop.Cursor {
+	if c, ok := l.term.(desktop.Cursorable); ok {
+		return c.Cursor()
+	}
+	return desktop.DefaultCursor
+}
+
+// AddRemoteTerminalTab appends a terminal tab backed by a remote PTY and
+// selects it. Unlike AddTerminalTab, the tab can take part in broadcast input;
+// it leaves the broadcast set once the remote side has gone away.
+func (t *TabBar) AddRemoteTerminalTab(title string, pty RemotePTY, onExit func()) *container.TabItem {
+	in := &tabInput{
+		RemotePTY: pty,
+		b:         &t.bcast,
+		scroll:    &scrollback{},
+		queue:     make(chan []byte, mirrorQueue),
+		done:      make(chan struct{}),
+	}
+	var tab *container.TabItem
+	in.term = NewRemoteTerminal(in, func() {
+		t.removeInput(tab)
+		if onExit != nil {
+			onExit()
+		}
+	})
+	tab = container.NewTabItem(title, container.NewStack(in.term, newKeyLayer(in)))
+	in.tab = tab
+	go in.pump()
+	t.titles[tab] = title
+	t.scrollbacks[tab] = in.scroll
+	t.bcast.mu.Lock()
+	t.bcast.inputs[tab] = in
+	// new tabs join a running broadcast so that opening "one more" host just works
+	t.bcast.members[tab] = t.bcast.on
+	t.bcast.mu.Unlock()
+	t.Tabs.Append(tab)
+	t.Tabs.Select(tab)
+	t.refreshBroadcast()
+	return tab
+}
+
+// SetBroadcast turns broadcast input on or off. Turning it on with no
+// participants selects every remote tab.
+func (t *TabBar) SetBroadcast(on bool) {
+	t.bcast.mu.Lock()
+	t.bcast.on = on
+	if on {
+		chosen := false
+		for tab := range t.bcast.inputs {
+			chosen = chosen || t.bcast.members[tab]
+		}
+		if !chosen {
+			for tab := range t.bcast.inputs {
+				t.bcast.members[tab] = true
+			}
+		}
+	}
+	t.bcast.mu.Unlock()
+	t.refreshBroadcast()
+}
+
+// SetBroadcastMember includes or excludes a tab from broadcast input.
+func (t *TabBar) SetBroadcastMember(tab *container.TabItem, on bool) {
+	t.bcast.mu.Lock()
+	if _, ok := t.bcast.inputs[tab]; ok {
+		t.bcast.members[tab] = on
+	}
+	t.bcast.mu.Unlock()
+	t.refreshBroadcast()
+}
+
+// ToggleCurrentBroadcast flips the selected tab's participation.
+func (t *TabBar) ToggleCurrentBroadcast() {
+	sel := t.Tabs.Selected()
+	if sel == nil {
+		return
+	}
+	t.bcast.mu.Lock()
+	on := t.bcast.members[sel]
+	t.bcast.mu.Unlock()
+	t.SetBroadcastMember(sel, !on)
+}
+
+func (t *TabBar) removeInput(tab *container.TabItem) {
+	t.bcast.mu.Lock()
+	if in, ok := t.bcast.inputs[tab]; ok {
+		close(in.done)
+	}
+	delete(t.bcast.inputs, tab)
+	delete(t.bcast.members, tab)
+	t.bcast.mu.Unlock()
+	t.refreshBroadcast()
+}
+
+// broadcastBar builds the row of participant checkboxes shown while
+// broadcasting; refreshBroadcast keeps it in sync with the tabs.
+func (t *TabBar) broadcastBar() fyne.CanvasObject {
+	t.broadcastBtn = widget.NewCheck("广播输入", func(on bool) {
+		if on != t.broadcasting() {
+			t.SetBroadcast(on)
+		}
+	})
+	t.excludeBtn = widget.NewButton("排除当前标签", t.ToggleCurrentBroadcast)
+	t.broadcastChecks = container.NewHBox()
+	t.broadcastRow = container.NewBorder(nil, nil, widget.NewLabel("广播到："), t.excludeBtn,
+		container.NewHScroll(t.broadcastChecks))
+	t.refreshBroadcast()
+	return t.broadcastRow
+}
+
+func (t *TabBar) broadcasting() bool {
+	t.bcast.mu.Lock()
+	defer t.bcast.mu.Unlock()
+	return t.bcast.on
+}
+
+// refreshBroadcast updates the tab titles, the checkboxes and the exclude
+// button after any change of tabs or participants.
+func (t *TabBar) refreshBroadcast() {
+	t.bcast.mu.Lock()
+	on := t.bcast.on
+	members := make(map[*container.TabItem]bool, len(t.bcast.members))
+	for tab, m := range t.bcast.members {
+		members[tab] = m
+	}
+	t.bcast.mu.Unlock()
+
+	for _, tab := range t.Tabs.Items {
+		if title, ok := t.titles[tab]; ok {
+			if on && members[tab] {
+				title = broadcastMark + title
+			}
+			tab.Text = title
+		}
+	}
+	t.Tabs.Refresh()
+	if t.broadcastRow == nil {
+		return
+	}
+	t.broadcastBtn.SetChecked(on)
+	if !on {
+		t.broadcastRow.Hide()
+		return
+	}
+	t.broadcastChecks.RemoveAll()
+	for _, tab := range t.Tabs.Items {
+		if _, remote := members[tab]; !remote {
+			continue
+		}
+		c := widget.NewCheck(t.titles[tab], func(v bool) { t.SetBroadcastMember(tab, v) })
+		c.Checked = members[tab]
+		t.broadcastChecks.Add(c)
+	}
+	t.broadcastChecks.Refresh()
+	sel := t.Tabs.Selected()
+	member, remote := members[sel]
+	switch {
+	case !remote:
+		t.excludeBtn.SetText("排除当前标签")
+		t.excludeBtn.Disable()
+	case member:
+		t.excludeBtn.SetText("排除当前标签")
+		t.excludeBtn.Enable()
+	default:
+		t.excludeBtn.SetText("加入当前标签")
+		t.excludeBtn.Enable()
+	}
+	t.broadcastRow.Show()
+}
@@ -0,0 +1,180 @@
+package ui
+
+// DeviceInfoPanel is the left-hand panel describing the host of the selected
+// terminal tab. It only renders a DeviceInfo snapshot; sampling, unit
+// formatting and following the tab selection are left to the caller.
+
+import (
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const devicePlaceholder = "待获取"
+
+// DeviceInfo is the display model of one monitor sample.
+type DeviceInfo struct {
+	Host string // tab title the sample belongs to
+
+	CPU         string  // e.g. "Intel Xeon · 4 核 · 2.4 GHz"
+	CPUUsage    float64 // 0..1
+	Memory      string  // e.g. "1.2 GB / 3.8 GB"
+	MemoryUsage float64
+	Swap        string
+	SwapUsage   float64
+	Disk        string
+	DiskUsage   float64
+	Load        string // e.g. "0.12 0.08 0.01"
+	System      string // OS and kernel
+	Uptime      string
+
+	Hostname  string
+	Addresses string
+	MAC       string
+	Network   []string // one line per interface, e.g. "eth0 ↓1.2 MB/s ↑3.0 KB/s"
+	Status    string   // connection status line
+}
+
+// DeviceInfoPanelProps defines the callbacks for panel interactions.
+type DeviceInfoPanelProps struct {
+	OnRefresh func()
+}
+
+// DeviceInfoPanel shows hardware and network cards bound to DeviceInfo values.
+type DeviceInfoPanel struct {
+	host *widget.Label
+
+	cpu, mem, swap, disk     *usageRow
+	load, system, uptime     *widget.Label
+	hostname, addresses, mac *widget.Label
+	network                  *widget.Label
+	status                   *widget.Label
+
+	view fyne.CanvasObject
+}
+
+// usageRow is a caption with a progress bar underneath.
+type usageRow struct {
+	label *widget.Label
+	bar   *widget.ProgressBar
+}
+
+func newUsageRow() *usageRow {
+	r := &usageRow{label: widget.NewLabel(""), bar: widget.NewProgressBar()}
+	r.label.Wrapping = fyne.TextWrapWord
+	return r
+}
+
+func (r *usageRow) set(prefix, text string, usage float64) {
+	if text == "" {
+		r.label.SetText(prefix + devicePlaceholder)
+		r.bar.Hide()
+		return
+	}
+	r.label.SetText(prefix + text)
+	r.bar.SetValue(usage)
+	r.bar.Show()
+}
+
+// NewDeviceInfoPanel creates the panel showing placeholders.
+func NewDeviceInfoPanel(props DeviceInfoPanelProps) *DeviceInfoPanel {
+	p := &DeviceInfoPanel{
+		host:      widget.NewLabel(""),
+		cpu:       newUsageRow(),
+		mem:       newUsageRow(),
+		swap:      newUsageRow(),
+		disk:      newUsageRow(),
+		load:      widget.NewLabel(""),
+		system:    widget.NewLabel(""),
+		uptime:    widget.NewLabel(""),
+		hostname:  widget.NewLabel(""),
+		addresses: widget.NewLabel(""),
+		mac:       widget.NewLabel(""),
+		network:   widget.NewLabel(""),
+		status:    widget.NewLabel(""),
+	}
+	for _, l := range []*widget.Label{p.host, p.system, p.addresses, p.mac, p.network, p.status} {
+		l.Wrapping = fyne.TextWrapWord
+	}
+
+	title := widget.NewLabel("设备信息")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	hwGroup := widget.NewCard("硬件信息", "", container.NewVBox(
+		p.cpu.label, p.cpu.bar,
+		p.mem.label, p.mem.bar,
+		p.swap.label, p.swap.bar,
+		p.disk.label, p.disk.bar,
+		p.load,
+		p.system,
+		p.uptime,
+	))
+	netGroup := widget.NewCard("网络信息", "", container.NewVBox(
+		p.hostname,
+		p.addresses,
+		p.mac,
+		p.network,
+		p.status,
+	))
+	refreshBtn := widget.NewButton("刷新信息", func() {
+		if props.OnRefresh != nil {
+			props.OnRefresh()
+		}
+	})
+
+	content := container.NewVBox(title, p.host, hwGroup, netGroup, refreshBtn)
+	scroll := container.NewScroll(content)
+	scroll.SetMinSize(fyne.NewSize(200, 0))
+	p.view = scroll
+	p.Clear("未连接")
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *DeviceInfoPanel) Object() fyne.CanvasObject { return p.view }
+
+// Set shows a sample; must be called on the UI thread (use fyne.Do).
+func (p *DeviceInfoPanel) Set(d DeviceInfo) {
+	p.host.SetText(d.Host)
+	if d.Host == "" {
+		p.host.Hide()
+	} else {
+		p.host.Show()
+	}
+	p.cpu.set("CPU: ", d.CPU, d.CPUUsage)
+	p.mem.set("内存: ", d.Memory, d.MemoryUsage)
+	p.swap.set("交换: ", d.Swap, d.SwapUsage)
+	p.disk.set("磁盘: ", d.Disk, d.DiskUsage)
+	p.load.SetText("负载: " + orPlaceholder(d.Load))
+	p.system.SetText("系统: " + orPlaceholder(d.System))
+	p.uptime.SetText("运行时间: " + orPlaceholder(d.Uptime))
+	p.hostname.SetText("主机名: " + orPlaceholder(d.Hostname))
+	p.addresses.SetText("IP地址: " + orPlaceholder(d.Addresses))
+	p.mac.SetText("MAC地址: " + orPlaceholder(d.MAC))
+	if len(d.Network) == 0 {
+		p.network.Hide()
+	} else {
+		p.network.SetText("流量:\n" + strings.Join(d.Network, "\n"))
+		p.network.Show()
+	}
+	p.status.SetText("连接状态: " + d.Status)
+}
+
+// SetStatus updates only the connection status line, keeping the last sample visible.
+func (p *DeviceInfoPanel) SetStatus(status string) {
+	p.status.SetText("连接状态: " + status)
+}
+
+// Clear resets every card to placeholders with the given status.
+func (p *DeviceInfoPanel) Clear(status string) {
+	p.Set(DeviceInfo{Status: status})
+}
+
+func orPlaceholder(s string) string {
+	if s == "" {
+		return devicePlaceholder
+	}
+	return s
+}
@@ -0,0 +1,79 @@
+package ui
+
+// Session export saves what a terminal tab has shown as plain text or as a
+// colourised HTML page. Remote tabs keep a copy of the raw output they
+// received (capped at maxScrollback, oldest lines dropped first), which also
+// survives a disconnect; local tabs fall back to the terminal's own text.
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+
+	terminal "github.com/fyne-io/terminal"
+)
+
+// maxScrollback caps the raw output kept per remote tab for export.
+const maxScrollback = 4 << 20
+
+// scrollback accumulates a tab's raw output; Read runs on the terminal's
+// goroutine while exports run on the UI thread.
+type scrollback struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (s *scrollback) append(p []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buf = append(s.buf, p...)
+	if over := len(s.buf) - maxScrollback; over > 0 {
+		// drop whole lines so the export does not start mid-line
+		cut := over
+		if i := bytes.IndexByte(s.buf[over:], '\n'); i >= 0 {
+			cut += i + 1
+		}
+		s.buf = append(s.buf[:0:0], s.buf[cut:]...)
+	}
+}
+
+func (s *scrollback) bytes() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.buf)
+}
+
+// Read tees remote output into the tab's scrollback.
+func (in *tabInput) Read(p []byte) (int, error) {
+	n, err := in.RemotePTY.Read(p)
+	if n > 0 && in.scroll != nil {
+		in.scroll.append(p[:n])
+	}
+	return n, err
+}
+
+// ExportCurrent renders the selected tab's session as plain text, or as an
+// HTML page when html is set.
+func (t *TabBar) ExportCurrent(html bool) (string, error) {
+	sel := t.Tabs.Selected()
+	if sel == nil {
+		return "", errors.New("没有打开的标签")
+	}
+	if sb, ok := t.scrollbacks[sel]; ok {
+		data := sb.bytes()
+		if html {
+			return ANSIToHTML(data, t.titles[sel]), nil
+		}
+		return ANSIToText(data), nil
+	}
+	term, ok := sel.Content.(*terminal.Terminal)
+	if !ok {
+		return "", errors.New("当前标签不是终端")
+	}
+	// the local terminal only exposes its text, so the HTML export has no colours
+	text := []byte(term.Text())
+	if html {
+		return ANSIToHTML(text, t.titles[sel]), nil
+	}
+	return ANSIToText(text), nil
+}
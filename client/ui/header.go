@@ -19,8 +19,18 @@ type HeaderProps struct {
 	OnNewConnection     func()
 	OnOpenConnectionMgr func()
 	OnOpenTerminal      func()
+	// Optional: open the credential vault dialog (status, unlock, lock, change master)
+	OnOpenVault func()
+	// Optional: open the SSH key manager (generate, import, export, install)
+	OnOpenKeys func()
+	// Optional: open the batch execution window (run one command on many hosts)
+	OnOpenBatchExec func()
+	// Optional: open the session recordings list with replay
+	OnOpenRecordings func()
 	// Optional: backend ping to verify service availability
 	OnPing func() (ok bool, msg string, err error)
+	// Optional: backend health widget shown in the status area
+	Status fyne.CanvasObject
 }
 
 // NewHeader creates a Figma-like header.
@@ -71,6 +81,38 @@ func NewHeader(props HeaderProps, window fyne.Window) *fyne.Container {
 		}
 	})
 
+	// Credential vault
+	vaultBtn := widget.NewButton("凭据库", func() {
+		fmt.Println("[USER] 点击了凭据库按钮")
+		if props.OnOpenVault != nil {
+			props.OnOpenVault()
+		}
+	})
+
+	// SSH key manager
+	keysBtn := widget.NewButton("密钥", func() {
+		fmt.Println("[USER] 点击了密钥按钮")
+		if props.OnOpenKeys != nil {
+			props.OnOpenKeys()
+		}
+	})
+
+	// Batch execution
+	batchBtn := widget.NewButton("批量执行", func() {
+		fmt.Println("[USER] 点击了批量执行按钮")
+		if props.OnOpenBatchExec != nil {
+			props.OnOpenBatchExec()
+		}
+	})
+
+	// Session recordings
+	recordingsBtn := widget.NewButton("会话录像", func() {
+		fmt.Println("[USER] 点击了会话录像按钮")
+		if props.OnOpenRecordings != nil {
+			props.OnOpenRecordings()
+		}
+	})
+
 	// Settings & Help
 	settingsBtn := widget.NewButton("设置", func() { fmt.Println("[USER] 点击了设置按钮") })
 	helpBtn := widget.NewButton("帮助", func() { fmt.Println("[USER] 点击了帮助按钮") })
@@ -112,8 +154,13 @@ func NewHeader(props HeaderProps, window fyne.Window) *fyne.Container {
 		}()
 	}
 
+	status := container.NewHBox(pingBtn, loading)
+	if props.Status != nil {
+		status.Add(props.Status)
+	}
+
 	left := container.NewHBox(brand, nav)
-	right := container.NewHBox(searchBtn, searchEntry, newConnBtn, settingsBtn, helpBtn, versionLabel, container.NewHBox(pingBtn, loading))
+	right := container.NewHBox(searchBtn, searchEntry, newConnBtn, vaultBtn, keysBtn, batchBtn, recordingsBtn, settingsBtn, helpBtn, versionLabel, status)
 
 	header := container.NewBorder(nil, nil, left, right, nil)
 	return container.NewPadded(header)
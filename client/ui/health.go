@@ -0,0 +1,62 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// HealthLevel selects the indicator color.
+type HealthLevel int
+
+const (
+	HealthUnknown HealthLevel = iota
+	HealthOK
+	HealthWarn
+	HealthError
+)
+
+// HealthIndicator is a colored dot plus a short label shown in the header
+// status area to reflect backend availability.
+type HealthIndicator struct {
+	dot   *canvas.Circle
+	label *widget.Label
+	view  *fyne.Container
+}
+
+// NewHealthIndicator creates an indicator in the unknown state.
+func NewHealthIndicator() *HealthIndicator {
+	h := &HealthIndicator{
+		dot:   canvas.NewCircle(healthColor(HealthUnknown)),
+		label: widget.NewLabel("后端: 检测中"),
+	}
+	dotBox := container.NewGridWrap(fyne.NewSize(10, 10), h.dot)
+	h.view = container.NewHBox(container.NewCenter(dotBox), h.label)
+	return h
+}
+
+// Object returns the canvas object to place in a layout.
+func (h *HealthIndicator) Object() fyne.CanvasObject { return h.view }
+
+// Set updates color and text; must be called on the UI thread (use fyne.Do).
+func (h *HealthIndicator) Set(level HealthLevel, text string) {
+	h.dot.FillColor = healthColor(level)
+	h.dot.Refresh()
+	h.label.SetText(text)
+}
+
+func healthColor(level HealthLevel) color.Color {
+	switch level {
+	case HealthOK:
+		return color.NRGBA{R: 46, G: 160, B: 67, A: 255}
+	case HealthWarn:
+		return color.NRGBA{R: 255, G: 165, B: 0, A: 255}
+	case HealthError:
+		return color.NRGBA{R: 220, G: 53, B: 69, A: 255}
+	default:
+		return color.NRGBA{R: 160, G: 160, B: 160, A: 255}
+	}
+}
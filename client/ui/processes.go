@@ -0,0 +1,323 @@
+package ui
+
+// ProcessesPanel lists the processes of one remote host, as in the design's
+// ProcessManager: a sortable table with a filter, an auto-refresh interval
+// and signal / renice actions on the selected row. Sorting and filtering are
+// done by the backend; the panel only keeps the current query and delegates
+// fetching and actions to callbacks.
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Process is the display model of one remote process.
+type Process struct {
+	PID     int
+	User    string
+	CPU     float64 // percent
+	Mem     float64 // percent
+	RSS     int64
+	Nice    int
+	State   string
+	Command string
+	Started time.Time
+}
+
+// ProcessQuery is the sort key and filter currently chosen in the panel.
+// Sort uses the backend keys; a leading "-" means descending.
+type ProcessQuery struct {
+	Sort   string
+	Filter string
+}
+
+// ProcessesPanelProps defines the callbacks for panel interactions.
+type ProcessesPanelProps struct {
+	// Signals offered in the "更多信号" menu, without the SIG prefix.
+	Signals []string
+	// OnQuery is called whenever the sort, the filter or the refresh button asks for a new listing.
+	OnQuery func(q ProcessQuery)
+	// OnInterval is called when the auto-refresh interval changes; 0 turns it off.
+	// The panel starts at 2 seconds, see Interval.
+	OnInterval func(d time.Duration)
+	OnSignal   func(p Process, signal string)
+	OnRenice   func(p Process)
+}
+
+var processColumns = []struct {
+	title string
+	sort  string // backend sort key, "" when the column is not sortable
+	desc  bool   // direction on first click
+	width float32
+}{
+	{"PID", "pid", false, 70},
+	{"用户", "user", false, 90},
+	{"CPU%", "cpu", true, 60},
+	{"内存%", "mem", true, 65},
+	{"常驻内存", "mem", true, 90},
+	{"NI", "", false, 40},
+	{"状态", "", false, 50},
+	{"启动时间", "start", true, 130},
+	{"命令", "command", false, 420},
+}
+
+var processIntervals = []struct {
+	label string
+	every time.Duration
+}{
+	{"不自动刷新", 0}, {"每 2 秒", 2 * time.Second}, {"每 5 秒", 5 * time.Second}, {"每 10 秒", 10 * time.Second},
+}
+
+// ProcessesPanel is meant to fill its own window.
+type ProcessesPanel struct {
+	props    ProcessesPanelProps
+	query    ProcessQuery
+	interval time.Duration
+	items    []Process
+	selected int // row index, -1 when nothing is selected
+
+	table   *widget.Table
+	filter  *widget.Entry
+	status  *widget.Label
+	message *widget.Label
+	actions []fyne.Disableable
+	view    fyne.CanvasObject
+}
+
+// NewProcessesPanel creates an empty panel sorted by CPU usage.
+func NewProcessesPanel(props ProcessesPanelProps) *ProcessesPanel {
+	p := &ProcessesPanel{props: props, query: ProcessQuery{Sort: "-cpu"}, selected: -1}
+	p.table = widget.NewTableWithHeaders(
+		func() (int, int) { return len(p.items), len(processColumns) },
+		func() fyne.CanvasObject {
+			l := widget.NewLabel("")
+			l.Truncation = fyne.TextTruncateEllipsis
+			return l
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(p.cellText(p.items[id.Row], id.Col))
+		},
+	)
+	p.table.ShowHeaderColumn = false
+	p.table.CreateHeader = func() fyne.CanvasObject {
+		b := widget.NewButton("", nil)
+		b.Importance = widget.LowImportance
+		b.Alignment = widget.ButtonAlignLeading
+		return b
+	}
+	p.table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col < 0 {
+			return
+		}
+		b := o.(*widget.Button)
+		c := processColumns[id.Col]
+		b.SetText(c.title + p.sortMark(id.Col))
+		b.OnTapped = func() { p.sortBy(id.Col) }
+	}
+	for i, c := range processColumns {
+		p.table.SetColumnWidth(i, c.width)
+	}
+	p.table.OnSelected = func(id widget.TableCellID) {
+		p.selected = id.Row
+		p.setActionsEnabled(true)
+	}
+	p.table.OnUnselected = func(widget.TableCellID) {
+		p.selected = -1
+		p.setActionsEnabled(false)
+	}
+
+	p.filter = widget.NewEntry()
+	p.filter.SetPlaceHolder("按命令、用户或 PID 过滤")
+	p.filter.OnSubmitted = func(string) { p.requery() }
+	p.filter.OnChanged = func(s string) {
+		// clearing the filter shows everything again without pressing Enter
+		if s == "" {
+			p.requery()
+		}
+	}
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), p.requery)
+	interval := widget.NewSelect(nil, nil)
+	for _, it := range processIntervals {
+		interval.Options = append(interval.Options, it.label)
+	}
+	interval.SetSelectedIndex(1)
+	p.interval = processIntervals[1].every
+	interval.OnChanged = func(label string) {
+		for _, it := range processIntervals {
+			if it.label == label {
+				p.interval = it.every
+				if props.OnInterval != nil {
+					props.OnInterval(it.every)
+				}
+			}
+		}
+	}
+	top := container.NewBorder(nil, nil, nil, container.NewHBox(interval, refreshBtn), p.filter)
+
+	termBtn := widget.NewButton("结束进程", func() { p.signal("TERM") })
+	killBtn := widget.NewButton("强制结束", func() { p.signal("KILL") })
+	killBtn.Importance = widget.DangerImportance
+	var moreBtn *widget.Button
+	moreBtn = widget.NewButton("更多信号", func() {
+		var items []*fyne.MenuItem
+		for _, sig := range props.Signals {
+			items = append(items, fyne.NewMenuItem("SIG"+sig, func() { p.signal(sig) }))
+		}
+		c := fyne.CurrentApp().Driver().CanvasForObject(moreBtn)
+		if c == nil || len(items) == 0 {
+			return
+		}
+		pos := fyne.CurrentApp().Driver().AbsolutePositionForObject(moreBtn)
+		widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), c, pos.AddXY(0, moreBtn.Size().Height))
+	})
+	niceBtn := widget.NewButton("调整优先级", func() {
+		if pr := p.selectedProcess(); pr != nil && props.OnRenice != nil {
+			props.OnRenice(*pr)
+		}
+	})
+	p.actions = []fyne.Disableable{termBtn, killBtn, moreBtn, niceBtn}
+	p.setActionsEnabled(false)
+	p.status = widget.NewLabel("")
+	bottom := container.NewBorder(nil, nil, nil, container.NewHBox(termBtn, killBtn, moreBtn, niceBtn), p.status)
+
+	p.message = widget.NewLabel("加载中…")
+	p.message.Wrapping = fyne.TextWrapWord
+	p.message.Alignment = fyne.TextAlignCenter
+	p.view = container.NewBorder(top, bottom, nil, nil,
+		container.NewStack(p.table, container.NewCenter(p.message)))
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *ProcessesPanel) Object() fyne.CanvasObject { return p.view }
+
+// Interval returns the auto-refresh interval, 0 when it is off.
+func (p *ProcessesPanel) Interval() time.Duration { return p.interval }
+
+// Query returns the sort and filter to use for the next listing.
+func (p *ProcessesPanel) Query() ProcessQuery { return p.query }
+
+// Set shows a listing, keeping the selected process selected when it still
+// exists; must be called on the UI thread (use fyne.Do).
+func (p *ProcessesPanel) Set(items []Process, status string) {
+	pid := 0
+	if pr := p.selectedProcess(); pr != nil {
+		pid = pr.PID
+	}
+	p.items = items
+	p.status.SetText(status)
+	if len(items) == 0 {
+		p.message.SetText("没有匹配的进程")
+		p.message.Show()
+	} else {
+		p.message.Hide()
+	}
+	p.table.Refresh()
+	for i, it := range items {
+		if pid != 0 && it.PID == pid {
+			p.table.Select(widget.TableCellID{Row: i})
+			return
+		}
+	}
+	p.table.UnselectAll()
+}
+
+// SetError shows why the listing failed while keeping the previous rows.
+func (p *ProcessesPanel) SetError(err error) {
+	p.status.SetText("刷新失败: " + err.Error())
+	if len(p.items) == 0 {
+		p.message.SetText(err.Error())
+		p.message.Show()
+	}
+}
+
+func (p *ProcessesPanel) requery() {
+	p.query.Filter = strings.TrimSpace(p.filter.Text)
+	if p.props.OnQuery != nil {
+		p.props.OnQuery(p.query)
+	}
+}
+
+// sortBy switches to the column's key, or flips the direction when it is already active.
+func (p *ProcessesPanel) sortBy(col int) {
+	c := processColumns[col]
+	if c.sort == "" {
+		return
+	}
+	key, desc := strings.CutPrefix(p.query.Sort, "-")
+	if key == c.sort {
+		desc = !desc
+	} else {
+		desc = c.desc
+	}
+	p.query.Sort = c.sort
+	if desc {
+		p.query.Sort = "-" + c.sort
+	}
+	p.table.Refresh()
+	p.requery()
+}
+
+func (p *ProcessesPanel) sortMark(col int) string {
+	key, desc := strings.CutPrefix(p.query.Sort, "-")
+	if processColumns[col].sort != key {
+		return ""
+	}
+	if desc {
+		return " ▼"
+	}
+	return " ▲"
+}
+
+func (p *ProcessesPanel) signal(sig string) {
+	if pr := p.selectedProcess(); pr != nil && p.props.OnSignal != nil {
+		p.props.OnSignal(*pr, sig)
+	}
+}
+
+func (p *ProcessesPanel) selectedProcess() *Process {
+	if p.selected < 0 || p.selected >= len(p.items) {
+		return nil
+	}
+	pr := p.items[p.selected]
+	return &pr
+}
+
+func (p *ProcessesPanel) setActionsEnabled(on bool) {
+	for _, b := range p.actions {
+		if on {
+			b.Enable()
+		} else {
+			b.Disable()
+		}
+	}
+}
+
+func (p *ProcessesPanel) cellText(pr Process, col int) string {
+	switch col {
+	case 0:
+		return fmt.Sprint(pr.PID)
+	case 1:
+		return pr.User
+	case 2:
+		return fmt.Sprintf("%.1f", pr.CPU)
+	case 3:
+		return fmt.Sprintf("%.1f", pr.Mem)
+	case 4:
+		return FormatBytes(pr.RSS)
+	case 5:
+		return fmt.Sprint(pr.Nice)
+	case 6:
+		return pr.State
+	case 7:
+		return pr.Started.Local().Format("01-02 15:04:05")
+	default:
+		return pr.Command
+	}
+}
@@ -0,0 +1,145 @@
+package ui
+
+// RecordingsPanel lists the session recordings kept by the backend, newest
+// first, and hands the selected one to OnPlay for replay.
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Recording is the display model of one session recording.
+type Recording struct {
+	ID       string
+	Host     string
+	Started  time.Time
+	Duration float64 // seconds
+	Size     int64
+	Input    bool // keystrokes were recorded too
+	Active   bool // the session is still running
+}
+
+// RecordingsPanelProps defines the callbacks for panel interactions.
+type RecordingsPanelProps struct {
+	OnRefresh func()
+	OnPlay    func(r Recording)
+}
+
+var recordingColumns = []struct {
+	title string
+	width float32
+}{
+	{"开始时间", 150}, {"主机", 220}, {"时长", 80}, {"大小", 90}, {"状态", 150},
+}
+
+// RecordingsPanel is meant to fill its own window.
+type RecordingsPanel struct {
+	items    []Recording
+	selected int
+	table    *widget.Table
+	message  *widget.Label
+	playBtn  *widget.Button
+	view     fyne.CanvasObject
+}
+
+// NewRecordingsPanel creates an empty panel, see Set.
+func NewRecordingsPanel(props RecordingsPanelProps) *RecordingsPanel {
+	p := &RecordingsPanel{selected: -1}
+	p.table = widget.NewTableWithHeaders(
+		func() (int, int) { return len(p.items), len(recordingColumns) },
+		func() fyne.CanvasObject {
+			l := widget.NewLabel("")
+			l.Truncation = fyne.TextTruncateEllipsis
+			return l
+		},
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(recordingCellText(p.items[id.Row], id.Col))
+		},
+	)
+	p.table.ShowHeaderColumn = false
+	p.table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 {
+			o.(*widget.Label).SetText(recordingColumns[id.Col].title)
+		}
+	}
+	for i, c := range recordingColumns {
+		p.table.SetColumnWidth(i, c.width)
+	}
+	play := func() {
+		if p.selected >= 0 && p.selected < len(p.items) && props.OnPlay != nil {
+			props.OnPlay(p.items[p.selected])
+		}
+	}
+	p.table.OnSelected = func(id widget.TableCellID) {
+		p.selected = id.Row
+		p.playBtn.Enable()
+	}
+	p.table.OnUnselected = func(widget.TableCellID) {
+		p.selected = -1
+		p.playBtn.Disable()
+	}
+	p.playBtn = widget.NewButton("回放", play)
+	p.playBtn.Importance = widget.HighImportance
+	p.playBtn.Disable()
+	refreshBtn := widget.NewButton("刷新", func() {
+		if props.OnRefresh != nil {
+			props.OnRefresh()
+		}
+	})
+	p.message = widget.NewLabel("加载中…")
+	p.message.Wrapping = fyne.TextWrapWord
+	p.message.Alignment = fyne.TextAlignCenter
+	bottom := container.NewHBox(refreshBtn, p.playBtn)
+	p.view = container.NewBorder(nil, bottom, nil, nil,
+		container.NewStack(p.table, container.NewCenter(p.message)))
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *RecordingsPanel) Object() fyne.CanvasObject { return p.view }
+
+// Set shows a listing; must be called on the UI thread (use fyne.Do).
+func (p *RecordingsPanel) Set(items []Recording) {
+	p.items = items
+	p.table.UnselectAll()
+	if len(items) == 0 {
+		p.message.SetText("还没有录像。以 -record output 或 -record input 启动后端后，新打开的终端会自动录像。")
+		p.message.Show()
+	} else {
+		p.message.Hide()
+	}
+	p.table.Refresh()
+}
+
+// SetError shows why the listing failed.
+func (p *RecordingsPanel) SetError(err error) {
+	p.items = nil
+	p.table.Refresh()
+	p.message.SetText("获取录像失败: " + err.Error())
+	p.message.Show()
+}
+
+func recordingCellText(r Recording, col int) string {
+	switch col {
+	case 0:
+		return r.Started.Local().Format("2006-01-02 15:04:05")
+	case 1:
+		return r.Host
+	case 2:
+		return FormatClock(r.Duration)
+	case 3:
+		return FormatBytes(r.Size)
+	default:
+		s := "已结束"
+		if r.Active {
+			s = "录制中"
+		}
+		if r.Input {
+			s += " · 含键盘输入"
+		}
+		return s
+	}
+}
@@ -0,0 +1,266 @@
+package ui
+
+// ReplayPanel plays an asciicast v2 session recording into a read-only
+// terminal, with play/pause, a seek slider and playback speed. Seeking
+// backwards cannot undo what a terminal has drawn, so every seek starts a fresh
+// terminal and feeds it all output up to the target time at once.
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	terminal "github.com/fyne-io/terminal"
+)
+
+// Cast is a parsed asciicast v2 recording.
+type Cast struct {
+	Width  int
+	Height int
+	Title  string
+	Events []CastEvent
+}
+
+// CastEvent is one line of the event stream: "o" output, "i" input, "r" resize.
+type CastEvent struct {
+	Time float64 // seconds since the start
+	Code string
+	Data string
+}
+
+// Duration returns the time of the last event.
+func (c Cast) Duration() float64 {
+	if len(c.Events) == 0 {
+		return 0
+	}
+	return c.Events[len(c.Events)-1].Time
+}
+
+// ParseCast parses an asciicast v2 file. Malformed event lines are skipped,
+// as the last line of a recording still in progress may be incomplete.
+func ParseCast(data []byte) (Cast, error) {
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16<<20)
+	if !sc.Scan() {
+		return Cast{}, errors.New("empty recording")
+	}
+	var h struct {
+		Version int    `json:"version"`
+		Width   int    `json:"width"`
+		Height  int    `json:"height"`
+		Title   string `json:"title"`
+	}
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil || h.Version != 2 {
+		return Cast{}, errors.New("not an asciicast v2 recording")
+	}
+	c := Cast{Width: h.Width, Height: h.Height, Title: h.Title}
+	for sc.Scan() {
+		var ev []any
+		if json.Unmarshal(sc.Bytes(), &ev) != nil || len(ev) != 3 {
+			continue
+		}
+		t, ok1 := ev[0].(float64)
+		code, ok2 := ev[1].(string)
+		s, ok3 := ev[2].(string)
+		if ok1 && ok2 && ok3 {
+			c.Events = append(c.Events, CastEvent{Time: t, Code: code, Data: s})
+		}
+	}
+	return c, sc.Err()
+}
+
+var replaySpeeds = []struct {
+	label string
+	speed float64
+}{
+	{"0.5x", 0.5}, {"1x", 1}, {"2x", 2}, {"4x", 4}, {"8x", 8},
+}
+
+// replayTick is how often the player advances its clock.
+const replayTick = 33 * time.Millisecond
+
+// ReplayPanel is meant to fill its own window; call Close when it goes away.
+type ReplayPanel struct {
+	cast     Cast
+	duration float64
+
+	mu      sync.Mutex
+	clock   float64 // playback position in seconds
+	next    int     // index of the first event not yet written
+	speed   float64
+	playing bool
+	out     *io.PipeWriter // feeds the current terminal
+	pending []byte         // output written to out by the next tick
+
+	screen  *fyne.Container
+	playBtn *widget.Button
+	slider  *widget.Slider
+	clockL  *widget.Label
+	view    fyne.CanvasObject
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewReplayPanel creates a player positioned at the start, paused.
+func NewReplayPanel(cast Cast) *ReplayPanel {
+	p := &ReplayPanel{cast: cast, duration: cast.Duration(), speed: 1, stop: make(chan struct{})}
+	p.screen = container.NewStack()
+	p.playBtn = widget.NewButton("播放", p.toggle)
+	p.slider = widget.NewSlider(0, max(p.duration, 0.1))
+	p.slider.Step = 0.1
+	p.slider.OnChangeEnded = p.Seek
+	p.clockL = widget.NewLabel("")
+	speed := widget.NewSelect(nil, nil)
+	for _, s := range replaySpeeds {
+		speed.Options = append(speed.Options, s.label)
+	}
+	speed.SetSelected("1x")
+	speed.OnChanged = func(label string) {
+		for _, s := range replaySpeeds {
+			if s.label == label {
+				p.mu.Lock()
+				p.speed = s.speed
+				p.mu.Unlock()
+			}
+		}
+	}
+	controls := container.NewBorder(nil, nil, p.playBtn, container.NewHBox(p.clockL, speed), p.slider)
+	p.view = container.NewBorder(nil, controls, nil, nil, p.screen)
+	p.Seek(0)
+	go p.run()
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *ReplayPanel) Object() fyne.CanvasObject { return p.view }
+
+// Close stops playback and releases the terminal.
+func (p *ReplayPanel) Close() {
+	p.once.Do(func() {
+		close(p.stop)
+		p.mu.Lock()
+		if p.out != nil {
+			p.out.Close()
+		}
+		p.mu.Unlock()
+	})
+}
+
+// Seek jumps to t seconds: a new terminal receives all output up to t.
+// Must be called on the UI thread.
+func (p *ReplayPanel) Seek(t float64) {
+	t = min(max(t, 0), p.duration)
+	term := terminal.New()
+	r, w := io.Pipe()
+	go func() { _ = term.RunWithConnection(discardCloser{}, r) }()
+
+	p.mu.Lock()
+	old := p.out
+	p.out, p.clock, p.next = w, t, 0
+	var buf []byte
+	for p.next < len(p.cast.Events) && p.cast.Events[p.next].Time <= t {
+		if ev := p.cast.Events[p.next]; ev.Code == "o" {
+			buf = append(buf, ev.Data...)
+		}
+		p.next++
+	}
+	p.pending = buf
+	p.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	p.screen.Objects = []fyne.CanvasObject{term}
+	p.screen.Refresh()
+	p.updateControls()
+}
+
+func (p *ReplayPanel) toggle() {
+	p.mu.Lock()
+	ended := p.next >= len(p.cast.Events)
+	p.playing = !p.playing
+	p.mu.Unlock()
+	if ended {
+		// replay from the start once the end has been reached
+		p.Seek(0)
+		p.mu.Lock()
+		p.playing = true
+		p.mu.Unlock()
+	}
+	p.updateControls()
+}
+
+// run advances the clock while playing and writes due output to the terminal.
+func (p *ReplayPanel) run() {
+	t := time.NewTicker(replayTick)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-t.C:
+		}
+		p.mu.Lock()
+		moved := p.playing
+		if p.playing {
+			p.clock += replayTick.Seconds() * p.speed
+			for p.next < len(p.cast.Events) && p.cast.Events[p.next].Time <= p.clock {
+				if ev := p.cast.Events[p.next]; ev.Code == "o" {
+					p.pending = append(p.pending, ev.Data...)
+				}
+				p.next++
+			}
+			if p.next >= len(p.cast.Events) {
+				p.playing, p.clock = false, p.duration
+			}
+		}
+		w, buf := p.out, p.pending
+		p.pending = nil
+		p.mu.Unlock()
+		if len(buf) > 0 {
+			// blocks until the terminal has consumed it; a seek closes w and unblocks
+			_, _ = w.Write(buf)
+		}
+		if moved {
+			fyne.Do(p.updateControls)
+		}
+	}
+}
+
+func (p *ReplayPanel) updateControls() {
+	p.mu.Lock()
+	clock, playing, ended := p.clock, p.playing, p.next >= len(p.cast.Events)
+	p.mu.Unlock()
+	switch {
+	case playing:
+		p.playBtn.SetText("暂停")
+	case ended:
+		p.playBtn.SetText("重新播放")
+	default:
+		p.playBtn.SetText("播放")
+	}
+	p.slider.SetValue(clock)
+	p.clockL.SetText(FormatClock(clock) + " / " + FormatClock(p.duration))
+}
+
+// FormatClock formats seconds as m:ss, or h:mm:ss from one hour on.
+func FormatClock(sec float64) string {
+	s := int(sec)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// discardCloser drops what is typed into the replay terminal.
+type discardCloser struct{}
+
+func (discardCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (discardCloser) Close() error                { return nil }
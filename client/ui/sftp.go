@@ -0,0 +1,352 @@
+package ui
+
+// SFTPPanel browses the remote file system of the host behind the selected
+// terminal tab: a name/size/permissions/modified table (as in the design's
+// SFTPManager), breadcrumb navigation and per-file context actions. Listing
+// and file operations are delegated to callbacks so the panel stays
+// presentation-only.
+
+import (
+	"image/color"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// RemoteFile is the display model of one remote directory entry.
+type RemoteFile struct {
+	Name       string
+	Path       string
+	IsDir      bool // directories and symlinks to directories
+	IsLink     bool
+	LinkTarget string
+	Size       int64
+	Mode       string // e.g. "drwxr-xr-x"
+	Perm       string // e.g. "0755"
+	ModTime    time.Time
+}
+
+// SFTPPanelProps defines the callbacks for panel interactions. Paths are
+// absolute remote paths; OnNavigate receives "" for the home directory.
+type SFTPPanelProps struct {
+	OnNavigate func(path string)
+	OnUpload   func(dir string)
+	OnDownload func(f RemoteFile)
+	OnMkdir    func(dir string)
+	OnSymlink  func(dir string, target *RemoteFile)
+	OnRename   func(f RemoteFile)
+	OnChmod    func(f RemoteFile)
+	OnDelete   func(f RemoteFile)
+}
+
+var sftpColumns = []struct {
+	title string
+	width float32
+}{
+	{"名称", 200}, {"大小", 80}, {"权限", 100}, {"修改时间", 130},
+}
+
+// SFTPPanel is a fixed-width side panel showing one remote directory.
+type SFTPPanel struct {
+	props    SFTPPanelProps
+	path     string
+	items    []RemoteFile
+	selected int
+
+	host    *widget.Label
+	crumbs  *fyne.Container
+	table   *widget.Table
+	message *widget.Label
+	actions []*widget.Button // enabled only while an entry is selected
+	toolbar *fyne.Container
+	view    *fyne.Container
+}
+
+// NewSFTPPanel creates a panel with no host attached.
+func NewSFTPPanel(props SFTPPanelProps) *SFTPPanel {
+	p := &SFTPPanel{props: props, selected: -1}
+	p.table = widget.NewTableWithHeaders(
+		func() (int, int) { return len(p.items), len(sftpColumns) },
+		func() fyne.CanvasObject { return newFileCell(p) },
+		func(id widget.TableCellID, o fyne.CanvasObject) {
+			cell := o.(*fileCell)
+			cell.row = id.Row
+			cell.SetText(p.cellText(p.items[id.Row], id.Col))
+		},
+	)
+	p.table.ShowHeaderColumn = false
+	p.table.CreateHeader = func() fyne.CanvasObject {
+		l := widget.NewLabel("")
+		l.TextStyle = fyne.TextStyle{Bold: true}
+		return l
+	}
+	p.table.UpdateHeader = func(id widget.TableCellID, o fyne.CanvasObject) {
+		if id.Col >= 0 {
+			o.(*widget.Label).SetText(sftpColumns[id.Col].title)
+		}
+	}
+	for i, c := range sftpColumns {
+		p.table.SetColumnWidth(i, c.width)
+	}
+	p.table.OnSelected = func(id widget.TableCellID) {
+		p.selected = id.Row
+		p.setActionsEnabled(true)
+	}
+	p.table.OnUnselected = func(widget.TableCellID) {
+		p.selected = -1
+		p.setActionsEnabled(false)
+	}
+
+	p.host = widget.NewLabel("SFTP")
+	p.host.TextStyle = fyne.TextStyle{Bold: true}
+	p.host.Truncation = fyne.TextTruncateEllipsis
+	p.crumbs = container.NewHBox()
+	homeBtn := widget.NewButtonWithIcon("", theme.HomeIcon(), func() { p.navigate("") })
+	upBtn := widget.NewButtonWithIcon("", theme.MoveUpIcon(), func() {
+		if p.path != "" && p.path != "/" {
+			p.navigate(parentDir(p.path))
+		}
+	})
+	refreshBtn := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { p.navigate(p.path) })
+	nav := container.NewBorder(nil, nil, container.NewHBox(homeBtn, upBtn), refreshBtn,
+		container.NewHScroll(p.crumbs))
+
+	uploadBtn := widget.NewButton("上传", func() {
+		if p.props.OnUpload != nil && p.path != "" {
+			p.props.OnUpload(p.path)
+		}
+	})
+	downloadBtn := widget.NewButton("下载", func() { p.act(p.props.OnDownload) })
+	mkdirBtn := widget.NewButton("新建文件夹", func() {
+		if p.props.OnMkdir != nil && p.path != "" {
+			p.props.OnMkdir(p.path)
+		}
+	})
+	linkBtn := widget.NewButton("新建链接", func() {
+		if p.props.OnSymlink != nil && p.path != "" {
+			p.props.OnSymlink(p.path, p.selectedFile())
+		}
+	})
+	renameBtn := widget.NewButton("重命名", func() { p.act(p.props.OnRename) })
+	chmodBtn := widget.NewButton("权限", func() { p.act(p.props.OnChmod) })
+	deleteBtn := widget.NewButton("删除", func() { p.act(p.props.OnDelete) })
+	deleteBtn.Importance = widget.DangerImportance
+	p.actions = []*widget.Button{downloadBtn, renameBtn, chmodBtn, deleteBtn}
+	p.setActionsEnabled(false)
+	p.toolbar = container.NewHBox(uploadBtn, downloadBtn, mkdirBtn, linkBtn, renameBtn, chmodBtn, deleteBtn)
+
+	p.message = widget.NewLabel("")
+	p.message.Wrapping = fyne.TextWrapWord
+	p.message.Alignment = fyne.TextAlignCenter
+	body := container.NewStack(p.table, container.NewCenter(p.message))
+
+	// Border only honours MinSize for side panels; a transparent strut fixes the width.
+	strut := canvas.NewRectangle(color.Transparent)
+	strut.SetMinSize(fyne.NewSize(520, 0))
+	p.view = container.NewStack(strut, container.NewBorder(
+		container.NewVBox(p.host, nav), container.NewHScroll(p.toolbar), nil, nil, body))
+	p.Clear("当前标签未连接远程主机")
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *SFTPPanel) Object() fyne.CanvasObject { return p.view }
+
+// Path returns the directory currently shown, or "" before the first listing.
+func (p *SFTPPanel) Path() string { return p.path }
+
+// SetHost switches the panel to another host and shows a loading hint until
+// the first Set; must be called on the UI thread (use fyne.Do).
+func (p *SFTPPanel) SetHost(title string) {
+	p.host.SetText("SFTP · " + title)
+	p.reset("加载中…")
+	p.toolbar.Show()
+}
+
+// Clear detaches the panel from any host and shows msg instead of a listing.
+func (p *SFTPPanel) Clear(msg string) {
+	p.host.SetText("SFTP")
+	p.reset(msg)
+	p.toolbar.Hide()
+}
+
+// Set shows the entries of dir; must be called on the UI thread (use fyne.Do).
+func (p *SFTPPanel) Set(dir string, items []RemoteFile) {
+	p.path = dir
+	p.items = items
+	p.table.UnselectAll()
+	p.table.ScrollToTop()
+	p.setCrumbs(dir)
+	if len(items) == 0 {
+		p.message.SetText("空目录")
+		p.message.Show()
+	} else {
+		p.message.Hide()
+	}
+	p.table.Refresh()
+}
+
+// SetError replaces the listing with an error, e.g. when the host has no
+// SFTP subsystem; must be called on the UI thread (use fyne.Do).
+func (p *SFTPPanel) SetError(err error) {
+	p.reset(err.Error())
+}
+
+func (p *SFTPPanel) reset(msg string) {
+	p.path = ""
+	p.items = nil
+	p.table.UnselectAll()
+	p.crumbs.RemoveAll()
+	p.message.SetText(msg)
+	p.message.Show()
+	p.table.Refresh()
+}
+
+func (p *SFTPPanel) navigate(dir string) {
+	if p.props.OnNavigate != nil {
+		p.props.OnNavigate(dir)
+	}
+}
+
+// open enters a directory row; files have no default action.
+func (p *SFTPPanel) open(row int) {
+	if row >= 0 && row < len(p.items) && p.items[row].IsDir {
+		p.navigate(p.items[row].Path)
+	}
+}
+
+func (p *SFTPPanel) act(fn func(RemoteFile)) {
+	if f := p.selectedFile(); f != nil && fn != nil {
+		fn(*f)
+	}
+}
+
+func (p *SFTPPanel) selectedFile() *RemoteFile {
+	if p.selected < 0 || p.selected >= len(p.items) {
+		return nil
+	}
+	f := p.items[p.selected]
+	return &f
+}
+
+func (p *SFTPPanel) setActionsEnabled(on bool) {
+	for _, b := range p.actions {
+		if on {
+			b.Enable()
+		} else {
+			b.Disable()
+		}
+	}
+}
+
+// setCrumbs renders one button per path segment, e.g. / › home › alice.
+func (p *SFTPPanel) setCrumbs(dir string) {
+	p.crumbs.RemoveAll()
+	add := func(label, target string) {
+		b := widget.NewButton(label, func() { p.navigate(target) })
+		b.Importance = widget.LowImportance
+		p.crumbs.Add(b)
+	}
+	add("/", "/")
+	cur := ""
+	for _, seg := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		cur += "/" + seg
+		p.crumbs.Add(widget.NewLabel("›"))
+		add(seg, cur)
+	}
+}
+
+// contextMenu shows the actions for one row at the pointer position.
+func (p *SFTPPanel) contextMenu(row int, obj fyne.CanvasObject, pos fyne.Position) {
+	if row < 0 || row >= len(p.items) {
+		return
+	}
+	p.table.Select(widget.TableCellID{Row: row})
+	f := p.items[row]
+	var items []*fyne.MenuItem
+	if f.IsDir {
+		items = append(items, fyne.NewMenuItem("打开", func() { p.navigate(f.Path) }))
+	}
+	items = append(items,
+		fyne.NewMenuItem("下载", func() { p.act(p.props.OnDownload) }),
+		fyne.NewMenuItem("重命名", func() { p.act(p.props.OnRename) }),
+		fyne.NewMenuItem("修改权限", func() { p.act(p.props.OnChmod) }),
+		fyne.NewMenuItem("创建指向它的链接", func() {
+			if p.props.OnSymlink != nil {
+				p.props.OnSymlink(p.path, &f)
+			}
+		}),
+		fyne.NewMenuItemSeparator(),
+		fyne.NewMenuItem("删除", func() { p.act(p.props.OnDelete) }),
+	)
+	c := fyne.CurrentApp().Driver().CanvasForObject(obj)
+	if c == nil {
+		return
+	}
+	widget.ShowPopUpMenuAtPosition(fyne.NewMenu("", items...), c, pos)
+}
+
+func (p *SFTPPanel) cellText(f RemoteFile, col int) string {
+	switch col {
+	case 0:
+		name := f.Name
+		if f.IsDir {
+			name += "/"
+		}
+		if f.IsLink {
+			name += " → " + f.LinkTarget
+		}
+		return name
+	case 1:
+		if f.IsDir {
+			return "-"
+		}
+		return FormatBytes(f.Size)
+	case 2:
+		return f.Mode
+	default:
+		return f.ModTime.Local().Format("2006-01-02 15:04")
+	}
+}
+
+func parentDir(dir string) string {
+	i := strings.LastIndex(strings.TrimSuffix(dir, "/"), "/")
+	if i <= 0 {
+		return "/"
+	}
+	return dir[:i]
+}
+
+// fileCell is a table cell that selects its row on tap, opens directories on
+// double tap and shows the context menu on secondary tap. Table cells that
+// handle any tap event receive all of them, so selection is forwarded here.
+type fileCell struct {
+	widget.Label
+	panel *SFTPPanel
+	row   int
+}
+
+func newFileCell(p *SFTPPanel) *fileCell {
+	c := &fileCell{panel: p}
+	c.Truncation = fyne.TextTruncateEllipsis
+	c.ExtendBaseWidget(c)
+	return c
+}
+
+func (c *fileCell) Tapped(*fyne.PointEvent) {
+	c.panel.table.Select(widget.TableCellID{Row: c.row})
+}
+
+func (c *fileCell) DoubleTapped(*fyne.PointEvent) { c.panel.open(c.row) }
+
+func (c *fileCell) TappedSecondary(e *fyne.PointEvent) {
+	c.panel.contextMenu(c.row, c, e.AbsolutePosition)
+}
@@ -4,11 +4,11 @@ package ui
 // We use Fyne's AppTabs for tabbed UI, and provide simple APIs to add/remove tabs.
 
 import (
-    "fmt"
+	"fmt"
 
-    "fyne.io/fyne/v2"
-    "fyne.io/fyne/v2/container"
-    "fyne.io/fyne/v2/widget"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
 )
 
 type TabBar struct {
@@ -16,9 +16,32 @@ type TabBar struct {
 	AddBtn  *widget.Button
 	OnAdd   func()
 	OnClose func(tabTitle string)
-	// Quick toggle buttons
+	// OnSelect is called when the selected tab changes, including after a tab is closed
+	OnSelect func()
+	// Quick toggle buttons; ToggleSFTPBtn's OnTapped is wired by the caller
 	ToggleSFTPBtn     *widget.Button
 	ToggleExplorerBtn *widget.Button
+	// Shows/hides the port forwarding panel; OnTapped is wired by the caller
+	ToggleTunnelsBtn *widget.Button
+	// Shows/hides the file transfer queue; OnTapped is wired by the caller
+	ToggleTransfersBtn *widget.Button
+	// Opens the process manager for the selected tab's host; OnTapped is wired by the caller
+	ProcessesBtn *widget.Button
+	// Saves the selected tab's session as text or HTML; OnTapped is wired by the caller
+	ExportBtn *widget.Button
+
+	closers map[*container.TabItem]func()
+	conns   map[*container.TabItem]string
+	// titles holds the tab titles without the broadcast marker
+	titles map[*container.TabItem]string
+	// scrollbacks holds the captured output of remote tabs, see ExportCurrent
+	scrollbacks map[*container.TabItem]*scrollback
+
+	bcast           broadcast
+	broadcastBtn    *widget.Check
+	excludeBtn      *widget.Button
+	broadcastChecks *fyne.Container
+	broadcastRow    *fyne.Container
 }
 
 // NewTabBar creates a TabBar with an "+ 新终端" button.
@@ -30,25 +53,83 @@ func NewTabBar(onAdd func(), onClose func(tabTitle string)) *TabBar {
 				onAdd()
 			}
 		}),
-		OnAdd:   onAdd,
-		OnClose: onClose,
+		OnAdd:       onAdd,
+		OnClose:     onClose,
+		closers:     make(map[*container.TabItem]func()),
+		conns:       make(map[*container.TabItem]string),
+		titles:      make(map[*container.TabItem]string),
+		scrollbacks: make(map[*container.TabItem]*scrollback),
+		bcast: broadcast{
+			members: make(map[*container.TabItem]bool),
+			inputs:  make(map[*container.TabItem]*tabInput),
+		},
 	}
-	t.ToggleSFTPBtn = widget.NewButton("隐藏SFTP", func() {
-		// TODO: hook this to actual SFTP panel visibility
-		fmt.Println("[USER] 点击了隐藏SFTP按钮（占位）")
-	})
+	t.Tabs.OnSelected = func(*container.TabItem) {
+		t.refreshBroadcast()
+		t.notifySelect()
+	}
+	t.ToggleSFTPBtn = widget.NewButton("显示SFTP", nil)
 	t.ToggleExplorerBtn = widget.NewButton("隐藏资源管理器", func() {
 		// TODO: hook this to actual explorer panel visibility
 		fmt.Println("[USER] 点击了隐藏资源管理器按钮（占位）")
 	})
+	t.ToggleTunnelsBtn = widget.NewButton("端口转发", nil)
+	t.ToggleTransfersBtn = widget.NewButton("文件传输", nil)
+	t.ProcessesBtn = widget.NewButton("进程管理", nil)
+	t.ExportBtn = widget.NewButton("导出会话", nil)
 	return t
 }
 
-// AddTerminalTab appends a new terminal tab.
-func (t *TabBar) AddTerminalTab(title string, content fyne.CanvasObject) {
+// AddTerminalTab appends a new terminal tab and returns it.
+func (t *TabBar) AddTerminalTab(title string, content fyne.CanvasObject) *container.TabItem {
 	tab := container.NewTabItem(title, content)
+	t.titles[tab] = title
 	t.Tabs.Append(tab)
 	t.Tabs.Select(tab)
+	return tab
+}
+
+// SetTabCloser registers a cleanup func (e.g. closing a remote shell) run when the tab is closed.
+func (t *TabBar) SetTabCloser(tab *container.TabItem, fn func()) {
+	t.closers[tab] = fn
+}
+
+// SetTabConn records the backend connection a remote terminal tab belongs to.
+func (t *TabBar) SetTabConn(tab *container.TabItem, connID string) {
+	t.conns[tab] = connID
+	if t.Tabs.Selected() == tab {
+		t.notifySelect()
+	}
+}
+
+// CurrentConn returns the connection ID of the selected tab, or "" for local tabs.
+func (t *TabBar) CurrentConn() string {
+	sel := t.Tabs.Selected()
+	if sel == nil {
+		return ""
+	}
+	return t.conns[sel]
+}
+
+// CurrentTitle returns the title of the selected tab without the broadcast marker.
+func (t *TabBar) CurrentTitle() string {
+	sel := t.Tabs.Selected()
+	if sel == nil {
+		return ""
+	}
+	return t.titles[sel]
+}
+
+func (t *TabBar) notifySelect() {
+	if t.OnSelect != nil {
+		t.OnSelect()
+	}
+}
+
+// SetTabTitle renames a tab, e.g. to mark a disconnected session.
+func (t *TabBar) SetTabTitle(tab *container.TabItem, title string) {
+	t.titles[tab] = title
+	t.refreshBroadcast()
 }
 
 // CloseCurrent closes the currently selected tab (if any).
@@ -57,22 +138,34 @@ func (t *TabBar) CloseCurrent() {
 	if sel == nil {
 		return
 	}
-	title := sel.Text
+	title := t.titles[sel]
+	delete(t.conns, sel)
+	delete(t.titles, sel)
+	delete(t.scrollbacks, sel)
 	t.Tabs.Remove(sel)
+	t.removeInput(sel)
+	if fn, ok := t.closers[sel]; ok {
+		delete(t.closers, sel)
+		fn()
+	}
+	// Removing the selected tab moves the selection without firing OnSelected
+	t.notifySelect()
 	if t.OnClose != nil {
 		t.OnClose(title)
 	}
 }
 
-// HeaderBar returns a control bar to place above the tabs, including add button.
+// HeaderBar returns a control bar to place above the tabs, including add button
+// and the broadcast input toggle with its participant row.
 func (t *TabBar) HeaderBar() *fyne.Container {
 	closeBtn := widget.NewButton("关闭当前", func() { t.CloseCurrent() })
-	controls := container.NewHBox(t.AddBtn, t.ToggleSFTPBtn, t.ToggleExplorerBtn)
-	return container.NewBorder(nil, nil, controls, closeBtn, nil)
+	row := t.broadcastBar()
+	controls := container.NewHBox(t.AddBtn, t.ToggleSFTPBtn, t.ToggleExplorerBtn, t.ToggleTunnelsBtn, t.ToggleTransfersBtn, t.ProcessesBtn, t.ExportBtn, t.broadcastBtn)
+	return container.NewVBox(container.NewBorder(nil, nil, controls, closeBtn, nil), row)
 }
 
 // DebugPopulate adds a welcome tab for initial state.
 func (t *TabBar) DebugPopulate() {
-    t.AddTerminalTab("欢迎", container.NewCenter(widget.NewLabel("请新建连接以开始使用终端")))
-    fmt.Println("[UI] TabBar 初始化完成，包含默认欢迎标签")
+	t.AddTerminalTab("欢迎", container.NewCenter(widget.NewLabel("请新建连接以开始使用终端")))
+	fmt.Println("[UI] TabBar 初始化完成，包含默认欢迎标签")
 }
@@ -1,12 +1,45 @@
 package ui
 
 import (
-    "fyne.io/fyne/v2"
-    terminal "github.com/fyne-io/terminal"
+	"io"
+
+	"fyne.io/fyne/v2"
+	terminal "github.com/fyne-io/terminal"
 )
 
 func NewLocalTerminal() fyne.CanvasObject {
-    t := terminal.New()
-    go t.RunLocalShell()
-    return t
+	t := terminal.New()
+	go t.RunLocalShell()
+	return t
+}
+
+// RemotePTY is the minimal contract a remote shell must satisfy to back a terminal:
+// reads yield remote output, writes carry keystrokes, Resize follows the widget size.
+type RemotePTY interface {
+	io.ReadWriteCloser
+	Resize(cols, rows int) error
+}
+
+// NewRemoteTerminal binds a remote PTY to a terminal widget. Size changes of the
+// widget are forwarded to the PTY; onExit (optional) runs on the UI thread once
+// the remote side has gone away.
+func NewRemoteTerminal(pty RemotePTY, onExit func()) *terminal.Terminal {
+	t := terminal.New()
+	cfg := make(chan terminal.Config)
+	t.AddListener(cfg)
+	go func() {
+		for c := range cfg {
+			if c.Columns > 0 && c.Rows > 0 {
+				_ = pty.Resize(int(c.Columns), int(c.Rows))
+			}
+		}
+	}()
+	go func() {
+		_ = t.RunWithConnection(pty, pty)
+		t.RemoveListener(cfg)
+		if onExit != nil {
+			fyne.Do(onExit)
+		}
+	}()
+	return t
 }
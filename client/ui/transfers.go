@@ -0,0 +1,131 @@
+package ui
+
+// TransfersPanel shows the backend transfer queue as a list of progress bars.
+// It sits below the terminal tabs and is toggled from the TabBar header;
+// cancellation is delegated to callbacks so the panel stays presentation-only.
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Transfer is the display model of one queued upload or download.
+type Transfer struct {
+	ID       string
+	Title    string  // e.g. "↑ big.iso → /srv/images"
+	Status   string  // e.g. "12.0 MB / 40.0 MB · 3.1 MB/s"
+	Progress float64 // 0..1
+	Active   bool    // queued or running, i.e. still cancellable
+}
+
+// TransfersPanelProps defines the callbacks for panel interactions.
+type TransfersPanelProps struct {
+	OnCancel  func(id string)
+	OnRefresh func()
+}
+
+// TransfersPanel is a fixed-height bottom panel listing transfers.
+type TransfersPanel struct {
+	items []Transfer
+	list  *widget.List
+	empty *widget.Label
+	view  *fyne.Container
+}
+
+// NewTransfersPanel creates an empty panel.
+func NewTransfersPanel(props TransfersPanelProps) *TransfersPanel {
+	p := &TransfersPanel{}
+	p.list = widget.NewList(
+		func() int { return len(p.items) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("")
+			title.Truncation = fyne.TextTruncateEllipsis
+			status := widget.NewLabel("")
+			status.Truncation = fyne.TextTruncateEllipsis
+			cancel := widget.NewButton("取消", nil)
+			return container.NewBorder(nil, nil, nil, cancel,
+				container.NewVBox(title, widget.NewProgressBar(), status))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			t := p.items[i]
+			row := o.(*fyne.Container)
+			rows := row.Objects[0].(*fyne.Container).Objects
+			rows[0].(*widget.Label).SetText(t.Title)
+			rows[1].(*widget.ProgressBar).SetValue(t.Progress)
+			rows[2].(*widget.Label).SetText(t.Status)
+			cancel := row.Objects[1].(*widget.Button)
+			cancel.OnTapped = func() {
+				if props.OnCancel != nil {
+					props.OnCancel(t.ID)
+				}
+			}
+			if t.Active {
+				cancel.Show()
+			} else {
+				cancel.Hide()
+			}
+		},
+	)
+
+	title := widget.NewLabel("文件传输")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	clearBtn := widget.NewButton("清除已结束", func() {
+		kept := p.items[:0]
+		for _, t := range p.items {
+			if t.Active {
+				kept = append(kept, t)
+			}
+		}
+		p.items = kept
+		p.refresh()
+	})
+	refreshBtn := widget.NewButton("刷新", func() {
+		if props.OnRefresh != nil {
+			props.OnRefresh()
+		}
+	})
+	p.empty = widget.NewLabel("暂无传输")
+	top := container.NewBorder(nil, nil, title, container.NewHBox(clearBtn, refreshBtn), nil)
+	body := container.NewStack(p.list, container.NewCenter(p.empty))
+
+	// Border only honours MinSize for top/bottom panels; a transparent strut fixes the height.
+	strut := canvas.NewRectangle(color.Transparent)
+	strut.SetMinSize(fyne.NewSize(0, 220))
+	p.view = container.NewStack(strut, container.NewBorder(top, nil, nil, nil, body))
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *TransfersPanel) Object() fyne.CanvasObject { return p.view }
+
+// Set replaces the whole list; must be called on the UI thread (use fyne.Do).
+func (p *TransfersPanel) Set(items []Transfer) {
+	p.items = items
+	p.refresh()
+}
+
+// Update inserts or refreshes one transfer; must be called on the UI thread (use fyne.Do).
+func (p *TransfersPanel) Update(t Transfer) {
+	for i := range p.items {
+		if p.items[i].ID == t.ID {
+			p.items[i] = t
+			p.list.RefreshItem(i)
+			return
+		}
+	}
+	p.items = append(p.items, t)
+	p.refresh()
+}
+
+func (p *TransfersPanel) refresh() {
+	if len(p.items) == 0 {
+		p.empty.Show()
+	} else {
+		p.empty.Hide()
+	}
+	p.list.Refresh()
+}
@@ -0,0 +1,170 @@
+package ui
+
+// TunnelsPanel lists the port forwards (-L / -R / -D) owned by the backend,
+// with live connection counts and byte counters. It sits next to the TabBar
+// and is toggled from its header bar; creation and teardown are delegated to
+// callbacks so the panel stays presentation-only.
+
+import (
+	"fmt"
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Tunnel is the display model of one port forward.
+type Tunnel struct {
+	ID       string
+	ConnID   string
+	Kind     string // "L", "R" or "D"
+	Spec     string // e.g. "127.0.0.1:8080 → db:5432"
+	Active   int
+	Total    int64
+	Failed   int64
+	BytesOut int64
+	BytesIn  int64
+}
+
+// TunnelsPanelProps defines the callbacks for panel interactions.
+type TunnelsPanelProps struct {
+	OnCreate  func()
+	OnClose   func(id string)
+	OnRefresh func()
+}
+
+// TunnelsPanel is a fixed-width side panel with a tunnel list and controls.
+type TunnelsPanel struct {
+	items    []Tunnel
+	selected int
+	list     *widget.List
+	closeBtn *widget.Button
+	empty    *widget.Label
+	view     *fyne.Container
+}
+
+// NewTunnelsPanel creates an empty panel.
+func NewTunnelsPanel(props TunnelsPanelProps) *TunnelsPanel {
+	p := &TunnelsPanel{selected: -1}
+	p.list = widget.NewList(
+		func() int { return len(p.items) },
+		func() fyne.CanvasObject {
+			title := widget.NewLabel("")
+			title.TextStyle = fyne.TextStyle{Bold: true}
+			title.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(title, widget.NewLabel(""))
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			t := p.items[i]
+			rows := o.(*fyne.Container).Objects
+			rows[0].(*widget.Label).SetText(fmt.Sprintf("[%s] %s", t.Kind, t.Spec))
+			stats := fmt.Sprintf("%s · 活动 %d · 累计 %d · ↑%s ↓%s", t.ConnID, t.Active, t.Total, FormatBytes(t.BytesOut), FormatBytes(t.BytesIn))
+			if t.Failed > 0 {
+				stats += fmt.Sprintf(" · 失败 %d", t.Failed)
+			}
+			rows[1].(*widget.Label).SetText(stats)
+		},
+	)
+	p.list.OnSelected = func(id widget.ListItemID) {
+		p.selected = id
+		p.closeBtn.Enable()
+	}
+	p.list.OnUnselected = func(widget.ListItemID) {
+		p.selected = -1
+		p.closeBtn.Disable()
+	}
+
+	createBtn := widget.NewButton("+ 新建转发", func() {
+		fmt.Println("[USER] 点击了新建转发按钮")
+		if props.OnCreate != nil {
+			props.OnCreate()
+		}
+	})
+	p.closeBtn = widget.NewButton("关闭", func() {
+		if p.selected < 0 || p.selected >= len(p.items) {
+			return
+		}
+		id := p.items[p.selected].ID
+		fmt.Println("[USER] 关闭转发:", id)
+		if props.OnClose != nil {
+			props.OnClose(id)
+		}
+	})
+	p.closeBtn.Disable()
+	refreshBtn := widget.NewButton("刷新", func() {
+		if props.OnRefresh != nil {
+			props.OnRefresh()
+		}
+	})
+
+	title := widget.NewLabel("端口转发")
+	title.TextStyle = fyne.TextStyle{Bold: true}
+	p.empty = widget.NewLabel("暂无转发")
+	top := container.NewBorder(nil, nil, title, refreshBtn, nil)
+	bottom := container.NewHBox(createBtn, p.closeBtn)
+	body := container.NewStack(p.list, container.NewCenter(p.empty))
+
+	// Border only honours MinSize for side panels; a transparent strut fixes the width.
+	strut := canvas.NewRectangle(color.Transparent)
+	strut.SetMinSize(fyne.NewSize(320, 0))
+	p.view = container.NewStack(strut, container.NewBorder(top, bottom, nil, nil, body))
+	return p
+}
+
+// Object returns the canvas object to place in a layout.
+func (p *TunnelsPanel) Object() fyne.CanvasObject { return p.view }
+
+// Set replaces the whole list; must be called on the UI thread (use fyne.Do).
+func (p *TunnelsPanel) Set(items []Tunnel) {
+	p.items = items
+	p.refresh()
+}
+
+// Update inserts or refreshes one tunnel, or removes it when closed is true;
+// must be called on the UI thread (use fyne.Do).
+func (p *TunnelsPanel) Update(t Tunnel, closed bool) {
+	for i := range p.items {
+		if p.items[i].ID != t.ID {
+			continue
+		}
+		if closed {
+			p.items = append(p.items[:i], p.items[i+1:]...)
+		} else {
+			p.items[i] = t
+		}
+		p.refresh()
+		return
+	}
+	if !closed {
+		p.items = append(p.items, t)
+		p.refresh()
+	}
+}
+
+func (p *TunnelsPanel) refresh() {
+	if p.selected >= len(p.items) {
+		p.list.UnselectAll()
+	}
+	if len(p.items) == 0 {
+		p.empty.Show()
+	} else {
+		p.empty.Hide()
+	}
+	p.list.Refresh()
+}
+
+// FormatBytes renders a byte count with a binary unit, e.g. "1.5 MB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
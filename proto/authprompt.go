@@ -0,0 +1,43 @@
+package proto
+
+// SSH 认证方式，ConnectRequest.AuthMethods 的取值
+const (
+	AuthAgent               = "agent"                // SSH_AUTH_SOCK 指向的 ssh-agent
+	AuthPublicKey           = "publickey"            // KeyPath 指定的私钥
+	AuthKeyboardInteractive = "keyboard-interactive" // 服务器逐轮下发问题（OTP/2FA 等）
+	AuthPassword            = "password"
+)
+
+// 认证提示的种类，AuthPromptEvent.Kind 的取值
+const (
+	PromptPassphrase          = "passphrase"           // 解密私钥的口令
+	PromptKeyboardInteractive = "keyboard-interactive" // 服务器下发的一轮问题
+	PromptPassword            = "password"             // 未提供密码时询问
+)
+
+// AuthPromptEvent 认证过程中需要用户输入时推送，前端以 auth_answer 应答；
+// 超时未应答或前端取消时该认证方式失败，继续尝试下一种。
+type AuthPromptEvent struct {
+	PromptID    string       `json:"promptId"`
+	ConnID      string       `json:"connId"`
+	Kind        string       `json:"kind"`
+	Host        string       `json:"host"`
+	User        string       `json:"user"`
+	Name        string       `json:"name,omitempty"`        // keyboard-interactive 的标题
+	Instruction string       `json:"instruction,omitempty"` // keyboard-interactive 的说明，或口令提示对应的私钥路径
+	Prompts     []AuthPrompt `json:"prompts"`
+	Retry       bool         `json:"retry,omitempty"` // 上一次输入错误，重新询问
+}
+
+// AuthPrompt 一个待回答的问题
+type AuthPrompt struct {
+	Text string `json:"text"`
+	Echo bool   `json:"echo"` // false 时输入应隐藏
+}
+
+// AuthAnswerRequest 应答 auth_prompt：Answers 与 Prompts 一一对应
+type AuthAnswerRequest struct {
+	PromptID string   `json:"promptId"`
+	Answers  []string `json:"answers,omitempty"`
+	Cancel   bool     `json:"cancel,omitempty"`
+}
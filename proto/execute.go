@@ -0,0 +1,124 @@
+package proto
+
+import "time"
+
+// execute 的超时与输出上限
+const (
+	DefaultExecuteTimeout = time.Minute
+	MaxExecuteTimeout     = time.Hour
+	DefaultExecuteOutput  = 1 << 20 // stdout、stderr 各自保留的字节数
+	MaxExecuteOutput      = 16 << 20
+)
+
+// ExecuteRequest 在已建立的连接上执行一条命令并等待结束。
+// Command 由远端登录 shell 解释；Env 以 export 前置到命令之前（不依赖 sshd 的 AcceptEnv），
+// Dir 为执行目录（支持 ~/ 前缀）。TimeoutMs 为 0 时使用 DefaultExecuteTimeout，
+// MaxOutput 为 0 时使用 DefaultExecuteOutput，二者超出上限时被截断到上限
+type ExecuteRequest struct {
+	ConnID    string            `json:"connId"`
+	Command   string            `json:"command"`
+	Stdin     string            `json:"stdin,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	Dir       string            `json:"dir,omitempty"`
+	TimeoutMs int               `json:"timeoutMs,omitempty"`
+	// PTY 为命令分配伪终端（关闭回显）：stderr 合并到 stdout，输出含终端控制字符
+	PTY       bool `json:"pty,omitempty"`
+	MaxOutput int  `json:"maxOutput,omitempty"`
+}
+
+// ExecuteResponse 命令的执行结果。命令以非零状态退出不视为请求失败。
+// 输出超过 MaxOutput 时保留开头与结尾各一半，中间替换为截断标记，
+// 对应的 Truncated 为 true，Bytes 为命令实际产生的字节数。截断点总在 UTF-8 字符边界上；
+// 输出不是合法 UTF-8（二进制数据）时整段以 base64 返回，对应的 Encoding 为 EncodingBase64
+type ExecuteResponse struct {
+	ExitCode        int    `json:"exitCode"`         // 没有退出状态（超时、被信号终止、连接断开）时为 -1
+	Signal          string `json:"signal,omitempty"` // 终止命令的信号，如 KILL
+	Error           string `json:"error,omitempty"`  // 未正常结束的原因
+	TimedOut        bool   `json:"timedOut,omitempty"`
+	Stdout          string `json:"stdout"`
+	Stderr          string `json:"stderr"`
+	StdoutBytes     int64  `json:"stdoutBytes"`
+	StderrBytes     int64  `json:"stderrBytes"`
+	StdoutTruncated bool   `json:"stdoutTruncated,omitempty"`
+	StderrTruncated bool   `json:"stderrTruncated,omitempty"`
+	StdoutEncoding  string `json:"stdoutEncoding,omitempty"`
+	StderrEncoding  string `json:"stderrEncoding,omitempty"`
+	DurationMs      int64  `json:"durationMs"`
+}
+
+// EncodingBase64 ExecuteResponse 的输出以 base64 编码
+const EncodingBase64 = "base64"
+
+// 批量执行中单台主机的状态，ExecuteResult.State 的取值
+const (
+	ExecuteRunning   = "running"   // 正在连接或执行
+	ExecuteCompleted = "completed" // 命令已结束，退出码见 Result（可能非零）
+	ExecuteFailed    = "failed"    // 未能连接或未能启动命令，原因见 Error
+)
+
+// 批量执行的并发数
+const (
+	DefaultMultiExecuteConcurrency = 8
+	MaxMultiExecuteConcurrency     = 64
+)
+
+// MultiExecuteRequest 在多台主机上执行同一命令：目标为 ProfileIDs 与 Folder 选中的连接配置的并集。
+// 配置 ID 对应的连接已建立时直接复用，否则为任务新建私有连接 batch:<任务 ID>:<配置 ID>，
+// 执行结束后断开，不影响同时打开的终端或其他任务；认证不弹窗询问。
+// Command 至 MaxOutput 的含义同 ExecuteRequest，TimeoutMs 为每台主机的命令超时
+type MultiExecuteRequest struct {
+	ID         string            `json:"id,omitempty"` // 任务 ID，为空时由后端生成；由前端指定可在响应到达前关联事件
+	ProfileIDs []string          `json:"profileIds,omitempty"`
+	Folder     string            `json:"folder,omitempty"` // 该分组及其子分组（以 / 分隔）下的全部连接配置
+	Command    string            `json:"command"`
+	Stdin      string            `json:"stdin,omitempty"`
+	Env        map[string]string `json:"env,omitempty"`
+	Dir        string            `json:"dir,omitempty"`
+	TimeoutMs  int               `json:"timeoutMs,omitempty"`
+	PTY        bool              `json:"pty,omitempty"`
+	MaxOutput  int               `json:"maxOutput,omitempty"`
+	// Concurrency 同时执行的主机数，为 0 时使用 DefaultMultiExecuteConcurrency，至多 MaxMultiExecuteConcurrency
+	Concurrency int `json:"concurrency,omitempty"`
+	// KeepConnected 以配置 ID 为连接 ID 新建连接并在执行后保留，供终端等复用；
+	// 此时任务取消不会中止正在进行的拨号
+	KeepConnected bool `json:"keepConnected,omitempty"`
+}
+
+// MultiExecuteResponse multi_execute 立即返回任务 ID 与目标主机，结果经 execute_result 事件推送
+type MultiExecuteResponse struct {
+	ID    string        `json:"id"`
+	Hosts []ExecuteHost `json:"hosts"`
+}
+
+// CancelMultiExecuteRequest 取消进行中的批量执行任务：未开始的主机报告 failed，
+// 正在执行的命令被终止（结果 completed，退出码 -1）
+type CancelMultiExecuteRequest struct {
+	ID string `json:"id"`
+}
+
+// ExecuteHost 批量执行的一台目标主机
+type ExecuteHost struct {
+	ProfileID string `json:"profileId"`
+	Name      string `json:"name"`
+	Host      string `json:"host"` // user@host:port
+}
+
+// ExecuteResult 批量执行中一台主机的状态，作为 execute_result 事件负载：
+// 每台主机开始时推送 running，结束时推送 completed 或 failed
+type ExecuteResult struct {
+	JobID string `json:"jobId"`
+	ExecuteHost
+	State  string           `json:"state"`
+	Error  string           `json:"error,omitempty"`
+	Result *ExecuteResponse `json:"result,omitempty"` // 仅 completed 时有值
+	Done   int              `json:"done"`             // 任务中已结束的主机数（含本条）
+	Total  int              `json:"total"`
+}
+
+// Exec 返回在 connID 上执行的单机请求
+func (r MultiExecuteRequest) Exec(connID string) ExecuteRequest {
+	return ExecuteRequest{
+		ConnID: connID, Command: r.Command, Stdin: r.Stdin, Env: r.Env, Dir: r.Dir,
+		TimeoutMs: r.TimeoutMs, PTY: r.PTY, MaxOutput: r.MaxOutput,
+	}
+}
@@ -0,0 +1,52 @@
+package proto
+
+// HostKeyEvent 主机密钥未受信任时推送（host_key_unknown / host_key_changed），
+// 前端确认指纹后以 trust_host_key 写入 known_hosts 并重新 connect。
+type HostKeyEvent struct {
+	ConnID      string         `json:"connId"`
+	Host        string         `json:"host"`
+	Port        int            `json:"port"`
+	KeyType     string         `json:"keyType"`
+	Fingerprint string         `json:"fingerprint"`     // SHA256:...
+	Key         string         `json:"key"`             // authorized_keys 格式："类型 base64"
+	Known       []HostKeyEntry `json:"known,omitempty"` // 已变更时为已记录的密钥
+}
+
+// HostKeyEntry known_hosts 中的一行
+type HostKeyEntry struct {
+	File        string   `json:"file"`
+	Line        int      `json:"line"`
+	Marker      string   `json:"marker,omitempty"` // "@cert-authority" / "@revoked"
+	Hosts       []string `json:"hosts"`            // 哈希条目原样返回（|1|...）
+	Hashed      bool     `json:"hashed"`
+	KeyType     string   `json:"keyType"`
+	Fingerprint string   `json:"fingerprint"`
+	Comment     string   `json:"comment,omitempty"`
+	ReadOnly    bool     `json:"readOnly"` // 非后端管理的文件（如 ~/.ssh/known_hosts），不可修改
+}
+
+// ListHostKeysResponse 全部 known_hosts 条目
+type ListHostKeysResponse struct {
+	Keys []HostKeyEntry `json:"keys"`
+}
+
+// TrustHostKeyRequest 信任主机密钥
+type TrustHostKeyRequest struct {
+	Host    string `json:"host"`
+	Port    int    `json:"port"`
+	Key     string `json:"key"`               // authorized_keys 格式，通常取自 HostKeyEvent.Key
+	Hash    bool   `json:"hash,omitempty"`    // 以哈希形式记录主机名（同 OpenSSH HashKnownHosts）
+	Replace bool   `json:"replace,omitempty"` // 先移除该主机已记录的密钥（密钥变更时使用）
+}
+
+// RemoveHostKeyRequest 删除后端管理的 known_hosts 条目：Line>0 时删除该行，否则删除 Host/Port 的全部条目
+type RemoveHostKeyRequest struct {
+	Host string `json:"host,omitempty"`
+	Port int    `json:"port,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// RemoveHostKeyResponse 删除的条目数
+type RemoveHostKeyResponse struct {
+	Removed int `json:"removed"`
+}
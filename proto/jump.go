@@ -0,0 +1,27 @@
+package proto
+
+// MaxJumps 单个连接允许的跳板机数量上限
+const MaxJumps = 8
+
+// JumpHost 跳板机（ProxyJump），按顺序逐跳拨号，每一跳独立认证。
+// 认证字段含义同 ConnectRequest；User 为空时沿用目标主机的用户名，Port 默认 22。
+type JumpHost struct {
+	Host          string   `json:"host"`
+	Port          int      `json:"port,omitempty"`
+	User          string   `json:"user,omitempty"`
+	Password      string   `json:"password,omitempty"`
+	KeyPath       string   `json:"keyPath,omitempty"`
+	Passphrase    string   `json:"passphrase,omitempty"`
+	PasswordRef   string   `json:"passwordRef,omitempty"`
+	PassphraseRef string   `json:"passphraseRef,omitempty"`
+	AuthMethods   []string `json:"authMethods,omitempty"`
+}
+
+// HopStatus 跳板链中一跳的状态；ConnectResponse.Chain 依次为各跳板机与目标主机
+type HopStatus struct {
+	Host  string `json:"host"`
+	Port  int    `json:"port"`
+	User  string `json:"user"`
+	State string `json:"state"`           // 尚未拨号时为空，其余取值同 ConnectResponse.State
+	Error string `json:"error,omitempty"` // 该跳失败的原因
+}
@@ -0,0 +1,67 @@
+package proto
+
+import "time"
+
+// 可生成的密钥类型，GenerateKeyRequest.Type 的取值
+const (
+	KeyTypeEd25519 = "ed25519"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeRSA     = "rsa"
+)
+
+// KeyInfo 受管密钥目录中的一把密钥
+type KeyInfo struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"` // ssh-ed25519 / ecdsa-sha2-nistp256 / ssh-rsa ...
+	Bits        int       `json:"bits"`
+	Fingerprint string    `json:"fingerprint"` // SHA256:...
+	Comment     string    `json:"comment,omitempty"`
+	Encrypted   bool      `json:"encrypted"` // 私钥受口令保护
+	Path        string    `json:"path"`      // 私钥文件，可直接用作 ConnectRequest.KeyPath
+	PublicKey   string    `json:"publicKey"` // authorized_keys 格式
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// GenerateKeyRequest 生成密钥对；Bits 仅对 ecdsa（256/384/521，默认 256）与 rsa（2048-8192，默认 3072）有效
+type GenerateKeyRequest struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Bits       int    `json:"bits,omitempty"`
+	Comment    string `json:"comment,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"` // 非空时以 OpenSSH 格式加密私钥
+}
+
+// ImportKeyRequest 导入已有私钥：PrivateKey 为 PEM 内容，或 Path 为本机私钥文件（二选一）。
+// 旧式 PEM 加密私钥需提供 Passphrase 以读取公钥；私钥按原样保存，不会被解密。
+type ImportKeyRequest struct {
+	Name       string `json:"name"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+}
+
+// KeyRequest 按名称引用受管密钥（export_public_key / delete_key）
+type KeyRequest struct {
+	Name string `json:"name"`
+}
+
+// ListKeysResponse 受管密钥列表，按名称排序
+type ListKeysResponse struct {
+	Keys []KeyInfo `json:"keys"`
+}
+
+// PublicKeyResponse 导出的公钥（authorized_keys 格式，含注释）
+type PublicKeyResponse struct {
+	PublicKey string `json:"publicKey"`
+}
+
+// InstallKeyRequest 经已建立的连接将受管密钥的公钥追加到远端 ~/.ssh/authorized_keys
+type InstallKeyRequest struct {
+	ConnID string `json:"connId"`
+	Name   string `json:"name"`
+}
+
+// InstallKeyResponse 公钥已存在时 Installed 为 false
+type InstallKeyResponse struct {
+	Installed bool `json:"installed"`
+}
@@ -1,49 +1,285 @@
 package proto
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
 
 // Message 统一的协议包（前后端共享）：一行一个 JSON
 type Message struct {
-    Type string          `json:"type"`
-    Data json.RawMessage `json:"data,omitempty"`
+	ID   string          `json:"id,omitempty"` // 请求关联 ID，响应原样带回；为空时按顺序应答
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
+// 单条消息（一行 JSON）的长度上限；超过时后端断开连接。
+// 认证前只允许较小的消息，避免未认证的对端占用内存
+const (
+	MaxFrameSize       = 32 << 20
+	MaxUnauthFrameSize = 64 << 10
+)
+
 // PingResponse 用于连接性验证
 type PingResponse struct {
-    Message string `json:"message"`
+	Message string `json:"message"`
 }
 
 // ConnectRequest/Response 用于建立与描述连接
 type ConnectRequest struct {
-    ID       string `json:"id"`
-    Host     string `json:"host"`
-    Port     int    `json:"port"`
-    User     string `json:"user"`
-    Password string `json:"password,omitempty"`
-    KeyPath  string `json:"keyPath,omitempty"`
+	ID         string `json:"id"`
+	Host       string `json:"host"`
+	Port       int    `json:"port"`
+	User       string `json:"user"`
+	Password   string `json:"password,omitempty"`
+	KeyPath    string `json:"keyPath,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"` // 加密私钥的口令；为空且私钥已加密时经 auth_prompt 询问
+	// PasswordRef/PassphraseRef 引用凭据库中的密码与口令（取自 Profile），后端解密后使用；
+	// 凭据库锁定时 connect 返回 CodeLocked
+	PasswordRef   string `json:"passwordRef,omitempty"`
+	PassphraseRef string `json:"passphraseRef,omitempty"`
+	// AuthMethods 认证方式及尝试顺序（AuthAgent/AuthPublicKey/AuthKeyboardInteractive/AuthPassword），
+	// 为空时按 agent、publickey、keyboard-interactive、password 中可用的方式依次尝试
+	AuthMethods []string `json:"authMethods,omitempty"`
+	// Interactive 前端会响应 auth_prompt 事件；为 false 时需要交互输入的认证方式直接失败
+	Interactive bool `json:"interactive,omitempty"`
+	// Jumps 跳板机，按顺序经上一跳的连接拨号下一跳，最后到达目标主机（至多 MaxJumps 个）
+	Jumps []JumpHost `json:"jumps,omitempty"`
 }
 
 type ConnectResponse struct {
-    ID    string `json:"id"`
-    State string `json:"state"` // connected/disconnected
+	ID    string `json:"id"`
+	Host  string `json:"host,omitempty"`
+	Port  int    `json:"port,omitempty"`
+	User  string `json:"user,omitempty"`
+	State string `json:"state"`           // connected/connecting/failed/disconnected
+	Error string `json:"error,omitempty"` // 最近一次失败原因（仅 failed 时有值）
+	// Chain 经跳板机连接时依次为各跳板机与目标主机的状态，失败时可据此定位出错的一跳
+	Chain []HopStatus `json:"chain,omitempty"`
+}
+
+// DisconnectRequest 按 ID 断开连接
+type DisconnectRequest struct {
+	ID string `json:"id"`
 }
 
+// 连接状态常量，ConnectResponse.State 的取值
+const (
+	StateConnecting   = "connecting"
+	StateConnected    = "connected"
+	StateFailed       = "failed"
+	StateDisconnected = "disconnected"
+)
+
 type ListConnectionsResponse struct {
-    Connections []ConnectResponse `json:"connections"`
+	Connections []ConnectResponse `json:"connections"`
 }
 
 // 通用响应封装，确保前后端错误处理一致
 type Response struct {
-    Code    int    `json:"code"`              // 0 表示成功，其它为错误码
-    Ok      bool   `json:"ok"`                // 成功/失败标识
-    Message string `json:"message,omitempty"` // 错误或提示信息
-    Data    any    `json:"data,omitempty"`    // 负载数据
+	ID      string `json:"id,omitempty"`      // 对应请求的 Message.ID
+	Code    int    `json:"code"`              // 0 表示成功，其它为错误码
+	Ok      bool   `json:"ok"`                // 成功/失败标识
+	Message string `json:"message,omitempty"` // 错误或提示信息
+	Data    any    `json:"data,omitempty"`    // 负载数据
 }
 
 // 公共错误码常量
 const (
-    CodeOK          = 0
-    CodeBadRequest  = 400
-    CodeUnknownType = 404
-    CodeServerError = 500
-)
\ No newline at end of file
+	CodeOK            = 0
+	CodeBadRequest    = 400
+	CodeUnauthorized  = 401 // 未通过 auth 握手
+	CodeForbidden     = 403 // 主密码错误
+	CodeUnknownType   = 404
+	CodeHostKey       = 412 // 主机密钥未受信任（未知或已变更），需先 trust_host_key
+	CodeLocked        = 423 // 凭据库未初始化或已锁定，需先 vault_init / vault_unlock
+	CodeVersion       = 426 // 协议版本不兼容
+	CodeServerError   = 500
+	CodeConnectFailed = 502 // SSH 拨号或认证失败
+	CodeTimeout       = 504 // 处理超时
+)
+
+// Profile 持久化的连接配置（连接管理器中的一台主机）
+type Profile struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Folder string `json:"folder,omitempty"` // 所属分组，空表示根目录
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	User   string `json:"user"`
+	// Password/Passphrase 仅用于保存时提交，后端将其存入凭据库并改为 PasswordRef/PassphraseRef；
+	// 凭据库启用前保存的旧配置可能仍带明文 Password，解锁后自动迁移
+	Password      string `json:"password,omitempty"`
+	Passphrase    string `json:"passphrase,omitempty"`
+	PasswordRef   string `json:"passwordRef,omitempty"`
+	PassphraseRef string `json:"passphraseRef,omitempty"`
+	KeyPath       string `json:"keyPath,omitempty"`
+	// AuthMethods 认证方式及顺序，含义同 ConnectRequest.AuthMethods
+	AuthMethods []string `json:"authMethods,omitempty"`
+	// Jumps 跳板机，其密码与口令同样存入凭据库
+	Jumps []JumpHost `json:"jumps,omitempty"`
+	// SessionLog 将该配置的 shell 会话输出去除控制序列后写入纯文本日志（后端数据目录 logs/<ID>）
+	SessionLog bool      `json:"sessionLog,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ProfileRequest 按 ID 获取/删除连接配置
+type ProfileRequest struct {
+	ID string `json:"id"`
+}
+
+// ListProfilesResponse 连接配置列表（不含密码）
+type ListProfilesResponse struct {
+	Profiles []Profile `json:"profiles"`
+}
+
+// OpenShellRequest 在已建立的连接上打开交互式 shell（会话流的首条消息）
+type OpenShellRequest struct {
+	ConnID string `json:"connId"`
+	Term   string `json:"term,omitempty"` // 默认 xterm-256color
+	Cols   int    `json:"cols,omitempty"`
+	Rows   int    `json:"rows,omitempty"`
+}
+
+// OpenShellResponse open_shell 成功后返回的会话标识
+type OpenShellResponse struct {
+	SessionID string `json:"sessionId"`
+}
+
+// StreamChunk 会话流中的数据块（stdin/stdout/stderr），JSON 中为 base64
+type StreamChunk struct {
+	Data []byte `json:"data"`
+}
+
+// ResizeRequest 调整远端 PTY 尺寸
+type ResizeRequest struct {
+	Cols int `json:"cols"`
+	Rows int `json:"rows"`
+}
+
+// ExitStatus 远端 shell 退出状态，会话流的最后一条消息
+type ExitStatus struct {
+	Code   int    `json:"code"`
+	Signal string `json:"signal,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// 会话流中的消息类型：open_shell 成功响应后，连接切换为双向流
+const (
+	StreamStdin  = "stdin"  // 前端 -> 后端，StreamChunk
+	StreamResize = "resize" // 前端 -> 后端，ResizeRequest
+	StreamClose  = "close"  // 前端 -> 后端，主动关闭会话
+	StreamStdout = "stdout" // 后端 -> 前端，StreamChunk
+	StreamStderr = "stderr" // 后端 -> 前端，StreamChunk
+	StreamExit   = "exit"   // 后端 -> 前端，ExitStatus
+)
+
+// Event 服务端主动推送的异步事件。与 Response 共用同一连接，
+// 以 event 字段区分：有 event 的行是事件，否则是带 id 的响应。
+type Event struct {
+	Event string    `json:"event"`
+	Seq   uint64    `json:"seq"` // 全局递增序号，便于发现丢失
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data,omitempty"`
+}
+
+// 事件名常量
+const (
+	EventConnectionState  = "connection_state"  // ConnectResponse
+	EventSessionExit      = "session_exit"      // SessionExitEvent
+	EventTransferProgress = "transfer_progress" // TransferInfo：传输状态变化及进度（每个传输每 500ms 至多一次）
+	EventMonitorSample    = "monitor_sample"    // MonitorSample：monitor_subscribe 会话流的每次采样
+	EventExecuteResult    = "execute_result"    // ExecuteResult：multi_execute 中每台主机开始与结束
+	EventHostKeyUnknown   = "host_key_unknown"  // HostKeyEvent：known_hosts 中没有该主机
+	EventHostKeyChanged   = "host_key_changed"  // HostKeyEvent：主机密钥与已记录的不一致
+	EventAuthPrompt       = "auth_prompt"       // AuthPromptEvent：认证需要用户输入，以 auth_answer 应答
+	EventVaultState       = "vault_state"       // VaultStatus：凭据库初始化、解锁或锁定（含空闲自动锁定）
+	EventTunnelStats      = "tunnel_stats"      // TunnelInfo：转发创建、关闭、连接数变化及流量统计（每秒至多一次）
+)
+
+// SubscribeRequest 订阅事件，Events 为空表示订阅全部
+type SubscribeRequest struct {
+	Events []string `json:"events,omitempty"`
+}
+
+// SessionExitEvent 终端会话结束通知
+type SessionExitEvent struct {
+	SessionID string     `json:"sessionId"`
+	ConnID    string     `json:"connId"`
+	Status    ExitStatus `json:"status"`
+}
+
+// Frame 前端解码用：同一连接上交错到达的响应与事件统一按此结构解析
+type Frame struct {
+	ID      string          `json:"id,omitempty"`
+	Event   string          `json:"event,omitempty"`
+	Seq     uint64          `json:"seq,omitempty"`
+	Code    int             `json:"code"`
+	Ok      bool            `json:"ok"`
+	Message string          `json:"message,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// IsEvent 判断该帧是否为服务端推送事件
+func (f Frame) IsEvent() bool { return f.Event != "" }
+
+// AuthRequest 每条 IPC 连接的第一条消息（type=auth），携带本机共享令牌
+type AuthRequest struct {
+	Token string `json:"token"`
+}
+
+// TokenFileName 令牌文件名，位于数据目录下，由后端首次启动时生成（权限 0600）
+const TokenFileName = "ipc.token"
+
+// PIDFileName 记录运行中后端进程信息（ServiceInfo）的文件，位于数据目录下；
+// LockFileName 为后端运行期间持有的排他锁文件，同一数据目录只允许一个后端实例。
+const (
+	PIDFileName  = "service.pid"
+	LockFileName = "service.lock"
+)
+
+// ExitAlreadyRunning 后端因同一数据目录已有实例而退出时的退出码
+const ExitAlreadyRunning = 3
+
+// ServiceInfo 为 PIDFileName 的内容，前端据此判断能否附着到已运行的后端
+type ServiceInfo struct {
+	PID       int       `json:"pid"`
+	Addr      string    `json:"addr,omitempty"`
+	Socket    string    `json:"socket,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// RequestTimeout 后端处理单个请求的默认超时（个别消息类型另有放宽），
+// 前端等待响应的默认超时以此为准
+const RequestTimeout = 10 * time.Second
+
+// DefaultAddr 后端默认监听地址（仅回环）
+const DefaultAddr = "127.0.0.1:8089"
+
+// DefaultDataDir 返回前后端共用的数据目录：优先环境变量 GO_SSH_DATA_DIR，
+// 否则为用户配置目录下的 go-ssh。
+func DefaultDataDir() string {
+	if d := os.Getenv("GO_SSH_DATA_DIR"); d != "" {
+		return d
+	}
+	if d, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(d, "go-ssh")
+	}
+	return filepath.Join("service", "data")
+}
+
+// SplitAddr 解析 IPC 地址：以 "unix:" 开头为 Unix 域套接字路径，否则为 TCP 地址
+func SplitAddr(addr string) (network, address string) {
+	if p, ok := strings.CutPrefix(addr, "unix:"); ok {
+		return "unix", p
+	}
+	return "tcp", addr
+}
+
+// ShutdownRequest 请求后端优雅退出
+type ShutdownRequest struct {
+	Reason string `json:"reason,omitempty"` // 仅用于日志
+}
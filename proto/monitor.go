@@ -0,0 +1,78 @@
+package proto
+
+import "time"
+
+// 监控采样间隔的默认值与范围
+const (
+	DefaultMonitorInterval = 2 * time.Second
+	MinMonitorInterval     = 500 * time.Millisecond
+	MaxMonitorInterval     = time.Minute
+)
+
+// StreamSample monitor_subscribe 会话流中后端推送的采样，MonitorSample
+const StreamSample = "sample"
+
+// MonitorRequest 在已建立的连接上订阅系统监控（会话流的首条消息）。
+// IntervalMs 为 0 时使用默认间隔，超出范围时被截断到 [MinMonitorInterval, MaxMonitorInterval]
+type MonitorRequest struct {
+	ConnID     string `json:"connId"`
+	IntervalMs int    `json:"intervalMs,omitempty"`
+}
+
+// MonitorResponse monitor_subscribe 成功后返回实际采样间隔
+type MonitorResponse struct {
+	ConnID     string `json:"connId"`
+	IntervalMs int    `json:"intervalMs"`
+}
+
+// MonitorSample 远端主机的一次系统快照，经 /proc 等只读接口采集，不在远端安装任何程序。
+// 同时作为 monitor_sample 事件负载；速率与使用率基于与上一次采样的差值，首个采样为 0
+type MonitorSample struct {
+	ConnID    string     `json:"connId"`
+	Time      time.Time  `json:"time"`
+	Error     string     `json:"error,omitempty"` // 本次采样失败的原因，其余字段为空
+	Hostname  string     `json:"hostname,omitempty"`
+	OS        string     `json:"os,omitempty"`     // /etc/os-release 的 PRETTY_NAME
+	Kernel    string     `json:"kernel,omitempty"` // 内核版本
+	Uptime    int64      `json:"uptime"`           // 秒
+	CPU       CPUStat    `json:"cpu"`
+	Load      [3]float64 `json:"load"` // 1 / 5 / 15 分钟平均负载
+	Memory    MemoryStat `json:"memory"`
+	Swap      MemoryStat `json:"swap"`
+	Disk      DiskStat   `json:"disk"` // 根文件系统
+	Addresses []string   `json:"addresses,omitempty"`
+	Net       []NetStat  `json:"net,omitempty"` // 不含回环接口
+}
+
+// CPUStat CPU 型号与使用率
+type CPUStat struct {
+	Model string  `json:"model,omitempty"`
+	Cores int     `json:"cores"`         // 逻辑核数
+	MHz   float64 `json:"mhz,omitempty"` // 各核当前频率的平均值
+	Usage float64 `json:"usage"`         // 0-100，全部核的平均
+}
+
+// MemoryStat 内存或交换分区用量，单位字节；Used 不含缓存与缓冲
+type MemoryStat struct {
+	Total     uint64 `json:"total"`
+	Used      uint64 `json:"used"`
+	Available uint64 `json:"available,omitempty"`
+	Cached    uint64 `json:"cached,omitempty"` // 页缓存与缓冲区，交换分区为 0
+}
+
+// DiskStat 文件系统用量，单位字节
+type DiskStat struct {
+	Mount string `json:"mount,omitempty"`
+	Total uint64 `json:"total"`
+	Used  uint64 `json:"used"`
+}
+
+// NetStat 网络接口的累计流量与速率
+type NetStat struct {
+	Name    string `json:"name"`
+	MAC     string `json:"mac,omitempty"`
+	RxBytes uint64 `json:"rxBytes"`
+	TxBytes uint64 `json:"txBytes"`
+	RxRate  uint64 `json:"rxRate"` // 字节/秒
+	TxRate  uint64 `json:"txRate"`
+}
@@ -0,0 +1,72 @@
+package proto
+
+import "time"
+
+// ProcessInfo 远端进程，取自 ps 输出
+type ProcessInfo struct {
+	PID       int       `json:"pid"`
+	PPID      int       `json:"ppid"`
+	User      string    `json:"user"`
+	CPU       float64   `json:"cpu"`   // %CPU，进程启动以来的平均值（ps 语义），多核时可超过 100
+	Mem       float64   `json:"mem"`   // %MEM，常驻内存占物理内存的比例
+	RSS       uint64    `json:"rss"`   // 常驻内存，字节
+	State     string    `json:"state"` // ps STAT，如 S、R、Z、Ss、D<
+	Nice      int       `json:"nice"`
+	Name      string    `json:"name"`    // 可执行文件名，内核线程为 [名称]
+	Command   string    `json:"command"` // 完整命令行
+	StartTime time.Time `json:"startTime"`
+}
+
+// 进程排序字段，ListProcessesRequest.Sort 的取值；前缀 "-" 表示降序
+const (
+	ProcessSortPID     = "pid"
+	ProcessSortUser    = "user"
+	ProcessSortCPU     = "cpu"
+	ProcessSortMem     = "mem"
+	ProcessSortStart   = "start"
+	ProcessSortCommand = "command"
+)
+
+// ListProcessesRequest 列出远端进程，排序与过滤在后端完成。
+// Sort 默认 "-cpu"；Filter 不区分大小写匹配命令行与用户，或等于 PID；Limit 为 0 表示不限
+type ListProcessesRequest struct {
+	ConnID string `json:"connId"`
+	Sort   string `json:"sort,omitempty"`
+	Filter string `json:"filter,omitempty"`
+	User   string `json:"user,omitempty"` // 只列出该用户的进程
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// ListProcessesResponse 排序、过滤后的进程；Total 为过滤后、截断前的数量
+type ListProcessesResponse struct {
+	Processes []ProcessInfo `json:"processes"`
+	Total     int           `json:"total"`
+	Time      time.Time     `json:"time"` // 远端采样时间
+}
+
+// SignalProcessRequest 向远端进程发送信号，Signal 为不带 SIG 前缀的名称（默认 TERM），见 ProcessSignals
+type SignalProcessRequest struct {
+	ConnID string `json:"connId"`
+	PIDs   []int  `json:"pids"`
+	Signal string `json:"signal,omitempty"`
+}
+
+// ProcessSignals signal_process 允许的信号
+var ProcessSignals = []string{"TERM", "KILL", "HUP", "INT", "QUIT", "STOP", "CONT", "USR1", "USR2"}
+
+// ReniceRequest 调整远端进程的 nice 值（-20 至 19），降低 nice 通常需要 root
+type ReniceRequest struct {
+	ConnID string `json:"connId"`
+	PIDs   []int  `json:"pids"`
+	Nice   int    `json:"nice"`
+}
+
+// ProcessActionResponse signal_process / renice_process 的逐个结果，Error 为空表示成功
+type ProcessActionResponse struct {
+	Results []ProcessActionResult `json:"results"`
+}
+
+type ProcessActionResult struct {
+	PID   int    `json:"pid"`
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,58 @@
+package proto
+
+import "time"
+
+// 会话录像模式，service 的 -record 参数取值
+const (
+	RecordOff    = "off"    // 不录像
+	RecordOutput = "output" // 仅记录远端输出
+	RecordInput  = "input"  // 同时记录键盘输入（可能包含输入的密码）
+)
+
+// get_recording 每次读取的默认与最大字节数
+const (
+	DefaultRecordingChunk = 4 << 20
+	MaxRecordingChunk     = 16 << 20
+)
+
+// RecordingInfo 一段 shell 会话录像，文件为数据目录 recordings/<ID>.cast（asciicast v2）
+type RecordingInfo struct {
+	ID        string    `json:"id"`
+	SessionID string    `json:"sessionId"`
+	ConnID    string    `json:"connId"`
+	Host      string    `json:"host"` // user@host:port
+	Width     int       `json:"width"`
+	Height    int       `json:"height"`
+	StartedAt time.Time `json:"startedAt"`
+	Duration  float64   `json:"duration"` // 秒，即最后一个事件的时间
+	Size      int64     `json:"size"`     // 文件字节数
+	Input     bool      `json:"input"`    // 是否记录了键盘输入（"i" 事件）
+	Active    bool      `json:"active"`   // 会话仍在进行，文件仍在增长
+}
+
+// ListRecordingsRequest 列出录像，ConnID 非空时只返回该连接的录像
+type ListRecordingsRequest struct {
+	ConnID string `json:"connId,omitempty"`
+}
+
+// ListRecordingsResponse 录像列表，按开始时间从新到旧
+type ListRecordingsResponse struct {
+	Recordings []RecordingInfo `json:"recordings"`
+}
+
+// GetRecordingRequest 从 Offset 起读取录像文件的至多 Limit 字节；
+// Limit 为 0 时使用 DefaultRecordingChunk，至多 MaxRecordingChunk
+type GetRecordingRequest struct {
+	ID     string `json:"id"`
+	Offset int64  `json:"offset,omitempty"`
+	Limit  int    `json:"limit,omitempty"`
+}
+
+// GetRecordingResponse 录像文件的一段原始内容（.cast 文本，JSON 中为 base64）。
+// EOF 为 true 表示已读到当前文件末尾；进行中的录像之后仍可能增长
+type GetRecordingResponse struct {
+	Recording RecordingInfo `json:"recording"`
+	Offset    int64         `json:"offset"`
+	Data      []byte        `json:"data"`
+	EOF       bool          `json:"eof"`
+}
@@ -0,0 +1,221 @@
+package proto
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ProtocolVersion 当前协议版本；MinProtocolVersion 为仍兼容的最低版本。
+// 不兼容的字段或语义变更时递增 ProtocolVersion，并视情况上调 MinProtocolVersion。
+const (
+	ProtocolVersion    = 1
+	MinProtocolVersion = 1
+)
+
+// HelloRequest 认证后的首个请求，交换协议版本与能力
+type HelloRequest struct {
+	ProtocolVersion int    `json:"protocolVersion"`
+	Client          string `json:"client,omitempty"` // 客户端名称/版本，仅用于日志
+}
+
+// HelloResponse 后端的协议版本与支持的消息类型、事件
+type HelloResponse struct {
+	ProtocolVersion    int      `json:"protocolVersion"`
+	MinProtocolVersion int      `json:"minProtocolVersion"`
+	Types              []string `json:"types"`
+	Events             []string `json:"events"`
+}
+
+// MetricsResponse 后端按消息类型统计的请求指标
+type MetricsResponse struct {
+	UptimeSeconds int64           `json:"uptimeSeconds"`
+	Types         []MessageMetric `json:"types"`
+}
+
+// MessageMetric 单个消息类型的累计指标，耗时单位为毫秒
+type MessageMetric struct {
+	Type     string  `json:"type"`
+	Count    int64   `json:"count"`
+	Errors   int64   `json:"errors"`   // ok=false 的响应数（含超时与 panic）
+	Timeouts int64   `json:"timeouts"` // code=504 的响应数
+	AvgMs    float64 `json:"avgMs"`
+	MaxMs    float64 `json:"maxMs"`
+}
+
+// Spec 描述一种消息类型的请求与响应负载
+type Spec struct {
+	Type       string
+	Request    reflect.Type // 为 nil 表示无请求负载
+	Response   reflect.Type // 为 nil 表示无响应负载
+	Idempotent bool         // 可安全重放：连接中断后客户端仅对这些类型自动重试
+	Stream     bool         // 成功响应后连接切换为会话流
+}
+
+// registry 消息类型 -> 负载描述，前后端共享同一份
+var registry = map[string]Spec{}
+
+// Register 登记消息类型；req/resp 传对应结构体的零值，无负载时传 nil
+func Register(msgType string, req, resp any, idempotent bool) {
+	registry[msgType] = Spec{Type: msgType, Request: typeOf(req), Response: typeOf(resp), Idempotent: idempotent}
+}
+
+// RegisterStream 登记会话流类型（如 open_shell）
+func RegisterStream(msgType string, req, resp any) {
+	registry[msgType] = Spec{Type: msgType, Request: typeOf(req), Response: typeOf(resp), Stream: true}
+}
+
+// Lookup 返回消息类型的描述
+func Lookup(msgType string) (Spec, bool) {
+	s, ok := registry[msgType]
+	return s, ok
+}
+
+// Types 返回已登记的全部消息类型（排序后）
+func Types() []string {
+	out := make([]string, 0, len(registry))
+	for t := range registry {
+		out = append(out, t)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// IsIdempotent 判断消息类型是否可安全重试
+func IsIdempotent(msgType string) bool {
+	return registry[msgType].Idempotent
+}
+
+// Events 返回全部事件名
+func Events() []string {
+	return []string{EventAuthPrompt, EventConnectionState, EventExecuteResult, EventHostKeyChanged, EventHostKeyUnknown, EventMonitorSample, EventSessionExit, EventTransferProgress, EventTunnelStats, EventVaultState}
+}
+
+// FieldError 请求负载与登记的结构不匹配时的精确字段错误，作为 CodeBadRequest 响应的 data
+type FieldError struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+func (e *FieldError) Error() string {
+	if e.Field == "" {
+		return e.Reason
+	}
+	return fmt.Sprintf("field %q: %s", e.Field, e.Reason)
+}
+
+// ValidateRequest 按登记的请求结构严格解码 msg.Data：未知字段、类型不符均返回 *FieldError。
+// 未登记的类型或无请求负载的类型不做校验。
+func ValidateRequest(msg Message) error {
+	spec, ok := registry[msg.Type]
+	if !ok || spec.Request == nil || len(msg.Data) == 0 {
+		return nil
+	}
+	_, err := DecodeStrict(msg.Data, spec.Request)
+	return err
+}
+
+// DecodeStrict 将 data 严格解码为 t 类型的新值（返回指针）
+func DecodeStrict(data json.RawMessage, t reflect.Type) (any, error) {
+	v := reflect.New(t)
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v.Interface()); err != nil {
+		return nil, toFieldError(err)
+	}
+	return v.Interface(), nil
+}
+
+// toFieldError 将 encoding/json 的错误转换为字段错误
+func toFieldError(err error) error {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return &FieldError{Field: typeErr.Field, Reason: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value)}
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &FieldError{Reason: fmt.Sprintf("invalid json at offset %d: %v", syntaxErr.Offset, err)}
+	}
+	// DisallowUnknownFields 的错误形如: json: unknown field "xxx"
+	if f, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		return &FieldError{Field: strings.Trim(f, `"`), Reason: "unknown field"}
+	}
+	return &FieldError{Reason: err.Error()}
+}
+
+func typeOf(v any) reflect.Type {
+	if v == nil {
+		return nil
+	}
+	return reflect.TypeOf(v)
+}
+
+// 协议中全部请求/响应类型的登记表
+func init() {
+	Register("auth", AuthRequest{}, nil, false)
+	Register("hello", HelloRequest{}, HelloResponse{}, true)
+	Register("ping", nil, PingResponse{}, true)
+	Register("subscribe", SubscribeRequest{}, nil, true)
+	Register("unsubscribe", nil, nil, true)
+	Register("metrics", nil, MetricsResponse{}, true)
+	Register("shutdown", ShutdownRequest{}, nil, false)
+
+	Register("connect", ConnectRequest{}, ConnectResponse{}, false)
+	Register("disconnect", DisconnectRequest{}, ConnectResponse{}, false)
+	Register("list_connections", nil, ListConnectionsResponse{}, true)
+	Register("auth_answer", AuthAnswerRequest{}, nil, false)
+
+	Register("save_connection", Profile{}, Profile{}, false)
+	Register("get_connection", ProfileRequest{}, Profile{}, true)
+	Register("delete_connection", ProfileRequest{}, nil, false)
+	Register("list_profiles", nil, ListProfilesResponse{}, true)
+
+	Register("vault_status", nil, VaultStatus{}, true)
+	Register("vault_init", VaultPasswordRequest{}, VaultStatus{}, false)
+	Register("vault_unlock", VaultPasswordRequest{}, VaultStatus{}, false)
+	Register("vault_lock", nil, VaultStatus{}, true)
+	Register("vault_change_master", VaultChangeMasterRequest{}, VaultStatus{}, false)
+
+	Register("generate_key", GenerateKeyRequest{}, KeyInfo{}, false)
+	Register("import_key", ImportKeyRequest{}, KeyInfo{}, false)
+	Register("list_keys", nil, ListKeysResponse{}, true)
+	Register("export_public_key", KeyRequest{}, PublicKeyResponse{}, true)
+	Register("delete_key", KeyRequest{}, nil, false)
+	Register("install_public_key", InstallKeyRequest{}, InstallKeyResponse{}, false)
+
+	Register("create_tunnel", TunnelRequest{}, TunnelInfo{}, false)
+	Register("list_tunnels", ListTunnelsRequest{}, ListTunnelsResponse{}, true)
+	Register("close_tunnel", TunnelIDRequest{}, TunnelInfo{}, false)
+
+	Register("sftp_list", SFTPPathRequest{}, SFTPListResponse{}, true)
+	Register("sftp_stat", SFTPPathRequest{}, FileEntry{}, true)
+	Register("sftp_mkdir", SFTPMkdirRequest{}, FileEntry{}, false)
+	Register("sftp_rename", SFTPRenameRequest{}, FileEntry{}, false)
+	Register("sftp_remove", SFTPRemoveRequest{}, nil, false)
+	Register("sftp_chmod", SFTPChmodRequest{}, FileEntry{}, false)
+	Register("sftp_symlink", SFTPSymlinkRequest{}, FileEntry{}, false)
+
+	Register("start_transfer", TransferRequest{}, TransferInfo{}, false)
+	Register("list_transfers", ListTransfersRequest{}, ListTransfersResponse{}, true)
+	Register("cancel_transfer", TransferIDRequest{}, TransferInfo{}, false)
+
+	Register("list_processes", ListProcessesRequest{}, ListProcessesResponse{}, true)
+	Register("signal_process", SignalProcessRequest{}, ProcessActionResponse{}, false)
+	Register("renice_process", ReniceRequest{}, ProcessActionResponse{}, false)
+	Register("execute", ExecuteRequest{}, ExecuteResponse{}, false)
+	Register("multi_execute", MultiExecuteRequest{}, MultiExecuteResponse{}, false)
+	Register("cancel_multi_execute", CancelMultiExecuteRequest{}, MultiExecuteResponse{}, false)
+	Register("list_recordings", ListRecordingsRequest{}, ListRecordingsResponse{}, true)
+	Register("get_recording", GetRecordingRequest{}, GetRecordingResponse{}, true)
+
+	Register("list_host_keys", nil, ListHostKeysResponse{}, true)
+	Register("trust_host_key", TrustHostKeyRequest{}, HostKeyEntry{}, false)
+	Register("remove_host_key", RemoveHostKeyRequest{}, RemoveHostKeyResponse{}, false)
+
+	RegisterStream("open_shell", OpenShellRequest{}, OpenShellResponse{})
+	RegisterStream("monitor_subscribe", MonitorRequest{}, MonitorResponse{})
+}
@@ -0,0 +1,74 @@
+package proto
+
+import "time"
+
+// 文件类型，FileEntry.Type 的取值
+const (
+	FileTypeFile    = "file"
+	FileTypeDir     = "dir"
+	FileTypeSymlink = "symlink"
+	FileTypeOther   = "other" // 设备、管道、套接字等
+)
+
+// FileEntry 远端文件信息；符号链接本身的类型为 symlink，IsDir 表示其指向目录
+type FileEntry struct {
+	Name       string    `json:"name"`
+	Path       string    `json:"path"` // 绝对路径
+	Type       string    `json:"type"`
+	IsDir      bool      `json:"isDir"` // 目录或指向目录的符号链接，前端据此决定能否进入
+	Size       int64     `json:"size"`
+	Mode       string    `json:"mode"` // 如 -rwxr-xr-x
+	Perm       string    `json:"perm"` // 八进制权限位，如 0755
+	ModTime    time.Time `json:"modTime"`
+	LinkTarget string    `json:"linkTarget,omitempty"` // 符号链接指向的路径
+}
+
+// SFTPPathRequest 单个路径的操作（sftp_list / sftp_stat）。
+// Path 为空或 ~ 开头时相对远端用户主目录
+type SFTPPathRequest struct {
+	ConnID string `json:"connId"`
+	Path   string `json:"path"`
+}
+
+// SFTPListResponse 目录列表，目录在前、按名称排序；Path 为解析后的绝对路径
+type SFTPListResponse struct {
+	Path    string      `json:"path"`
+	Entries []FileEntry `json:"entries"`
+}
+
+// SFTPMkdirRequest 创建目录；Parents 同 mkdir -p，Mode 为八进制权限（如 "755"），为空使用远端默认
+type SFTPMkdirRequest struct {
+	ConnID  string `json:"connId"`
+	Path    string `json:"path"`
+	Parents bool   `json:"parents,omitempty"`
+	Mode    string `json:"mode,omitempty"`
+}
+
+// SFTPRenameRequest 重命名或移动；Overwrite 时使用 posix-rename 扩展覆盖已存在的目标
+type SFTPRenameRequest struct {
+	ConnID    string `json:"connId"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Overwrite bool   `json:"overwrite,omitempty"`
+}
+
+// SFTPRemoveRequest 删除文件、符号链接或空目录；Recursive 时递归删除目录
+type SFTPRemoveRequest struct {
+	ConnID    string `json:"connId"`
+	Path      string `json:"path"`
+	Recursive bool   `json:"recursive,omitempty"`
+}
+
+// SFTPChmodRequest 修改权限，Mode 为八进制字符串（如 "644"、"0755"）
+type SFTPChmodRequest struct {
+	ConnID string `json:"connId"`
+	Path   string `json:"path"`
+	Mode   string `json:"mode"`
+}
+
+// SFTPSymlinkRequest 创建符号链接 Link -> Target
+type SFTPSymlinkRequest struct {
+	ConnID string `json:"connId"`
+	Target string `json:"target"`
+	Link   string `json:"link"`
+}
@@ -0,0 +1,73 @@
+package proto
+
+import "time"
+
+// 传输方向，TransferRequest.Direction 的取值
+const (
+	TransferUpload   = "upload"   // 本机 -> 远端
+	TransferDownload = "download" // 远端 -> 本机
+)
+
+// 传输状态，TransferInfo.State 的取值
+const (
+	TransferQueued    = "queued"
+	TransferRunning   = "running"
+	TransferCompleted = "completed"
+	TransferFailed    = "failed"
+	TransferCanceled  = "canceled"
+)
+
+// TransferRequest 将文件或目录树加入传输队列。
+// 源为文件时，目标为已存在的目录或以 / 结尾则放入其中，否则即为目标文件路径；
+// 源为目录时递归传输，目标为对应的目录（不存在时创建）。
+// Include / Exclude 为 glob（path.Match 语法），与相对源目录的路径或文件名匹配即生效：
+// Include 非空时只传输匹配的文件，Exclude 匹配的文件与目录被跳过。
+type TransferRequest struct {
+	ConnID     string   `json:"connId"`
+	Direction  string   `json:"direction"`
+	LocalPath  string   `json:"localPath"`
+	RemotePath string   `json:"remotePath"` // 为空或 ~ 开头时相对远端主目录
+	Include    []string `json:"include,omitempty"`
+	Exclude    []string `json:"exclude,omitempty"`
+	Resume     bool     `json:"resume,omitempty"` // 目标已有较短的同名文件时从其末尾续传
+	Verify     bool     `json:"verify,omitempty"` // 完成后比对两端的 SHA-256
+}
+
+// TransferInfo 传输任务的配置与进度，同时作为 transfer_progress 事件负载
+type TransferInfo struct {
+	ID          string     `json:"id"`
+	ConnID      string     `json:"connId"`
+	Direction   string     `json:"direction"`
+	LocalPath   string     `json:"localPath"`
+	RemotePath  string     `json:"remotePath"` // 已解析为绝对路径
+	Resume      bool       `json:"resume,omitempty"`
+	Verify      bool       `json:"verify,omitempty"`
+	State       string     `json:"state"`
+	Error       string     `json:"error,omitempty"`
+	Files       int        `json:"files"`              // 待传输的文件数，扫描完成前为 0
+	FilesDone   int        `json:"filesDone"`          // 已完成（含校验）的文件数
+	Bytes       int64      `json:"bytes"`              // 全部文件的总字节数
+	Done        int64      `json:"done"`               // 已到达目标的字节数，含续传跳过的部分
+	Resumed     int64      `json:"resumed,omitempty"`  // 续传时跳过的字节数
+	Current     string     `json:"current,omitempty"`  // 正在传输的文件（相对源路径）
+	Checksum    string     `json:"checksum,omitempty"` // 单文件传输校验通过后的 SHA-256（十六进制）
+	BytesPerSec int64      `json:"bytesPerSec"`        // 最近一次进度推送以来的速率
+	CreatedAt   time.Time  `json:"createdAt"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// TransferIDRequest 按 ID 取消传输
+type TransferIDRequest struct {
+	ID string `json:"id"`
+}
+
+// ListTransfersRequest 列出传输；ConnID 非空时只列出该连接上的传输
+type ListTransfersRequest struct {
+	ConnID string `json:"connId,omitempty"`
+}
+
+// ListTransfersResponse 排队中、进行中与最近结束的传输，按创建时间排序
+type ListTransfersResponse struct {
+	Transfers []TransferInfo `json:"transfers"`
+}
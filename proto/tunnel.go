@@ -0,0 +1,62 @@
+package proto
+
+import "time"
+
+// 端口转发类型，TunnelRequest.Kind 的取值，对应 ssh -L / -R / -D
+const (
+	TunnelLocal   = "local"   // 本机监听，经 SSH 连接到远端可达的目标
+	TunnelRemote  = "remote"  // 远端监听，经 SSH 回连到本机可达的目标
+	TunnelDynamic = "dynamic" // 本机 SOCKS5 代理，目标由客户端在 SOCKS 请求中指定
+)
+
+// 转发状态，TunnelInfo.State 的取值
+const (
+	TunnelActive = "active"
+	TunnelClosed = "closed"
+)
+
+// TunnelRequest 在已建立的连接上创建端口转发。
+// BindHost 默认 127.0.0.1（remote 为远端的 localhost）；BindPort 为 0 时由系统分配，实际端口见 TunnelInfo。
+// Target 对 local / remote 必填，dynamic 忽略。
+type TunnelRequest struct {
+	ConnID     string `json:"connId"`
+	Kind       string `json:"kind"`
+	BindHost   string `json:"bindHost,omitempty"`
+	BindPort   int    `json:"bindPort"`
+	TargetHost string `json:"targetHost,omitempty"`
+	TargetPort int    `json:"targetPort,omitempty"`
+}
+
+// TunnelInfo 转发的配置与累计统计，同时作为 tunnel_stats 事件负载
+type TunnelInfo struct {
+	ID         string    `json:"id"`
+	ConnID     string    `json:"connId"`
+	Kind       string    `json:"kind"`
+	BindHost   string    `json:"bindHost"`
+	BindPort   int       `json:"bindPort"`
+	TargetHost string    `json:"targetHost,omitempty"`
+	TargetPort int       `json:"targetPort,omitempty"`
+	State      string    `json:"state"`           // active / closed
+	Error      string    `json:"error,omitempty"` // 非主动关闭时的原因，如底层连接断开
+	Active     int       `json:"active"`          // 当前转发中的连接数
+	Total      int64     `json:"total"`           // 累计接受的连接数
+	Failed     int64     `json:"failed"`          // 累计连接目标失败的次数
+	BytesOut   int64     `json:"bytesOut"`        // 发往目标的字节数
+	BytesIn    int64     `json:"bytesIn"`         // 从目标收到的字节数
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// TunnelIDRequest 按 ID 关闭转发
+type TunnelIDRequest struct {
+	ID string `json:"id"`
+}
+
+// ListTunnelsRequest 列出转发；ConnID 非空时只列出该连接上的转发
+type ListTunnelsRequest struct {
+	ConnID string `json:"connId,omitempty"`
+}
+
+// ListTunnelsResponse 转发列表，按创建时间排序
+type ListTunnelsResponse struct {
+	Tunnels []TunnelInfo `json:"tunnels"`
+}
@@ -0,0 +1,21 @@
+package proto
+
+// VaultStatus 凭据库状态
+type VaultStatus struct {
+	Initialized bool   `json:"initialized"` // 已设置主密码
+	Locked      bool   `json:"locked"`
+	Secrets     int    `json:"secrets"`          // 已保存的凭据数量
+	IdleSeconds int    `json:"idleSeconds"`      // 空闲多久后自动锁定，0 表示不自动锁定
+	Reason      string `json:"reason,omitempty"` // vault_state 事件中的变化原因：init/unlock/lock/idle/change_master
+}
+
+// VaultPasswordRequest 初始化或解锁凭据库
+type VaultPasswordRequest struct {
+	Password string `json:"password"`
+}
+
+// VaultChangeMasterRequest 修改主密码，已保存的凭据无需重新加密
+type VaultChangeMasterRequest struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"context"
+	"errors"
+
+	"go-ssh/proto"
+	"go-ssh/service/server"
+	"go-ssh/service/vault"
+)
+
+// Register 注册连接配置的增删改查消息处理器
+func (s *Store) Register(r *server.Router) {
+	r.Handle("save_connection", s.handleSave)
+	r.Handle("get_connection", s.handleGet)
+	r.Handle("delete_connection", s.handleDelete)
+	r.Handle("list_profiles", s.handleList)
+}
+
+func (s *Store) handleSave(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.Profile
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	p, err := s.Save(req)
+	if errors.Is(err, ErrInvalid) {
+		return server.BadRequest(err), nil
+	}
+	if errors.Is(err, vault.ErrLocked) {
+		return vault.LockedResponse(err), nil
+	}
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: p}, nil
+}
+
+func (s *Store) handleGet(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ProfileRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	p, err := s.Get(req.ID)
+	if errors.Is(err, ErrNotFound) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: p}, nil
+}
+
+func (s *Store) handleDelete(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ProfileRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	err := s.Delete(req.ID)
+	if errors.Is(err, ErrNotFound) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK}, nil
+}
+
+func (s *Store) handleList(context.Context, proto.Message) (proto.Response, error) {
+	ps, err := s.List()
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListProfilesResponse{Profiles: ps}}, nil
+}
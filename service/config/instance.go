@@ -0,0 +1,59 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"go-ssh/proto"
+)
+
+// ErrAlreadyRunning 表示同一数据目录下已有后端实例在运行
+var ErrAlreadyRunning = errors.New("service already running")
+
+var errLocked = errors.New("file locked")
+
+// AcquireInstance 获取数据目录的实例锁并写入 PID 文件。
+// 锁在进程存活期间一直持有（进程崩溃时由操作系统释放），release 在退出前释放并删除 PID 文件。
+func AcquireInstance(dir string, info proto.ServiceInfo) (release func(), err error) {
+	f, err := os.OpenFile(filepath.Join(dir, proto.LockFileName), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := tryLockFile(f); err != nil {
+		f.Close()
+		if errors.Is(err, errLocked) {
+			if old, rerr := ReadInstance(dir); rerr == nil {
+				return nil, fmt.Errorf("%w (pid %d)", ErrAlreadyRunning, old.PID)
+			}
+			return nil, ErrAlreadyRunning
+		}
+		return nil, fmt.Errorf("lock instance: %w", err)
+	}
+
+	pidPath := filepath.Join(dir, proto.PIDFileName)
+	b, _ := json.Marshal(info)
+	if err := os.WriteFile(pidPath, b, 0o600); err != nil {
+		unlockFile(f)
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		os.Remove(pidPath)
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// ReadInstance 读取 PID 文件
+func ReadInstance(dir string) (proto.ServiceInfo, error) {
+	var info proto.ServiceInfo
+	b, err := os.ReadFile(filepath.Join(dir, proto.PIDFileName))
+	if err != nil {
+		return info, err
+	}
+	err = json.Unmarshal(b, &info)
+	return info, err
+}
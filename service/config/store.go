@@ -0,0 +1,457 @@
+package config
+
+// Store 负责连接配置的 JSON 持久化：写入采用临时文件+重命名保证原子性，
+// 读改写全程持有文件锁，避免多个服务进程同时修改同一数据目录。
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+	"go-ssh/service/vault"
+)
+
+// SchemaVersion 为当前配置文件格式版本
+const SchemaVersion = 1
+
+// ErrNotFound 表示指定 ID 的配置不存在
+var ErrNotFound = errors.New("profile not found")
+
+// ErrInvalid 表示配置参数不合法
+var ErrInvalid = errors.New("invalid profile")
+
+// ErrClosed 表示存储已关闭
+var ErrClosed = errors.New("store closed")
+
+// fileData 为 profiles.json 的磁盘格式
+type fileData struct {
+	Version  int             `json:"version"`
+	Profiles []proto.Profile `json:"profiles"`
+}
+
+// Store 管理数据目录下的 profiles.json
+type Store struct {
+	// Vault 保存配置中的密码与私钥口令；为空时拒绝保存带密码的配置
+	Vault *vault.Vault
+
+	dir    string
+	mu     sync.Mutex
+	closed bool
+}
+
+// Open 在 dir 下打开（必要时创建）配置存储，并校验已有文件的版本
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	s := &Store{dir: dir}
+	err := s.withLock(func() error {
+		_, err := s.load()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Dir 返回数据目录
+func (s *Store) Dir() string { return s.dir }
+
+// List 返回全部配置，按分组、名称排序，密码字段被清空（凭据引用保留）
+func (s *Store) List() ([]proto.Profile, error) {
+	var out []proto.Profile
+	err := s.withLock(func() error {
+		d, err := s.load()
+		if err != nil {
+			return err
+		}
+		out = make([]proto.Profile, 0, len(d.Profiles))
+		for _, p := range d.Profiles {
+			p.Password, p.Passphrase = "", ""
+			p.Jumps = slices.Clone(p.Jumps)
+			for i := range p.Jumps {
+				p.Jumps[i].Password, p.Jumps[i].Passphrase = "", ""
+			}
+			out = append(out, p)
+		}
+		return nil
+	})
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Folder != out[j].Folder {
+			return out[i].Folder < out[j].Folder
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, err
+}
+
+// Get 返回指定 ID 的完整配置
+func (s *Store) Get(id string) (proto.Profile, error) {
+	var out proto.Profile
+	err := s.withLock(func() error {
+		d, err := s.load()
+		if err != nil {
+			return err
+		}
+		i := indexOf(d.Profiles, id)
+		if i < 0 {
+			return ErrNotFound
+		}
+		out = d.Profiles[i]
+		return nil
+	})
+	return out, err
+}
+
+// Save 新增或更新配置：ID 为空时生成新 ID，返回保存后的配置。
+// 提交的 Password/Passphrase 存入凭据库（需已解锁，否则返回 vault.ErrLocked），
+// 配置中只保留 PasswordRef/PassphraseRef；不再被引用的旧凭据随之删除。
+// 提交的引用只有在已保存的同一配置中存在时才保留，其余视为未设置。
+func (s *Store) Save(p proto.Profile) (proto.Profile, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Host = strings.TrimSpace(p.Host)
+	p.User = strings.TrimSpace(p.User)
+	p.Folder = strings.Trim(strings.TrimSpace(p.Folder), "/")
+	if p.Host == "" {
+		return proto.Profile{}, fmt.Errorf("%w: host is required", ErrInvalid)
+	}
+	if p.Port == 0 {
+		p.Port = 22
+	}
+	if p.Port < 0 || p.Port > 65535 {
+		return proto.Profile{}, fmt.Errorf("%w: port %d out of range", ErrInvalid, p.Port)
+	}
+	if p.Name == "" {
+		p.Name = p.Host
+	}
+	if len(p.Jumps) > proto.MaxJumps {
+		return proto.Profile{}, fmt.Errorf("%w: at most %d jump hosts", ErrInvalid, proto.MaxJumps)
+	}
+	for i := range p.Jumps {
+		j := &p.Jumps[i]
+		j.Host = strings.TrimSpace(j.Host)
+		j.User = strings.TrimSpace(j.User)
+		if j.Host == "" {
+			return proto.Profile{}, fmt.Errorf("%w: jump %d: host is required", ErrInvalid, i+1)
+		}
+		if j.Port == 0 {
+			j.Port = 22
+		}
+		if j.Port < 0 || j.Port > 65535 {
+			return proto.Profile{}, fmt.Errorf("%w: jump %d: port %d out of range", ErrInvalid, i+1, j.Port)
+		}
+	}
+	if p.ID == "" {
+		p.ID = newID()
+	}
+	// 凭据引用不信任前端：只保留该配置已保存的副本本来就引用的凭据，
+	// 否则提交他人的引用即可覆盖或借用其他配置的密码
+	var owned []string
+	switch old, err := s.Get(p.ID); {
+	case err == nil:
+		owned = secretRefs(old)
+	case !errors.Is(err, ErrNotFound):
+		return proto.Profile{}, err
+	}
+	dropForeignRefs(&p, owned)
+	created, err := s.sealSecrets(&p)
+	if err != nil {
+		return proto.Profile{}, err
+	}
+
+	var orphaned []string
+	err = s.withLock(func() error {
+		d, err := s.load()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		p.UpdatedAt = now
+		if i := indexOf(d.Profiles, p.ID); i >= 0 {
+			p.CreatedAt = d.Profiles[i].CreatedAt
+			orphaned = unreferenced(d.Profiles[i], p)
+			d.Profiles[i] = p
+		} else {
+			p.CreatedAt = now
+			d.Profiles = append(d.Profiles, p)
+		}
+		return s.write(d)
+	})
+	if err != nil {
+		s.deleteSecrets(created)
+		return proto.Profile{}, err
+	}
+	s.deleteSecrets(orphaned)
+	return p, nil
+}
+
+// MigrateSecrets 将凭据库启用前保存的明文凭据（密码、私钥口令及跳板机的密码与口令）移入凭据库，
+// 返回迁移的配置数量；凭据库锁定时返回 vault.ErrLocked
+func (s *Store) MigrateSecrets() (int, error) {
+	if s.Vault == nil {
+		return 0, nil
+	}
+	n := 0
+	var created []string
+	err := s.withLock(func() error {
+		d, err := s.load()
+		if err != nil {
+			return err
+		}
+		for i := range d.Profiles {
+			p := &d.Profiles[i]
+			if !hasPlaintext(*p) {
+				continue
+			}
+			ids, err := s.sealSecrets(p)
+			created = append(created, ids...)
+			if err != nil {
+				return err
+			}
+			n++
+		}
+		if n == 0 {
+			return nil
+		}
+		return s.write(d)
+	})
+	if err != nil {
+		s.deleteSecrets(created)
+		return 0, err
+	}
+	return n, nil
+}
+
+// sealSecrets 将配置（含跳板机）中的明文密码与口令存入凭据库并改为引用，返回新建的凭据 ID
+func (s *Store) sealSecrets(p *proto.Profile) ([]string, error) {
+	if !hasPlaintext(*p) {
+		return nil, nil
+	}
+	if s.Vault == nil {
+		return nil, fmt.Errorf("%w: no credential vault", ErrInvalid)
+	}
+	var created []string
+	put := func(ref *string, value *string, label string) error {
+		if *value == "" {
+			return nil
+		}
+		id, err := s.Vault.Put(*ref, label, *value)
+		if err != nil {
+			return err
+		}
+		if *ref == "" {
+			created = append(created, id)
+		}
+		*ref, *value = id, ""
+		return nil
+	}
+	if err := put(&p.PasswordRef, &p.Password, fmt.Sprintf("%s password", p.ID)); err != nil {
+		return created, err
+	}
+	if err := put(&p.PassphraseRef, &p.Passphrase, fmt.Sprintf("%s passphrase", p.ID)); err != nil {
+		return created, err
+	}
+	for i := range p.Jumps {
+		j := &p.Jumps[i]
+		if err := put(&j.PasswordRef, &j.Password, fmt.Sprintf("%s jump %d password", p.ID, i+1)); err != nil {
+			return created, err
+		}
+		if err := put(&j.PassphraseRef, &j.Passphrase, fmt.Sprintf("%s jump %d passphrase", p.ID, i+1)); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// hasPlaintext 判断配置（含跳板机）中是否有明文密码或口令
+func hasPlaintext(p proto.Profile) bool {
+	plain := p.Password != "" || p.Passphrase != ""
+	for _, j := range p.Jumps {
+		plain = plain || j.Password != "" || j.Passphrase != ""
+	}
+	return plain
+}
+
+// dropForeignRefs 清除 p（含跳板机）中不属于 owned 的凭据引用，对应的密码需重新提交
+func dropForeignRefs(p *proto.Profile, owned []string) {
+	drop := func(ref *string) {
+		if *ref != "" && !slices.Contains(owned, *ref) {
+			*ref = ""
+		}
+	}
+	drop(&p.PasswordRef)
+	drop(&p.PassphraseRef)
+	for i := range p.Jumps {
+		drop(&p.Jumps[i].PasswordRef)
+		drop(&p.Jumps[i].PassphraseRef)
+	}
+}
+
+// deleteSecrets 尽力删除凭据，失败只记录日志
+func (s *Store) deleteSecrets(ids []string) {
+	if s.Vault == nil || len(ids) == 0 {
+		return
+	}
+	if err := s.Vault.Delete(ids...); err != nil {
+		log.Printf("delete secrets %v: %v", ids, err)
+	}
+}
+
+// unreferenced 返回旧配置引用、新配置不再引用的凭据
+func unreferenced(old, p proto.Profile) []string {
+	var out []string
+	kept := secretRefs(p)
+	for _, ref := range secretRefs(old) {
+		if !slices.Contains(kept, ref) {
+			out = append(out, ref)
+		}
+	}
+	return out
+}
+
+// secretRefs 返回配置（含跳板机）引用的全部凭据 ID
+func secretRefs(p proto.Profile) []string {
+	var out []string
+	add := func(refs ...string) {
+		for _, ref := range refs {
+			if ref != "" {
+				out = append(out, ref)
+			}
+		}
+	}
+	add(p.PasswordRef, p.PassphraseRef)
+	for _, j := range p.Jumps {
+		add(j.PasswordRef, j.PassphraseRef)
+	}
+	return out
+}
+
+// Delete 删除指定 ID 的配置及其引用的凭据
+func (s *Store) Delete(id string) error {
+	var refs []string
+	err := s.withLock(func() error {
+		d, err := s.load()
+		if err != nil {
+			return err
+		}
+		i := indexOf(d.Profiles, id)
+		if i < 0 {
+			return ErrNotFound
+		}
+		refs = unreferenced(d.Profiles[i], proto.Profile{})
+		d.Profiles = append(d.Profiles[:i], d.Profiles[i+1:]...)
+		return s.write(d)
+	})
+	if err == nil {
+		s.deleteSecrets(refs)
+	}
+	return err
+}
+
+// Close 等待进行中的读写完成后关闭存储，之后的调用返回 ErrClosed。
+// 每次写入均已 fsync，关闭后磁盘上的文件即为最终状态。
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+// withLock 在进程内互斥锁与跨进程文件锁保护下执行 fn
+func (s *Store) withLock(fn func() error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	f, err := os.OpenFile(filepath.Join(s.dir, "profiles.lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("lock store: %w", err)
+	}
+	defer unlockFile(f)
+	return fn()
+}
+
+// load 读取 profiles.json；文件不存在时返回空数据
+func (s *Store) load() (*fileData, error) {
+	b, err := os.ReadFile(s.path())
+	if errors.Is(err, os.ErrNotExist) {
+		return &fileData{Version: SchemaVersion}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d fileData
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", s.path(), err)
+	}
+	if d.Version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d (max %d)", d.Version, SchemaVersion)
+	}
+	// 版本 0 为早期无版本号的文件，字段兼容，直接升级
+	d.Version = SchemaVersion
+	return &d, nil
+}
+
+// write 原子写入：先写同目录临时文件并 fsync，再重命名覆盖
+func (s *Store) write(d *fileData) error {
+	d.Version = SchemaVersion
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, "profiles-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path())
+}
+
+func (s *Store) path() string { return filepath.Join(s.dir, "profiles.json") }
+
+func indexOf(ps []proto.Profile, id string) int {
+	for i := range ps {
+		if ps[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// newID 生成 16 位十六进制随机 ID
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-ssh/proto"
+	"go-ssh/service/vault"
+)
+
+func TestMigrateSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile proto.Profile
+		// secrets 迁移后各引用应解出的值，键为引用所在位置
+		secrets map[string]string
+	}{
+		{"password", proto.Profile{ID: "pw", Password: "s1"}, map[string]string{"password": "s1"}},
+		{"passphrase only", proto.Profile{ID: "pp", KeyPath: "~/.ssh/id", Passphrase: "s2"}, map[string]string{"passphrase": "s2"}},
+		{"jump password only", proto.Profile{ID: "jp", Jumps: []proto.JumpHost{{Host: "b", Password: "s3"}}}, map[string]string{"jump password": "s3"}},
+		{"jump passphrase only", proto.Profile{ID: "jk", Jumps: []proto.JumpHost{{Host: "b", Passphrase: "s4"}}}, map[string]string{"jump passphrase": "s4"}},
+		{"no secrets", proto.Profile{ID: "none"}, map[string]string{}},
+	}
+
+	dir := t.TempDir()
+	d := fileData{Version: SchemaVersion}
+	for _, tt := range tests {
+		p := tt.profile
+		p.Name, p.Host, p.User = tt.name, "h", "u"
+		d.Profiles = append(d.Profiles, p)
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "profiles.json"), b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := vault.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Init("master"); err != nil {
+		t.Fatal(err)
+	}
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	s.Vault = v
+
+	n, err := s.MigrateSecrets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("migrated %d profiles, want 4", n)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "profiles.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"s1", "s2", "s3", "s4"} {
+		if strings.Contains(string(raw), `"`+secret+`"`) {
+			t.Errorf("profiles.json still contains %q in clear text", secret)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := s.Get(tt.profile.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			refs := map[string]string{"password": p.PasswordRef, "passphrase": p.PassphraseRef}
+			if len(p.Jumps) > 0 {
+				refs["jump password"], refs["jump passphrase"] = p.Jumps[0].PasswordRef, p.Jumps[0].PassphraseRef
+			}
+			for where, ref := range refs {
+				want, ok := tt.secrets[where]
+				if !ok {
+					if ref != "" {
+						t.Errorf("%s: unexpected reference %s", where, ref)
+					}
+					continue
+				}
+				got, err := v.Get(ref)
+				if err != nil || got != want {
+					t.Errorf("%s: vault value %q, %v; want %q", where, got, err, want)
+				}
+			}
+		})
+	}
+
+	// 再次迁移没有可迁移的内容
+	if n, err := s.MigrateSecrets(); err != nil || n != 0 {
+		t.Errorf("second migration = %d, %v; want 0", n, err)
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"go-ssh/proto"
+)
+
+// LoadOrCreateToken 读取数据目录下的 IPC 共享令牌，不存在时生成。
+// 令牌文件权限为 0600，只有当前用户可读，借此限制谁能驱动后端。
+func LoadOrCreateToken(dir string) (string, error) {
+	path := filepath.Join(dir, proto.TokenFileName)
+	b, err := os.ReadFile(path)
+	if err == nil {
+		tok := strings.TrimSpace(string(b))
+		if tok == "" {
+			return "", fmt.Errorf("empty token file %s", path)
+		}
+		if runtime.GOOS != "windows" {
+			if fi, err := os.Stat(path); err == nil && fi.Mode().Perm()&0o077 != 0 {
+				// 权限被放宽时收紧
+				if err := os.Chmod(path, 0o600); err != nil {
+					return "", err
+				}
+			}
+		}
+		return tok, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	tok := hex.EncodeToString(raw)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		// 并发启动的另一个进程已生成
+		return LoadOrCreateToken(dir)
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.WriteString(tok + "\n"); err != nil {
+		f.Close()
+		return "", err
+	}
+	return tok, f.Close()
+}
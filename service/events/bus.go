@@ -0,0 +1,86 @@
+package events
+
+// Bus 进程内事件总线：子系统发布事件，IPC 连接按订阅过滤后推送给前端。
+// 发布永不阻塞，订阅者缓冲区满时丢弃事件并计数，前端可通过 seq 断档发现丢失。
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// Bus 事件总线，零值不可用，请使用 NewBus
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+	seq  atomic.Uint64
+}
+
+// Subscription 单个订阅者，C 在 Close 后被关闭
+type Subscription struct {
+	C <-chan proto.Event
+
+	c       chan proto.Event
+	filter  map[string]bool // 为空表示全部
+	bus     *Bus
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe 订阅指定事件（names 为空表示全部），buf 为缓冲区大小
+func (b *Bus) Subscribe(names []string, buf int) *Subscription {
+	if buf <= 0 {
+		buf = 256
+	}
+	c := make(chan proto.Event, buf)
+	s := &Subscription{C: c, c: c, bus: b}
+	if len(names) > 0 {
+		s.filter = make(map[string]bool, len(names))
+		for _, n := range names {
+			s.filter[n] = true
+		}
+	}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish 向所有匹配的订阅者广播事件
+func (b *Bus) Publish(name string, data any) {
+	ev := proto.Event{Event: name, Seq: b.seq.Add(1), Time: time.Now(), Data: data}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		if s.filter != nil && !s.filter[name] {
+			continue
+		}
+		select {
+		case s.c <- ev:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
+
+// Close 取消订阅并关闭通道，可重复调用
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.c)
+	})
+}
+
+// Dropped 返回因缓冲区满而丢弃的事件数
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
@@ -1,28 +1,252 @@
 package main
 
 import (
-    "log"
-    "go-ssh/proto"
-    "go-ssh/service/server"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+
+	"go-ssh/proto"
+	"go-ssh/service/config"
+	"go-ssh/service/events"
+	"go-ssh/service/server"
+	"go-ssh/service/ssh"
+	"go-ssh/service/vault"
 )
 
+// sshManager 统一持有所有 SSH 连接
+var sshManager = ssh.NewManager()
+
+// bus 事件总线，向订阅的前端连接推送异步通知
+var bus = events.NewBus()
+
+// store 连接配置存储，在 main 中按数据目录打开
+var store *config.Store
+
+// secrets 凭据库，保存配置中的密码与私钥口令
+var secrets *vault.Vault
+
 // main 启动后端服务进程，接入统一的 TCP+JSON 服务器。
 func main() {
-    if err := server.Start(":8089", handleMessage); err != nil {
-        log.Fatalf("server start error: %v", err)
-    }
+	dataDir := flag.String("data", proto.DefaultDataDir(), "数据目录（JSON 配置文件）")
+	addr := flag.String("addr", proto.DefaultAddr, "TCP 监听地址，默认仅回环；为空则不监听 TCP")
+	sock := flag.String("socket", "auto", "Unix 域套接字路径；auto 为数据目录下的 service.sock（Windows 不监听），为空则不监听")
+	vaultIdle := flag.Duration("vault-idle", 15*time.Minute, "凭据库解锁后空闲多久自动锁定，0 表示不自动锁定")
+	transfers := flag.Int("transfer-concurrency", ssh.DefaultTransferConcurrency, "同时进行的文件传输数，其余排队等待")
+	record := flag.String("record", proto.RecordOff, "shell 会话录像：off 不录像，output 记录输出，input 同时记录键盘输入；文件位于数据目录下的 recordings")
+	logMaxSize := flag.Int64("log-max-size", ssh.DefaultSessionLogMaxSize, "会话文本日志单个文件的最大字节数，超过后轮转")
+	logMaxAge := flag.Duration("log-max-age", ssh.DefaultSessionLogMaxAge, "会话文本日志轮转后保留多久")
+	flag.Parse()
+	switch *record {
+	case proto.RecordOff, proto.RecordOutput, proto.RecordInput:
+	default:
+		log.Fatalf("invalid -record %q: want off, output or input", *record)
+	}
+	if *sock == "auto" {
+		*sock = defaultSocket(*dataDir)
+	}
+	// 参数错误在取得实例锁之前退出，不留下 PID 文件与锁
+	var addrs []string
+	if *sock != "" {
+		addrs = append(addrs, "unix:"+*sock)
+	}
+	if *addr != "" {
+		addrs = append(addrs, *addr)
+	}
+	if len(addrs) == 0 {
+		log.Fatalf("no listener: both -addr and -socket are empty")
+	}
+
+	var err error
+	if store, err = config.Open(*dataDir); err != nil {
+		log.Fatalf("open config store error: %v", err)
+	}
+	log.Printf("config store: %s", store.Dir())
+	if secrets, err = vault.Open(*dataDir); err != nil {
+		log.Fatalf("open vault error: %v", err)
+	}
+	secrets.IdleTimeout = *vaultIdle
+	store.Vault = secrets
+
+	sshManager.Publish = bus.Publish
+	sshManager.HostKeys = ssh.NewKnownHosts(filepath.Join(*dataDir, "known_hosts"))
+	sshManager.HostKeys.Publish = bus.Publish
+	sshManager.Vault = secrets
+	sshManager.Keys = ssh.NewKeyStore(filepath.Join(*dataDir, "keys"))
+	sshManager.TransferConcurrency = *transfers
+	sshManager.Recordings = ssh.NewRecordingStore(filepath.Join(*dataDir, "recordings"), *record)
+	sshManager.SessionLogs = ssh.NewSessionLogStore(filepath.Join(*dataDir, "logs"), *logMaxSize, *logMaxAge)
+	sshManager.Profile = store.Get
+	sshManager.Profiles = func() ([]proto.Profile, error) {
+		// List 清空了明文密码，逐个取完整配置以兼容凭据库启用前保存的配置
+		list, err := store.List()
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			if list[i], err = store.Get(list[i].ID); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	}
+	secrets.Publish = bus.Publish
+	// 解锁后将旧配置中的明文密码迁入凭据库
+	secrets.OnUnlock = func() {
+		if n, err := store.MigrateSecrets(); err != nil {
+			log.Printf("migrate secrets: %v", err)
+		} else if n > 0 {
+			log.Printf("migrated %d profile password(s) into vault", n)
+		}
+	}
+
+	release, err := config.AcquireInstance(*dataDir, proto.ServiceInfo{
+		PID: os.Getpid(), Addr: *addr, Socket: *sock, StartedAt: time.Now().UTC(),
+	})
+	if errors.Is(err, config.ErrAlreadyRunning) {
+		log.Printf("%v: data dir %s", err, *dataDir)
+		os.Exit(proto.ExitAlreadyRunning)
+	}
+	if err != nil {
+		log.Fatalf("acquire instance lock error: %v", err)
+	}
+	// 此后的错误经 run 返回，先释放实例锁再退出
+	err = run(*dataDir, addrs)
+	release()
+	if err != nil {
+		log.Printf("%v", err)
+		os.Exit(1)
+	}
 }
 
-// handleMessage 路由消息类型并返回统一响应。
-func handleMessage(msg proto.Message) (proto.Response, error) {
-    switch msg.Type {
-    case "ping":
-        return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.PingResponse{Message: "pong"}}, nil
-    case "list_connections":
-        // TODO: 真实实现从连接管理器拉取现有连接列表
-        return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListConnectionsResponse{Connections: []proto.ConnectResponse{}}}, nil
-    default:
-        // 未知类型时返回标准错误响应
-        return proto.Response{Ok: false, Code: proto.CodeUnknownType, Message: "unknown type: " + msg.Type}, nil
-    }
-}
\ No newline at end of file
+// run 加载 IPC token 并在 addrs 上监听，直到收到退出信号、shutdown 消息或监听失败，随后优雅关闭
+func run(dataDir string, addrs []string) error {
+	token, err := config.LoadOrCreateToken(dataDir)
+	if err != nil {
+		return fmt.Errorf("load ipc token: %w", err)
+	}
+
+	// SIGINT/SIGTERM 或 shutdown 消息均通过取消 ctx 触发优雅关闭
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	srv := server.New(newRouter(token, server.NewMetrics(), stop))
+	srv.Events = bus
+	srv.OnShutdown(sshManager.CloseAll)
+
+	errc := make(chan error, len(addrs))
+	for _, a := range addrs {
+		go func(a string) {
+			errc <- fmt.Errorf("listen %s: %w", a, srv.Serve(a))
+		}(a)
+	}
+
+	select {
+	case <-ctx.Done():
+		log.Printf("shutting down...")
+	case err = <-errc:
+		// 监听失败：关闭后由 main 记录错误并以 1 退出
+	}
+	return errors.Join(err, shutdown(srv))
+}
+
+// shutdown 依次停止服务器、关闭全部 SSH 会话与连接、锁定凭据库、关闭配置存储，返回其间的错误
+func shutdown(srv *server.Server) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	var errs []error
+	if err := srv.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("shutdown: %w", err))
+	}
+	sshManager.CloseAll()
+	_ = secrets.Close()
+	if err := store.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("close config store: %w", err))
+	}
+	log.Printf("service stopped")
+	return errors.Join(errs...)
+}
+
+// defaultSocket 非 Windows 平台默认在数据目录下监听 Unix 域套接字
+func defaultSocket(dataDir string) string {
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	return filepath.Join(dataDir, "service.sock")
+}
+
+// newRouter 组装中间件链并注册各子系统的处理器。
+// 顺序：日志在最外层，认证与校验先于指标与超时（未认证或格式错误的请求不计入指标），
+// Recover 位于超时协程内以捕获处理器 panic。
+// shutdown 在 "shutdown" 消息应答后被调用以触发退出。
+func newRouter(token string, metrics *server.Metrics, shutdown func()) *server.Router {
+	r := server.NewRouter()
+	r.Use(
+		server.Logging(),
+		server.Auth(token),
+		server.Validate(),
+		metrics.Middleware(),
+		server.Timeout(proto.RequestTimeout, map[string]time.Duration{
+			// 拨号由 Manager.DialTimeout 控制；认证可能等待用户输入（Manager.PromptTimeout，
+			// 口令与多轮 keyboard-interactive 各自计时），这里留出余量
+			"connect": 10 * time.Minute,
+			// 大位数 RSA 密钥生成可能耗时数十秒
+			"generate_key": 2 * time.Minute,
+			// 由 ExecuteRequest.TimeoutMs 控制（至多 proto.MaxExecuteTimeout），超时后仍返回已收到的输出
+			"execute": 0,
+		}),
+		server.Recover(),
+	)
+	r.Handle("ping", handlePing)
+	r.Handle("hello", handleHello)
+	r.Handle("metrics", metrics.Handle)
+	r.Handle("shutdown", func(_ context.Context, msg proto.Message) (proto.Response, error) {
+		var req proto.ShutdownRequest
+		if len(msg.Data) > 0 {
+			if err := server.Decode(msg, &req); err != nil {
+				return server.BadRequest(err), nil
+			}
+		}
+		log.Printf("shutdown requested: %s", req.Reason)
+		// 仅取消主 ctx，当前响应在连接排空过程中照常写回
+		shutdown()
+		return proto.Response{Ok: true, Code: proto.CodeOK}, nil
+	})
+	sshManager.Register(r)
+	sshManager.HostKeys.Register(r)
+	sshManager.Keys.Register(r)
+	store.Register(r)
+	secrets.Register(r)
+	return r
+}
+
+func handlePing(context.Context, proto.Message) (proto.Response, error) {
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.PingResponse{Message: "pong"}}, nil
+}
+
+// handleHello 协商协议版本并返回支持的消息类型与事件
+func handleHello(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.HelloRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	hello := proto.HelloResponse{
+		ProtocolVersion:    proto.ProtocolVersion,
+		MinProtocolVersion: proto.MinProtocolVersion,
+		Types:              proto.Types(),
+		Events:             proto.Events(),
+	}
+	if req.ProtocolVersion < proto.MinProtocolVersion {
+		msg := fmt.Sprintf("client protocol v%d is too old, service requires >= v%d", req.ProtocolVersion, proto.MinProtocolVersion)
+		return proto.Response{Ok: false, Code: proto.CodeVersion, Message: msg, Data: hello}, nil
+	}
+	log.Printf("hello from %q protocol v%d", req.Client, req.ProtocolVersion)
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: hello}, nil
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// Logging 记录每个请求的类型、ID、结果码与耗时
+func Logging() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg proto.Message) (proto.Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, msg)
+			elapsed := time.Since(start)
+			switch {
+			case err != nil:
+				log.Printf("req %s id=%s error=%v elapsed=%s", msg.Type, msg.ID, err, elapsed)
+			case !resp.Ok:
+				log.Printf("req %s id=%s code=%d msg=%s elapsed=%s", msg.Type, msg.ID, resp.Code, resp.Message, elapsed)
+			default:
+				log.Printf("req %s id=%s code=%d elapsed=%s", msg.Type, msg.ID, resp.Code, elapsed)
+			}
+			return resp, err
+		}
+	}
+}
+
+// Recover 将处理器中的 panic 转换为 500 响应并记录堆栈，避免连接协程静默退出
+func Recover() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg proto.Message) (resp proto.Response, err error) {
+			defer func() {
+				if v := recover(); v != nil {
+					log.Printf("panic in %s handler: %v\n%s", msg.Type, v, debug.Stack())
+					resp = proto.Response{Ok: false, Code: proto.CodeServerError, Message: fmt.Sprintf("internal error: %v", v)}
+					err = nil
+				}
+			}()
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Timeout 为每个请求设置截止时间：d 为默认值，perType 按消息类型覆盖，<=0 表示不限。
+// 到期时立即返回 504，处理器通过 ctx 感知取消后自行收尾。
+func Timeout(d time.Duration, perType map[string]time.Duration) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg proto.Message) (proto.Response, error) {
+			t := d
+			if v, ok := perType[msg.Type]; ok {
+				t = v
+			}
+			if t <= 0 {
+				return next(ctx, msg)
+			}
+			ctx, cancel := context.WithTimeout(ctx, t)
+			defer cancel()
+
+			type result struct {
+				resp proto.Response
+				err  error
+			}
+			done := make(chan result, 1)
+			go func() {
+				resp, err := next(ctx, msg)
+				done <- result{resp, err}
+			}()
+			select {
+			case r := <-done:
+				return r.resp, r.err
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return proto.Response{Ok: false, Code: proto.CodeTimeout, Message: fmt.Sprintf("%s timed out after %s", msg.Type, t)}, nil
+				}
+				return proto.Response{}, ctx.Err()
+			}
+		}
+	}
+}
+
+// Auth 要求连接先发送携带 token 的 auth 消息：auth 由本中间件直接应答，
+// 未认证连接上的其它请求返回 401（连接随后被服务器断开）。
+func Auth(token string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg proto.Message) (proto.Response, error) {
+			p := PeerFrom(ctx)
+			if msg.Type == "auth" {
+				var req proto.AuthRequest
+				_ = json.Unmarshal(msg.Data, &req)
+				if subtle.ConstantTimeCompare([]byte(req.Token), []byte(token)) != 1 {
+					return proto.Response{Ok: false, Code: proto.CodeUnauthorized, Message: "unauthorized"}, nil
+				}
+				if p != nil {
+					p.authed.Store(true)
+				}
+				return proto.Response{Ok: true, Code: proto.CodeOK}, nil
+			}
+			if p == nil || !p.Authenticated() {
+				return proto.Response{Ok: false, Code: proto.CodeUnauthorized, Message: "unauthorized"}, nil
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Validate 按协议登记表严格校验请求负载，字段错误作为 data 返回
+func Validate() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg proto.Message) (proto.Response, error) {
+			if err := proto.ValidateRequest(msg); err != nil {
+				return proto.Response{Ok: false, Code: proto.CodeBadRequest, Message: err.Error(), Data: err}, nil
+			}
+			return next(ctx, msg)
+		}
+	}
+}
+
+// Metrics 按消息类型累计请求数、错误数、超时数与耗时
+type Metrics struct {
+	start  time.Time
+	mu     sync.Mutex
+	byType map[string]*metric
+}
+
+type metric struct {
+	count, errors, timeouts int64
+	total, max              time.Duration
+}
+
+// NewMetrics 创建指标收集器
+func NewMetrics() *Metrics {
+	return &Metrics{start: time.Now(), byType: make(map[string]*metric)}
+}
+
+// unknownMetric 未在 proto 中登记的消息类型统一计入该项，避免任意类型名使指标无限增长
+const unknownMetric = "unknown"
+
+// Middleware 返回记录指标的中间件；应注册在 Auth 之后，只统计已认证的请求
+func (m *Metrics) Middleware() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(ctx context.Context, msg proto.Message) (proto.Response, error) {
+			start := time.Now()
+			resp, err := next(ctx, msg)
+			msgType := msg.Type
+			if _, ok := proto.Lookup(msgType); !ok {
+				msgType = unknownMetric
+			}
+			m.observe(msgType, time.Since(start), err == nil && resp.Ok, resp.Code == proto.CodeTimeout)
+			return resp, err
+		}
+	}
+}
+
+func (m *Metrics) observe(msgType string, d time.Duration, ok, timeout bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.byType[msgType]
+	if s == nil {
+		s = &metric{}
+		m.byType[msgType] = s
+	}
+	s.count++
+	s.total += d
+	if d > s.max {
+		s.max = d
+	}
+	if !ok {
+		s.errors++
+	}
+	if timeout {
+		s.timeouts++
+	}
+}
+
+// Snapshot 返回当前指标，按消息类型排序
+func (m *Metrics) Snapshot() proto.MetricsResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := proto.MetricsResponse{UptimeSeconds: int64(time.Since(m.start).Seconds()), Types: make([]proto.MessageMetric, 0, len(m.byType))}
+	for t, s := range m.byType {
+		out.Types = append(out.Types, proto.MessageMetric{
+			Type:     t,
+			Count:    s.count,
+			Errors:   s.errors,
+			Timeouts: s.timeouts,
+			AvgMs:    float64(s.total) / float64(s.count) / float64(time.Millisecond),
+			MaxMs:    float64(s.max) / float64(time.Millisecond),
+		})
+	}
+	sort.Slice(out.Types, func(i, j int) bool { return out.Types[i].Type < out.Types[j].Type })
+	return out
+}
+
+// Handle 为 "metrics" 消息的处理器
+func (m *Metrics) Handle(context.Context, proto.Message) (proto.Response, error) {
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: m.Snapshot()}, nil
+}
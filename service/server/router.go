@@ -0,0 +1,145 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"go-ssh/proto"
+)
+
+// HandlerFunc 处理一条请求并返回已封装好的响应；返回 error 时统一转换为 500。
+// ctx 在连接断开或超时中间件到期时取消。
+type HandlerFunc func(ctx context.Context, msg proto.Message) (proto.Response, error)
+
+// Middleware 包装处理器，用于日志、恢复、超时、指标、认证等横切逻辑
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// Router 按消息类型分发请求。各子系统通过 Handle/HandleStream 注册自己的处理器，
+// 中间件对普通请求、流的首条消息以及连接内置的 subscribe/unsubscribe 同样生效。
+type Router struct {
+	mu       sync.RWMutex
+	handlers map[string]HandlerFunc
+	streams  map[string]StreamHandler
+	chain    []Middleware
+}
+
+// NewRouter 创建空路由
+func NewRouter() *Router {
+	return &Router{handlers: make(map[string]HandlerFunc), streams: make(map[string]StreamHandler)}
+}
+
+// Use 追加中间件，先注册的在外层。
+// Timeout 会在新的 goroutine 中执行后续处理器，Recover 须注册在它之后才能捕获处理器的 panic。
+func (r *Router) Use(mw ...Middleware) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.chain = append(r.chain, mw...)
+}
+
+// Handle 注册普通请求处理器，同一类型重复注册视为编码错误
+func (r *Router) Handle(msgType string, h HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mustBeFree(msgType)
+	r.handlers[msgType] = h
+}
+
+// HandleStream 注册流处理器：收到该类型消息时，连接由处理器独占
+func (r *Router) HandleStream(msgType string, h StreamHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mustBeFree(msgType)
+	r.streams[msgType] = h
+}
+
+func (r *Router) mustBeFree(msgType string) {
+	_, h := r.handlers[msgType]
+	_, s := r.streams[msgType]
+	if h || s {
+		panic(fmt.Sprintf("server: handler for %q already registered", msgType))
+	}
+}
+
+// Serve 经过中间件链调用对应处理器，响应带回请求 ID
+func (r *Router) Serve(ctx context.Context, msg proto.Message) proto.Response {
+	r.mu.RLock()
+	h, ok := r.handlers[msg.Type]
+	chain := r.chain
+	r.mu.RUnlock()
+	if !ok {
+		h = unknownType
+	}
+	return r.run(ctx, msg, chain, h)
+}
+
+// admit 仅执行中间件链（认证、校验等），通过时返回 ok，用于流与连接内置消息
+func (r *Router) admit(ctx context.Context, msg proto.Message) proto.Response {
+	r.mu.RLock()
+	chain := r.chain
+	r.mu.RUnlock()
+	return r.run(ctx, msg, chain, func(context.Context, proto.Message) (proto.Response, error) {
+		return proto.Response{Ok: true, Code: proto.CodeOK}, nil
+	})
+}
+
+// stream 返回消息类型对应的流处理器
+func (r *Router) stream(msgType string) (StreamHandler, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	h, ok := r.streams[msgType]
+	return h, ok
+}
+
+func (r *Router) run(ctx context.Context, msg proto.Message, chain []Middleware, h HandlerFunc) proto.Response {
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	resp, err := h(ctx, msg)
+	if err != nil {
+		resp = proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()}
+	}
+	resp.ID = msg.ID
+	return resp
+}
+
+func unknownType(_ context.Context, msg proto.Message) (proto.Response, error) {
+	return proto.Response{Ok: false, Code: proto.CodeUnknownType, Message: "unknown type: " + msg.Type}, nil
+}
+
+// Peer 描述发起请求的 IPC 连接，可通过 PeerFrom 从 ctx 取得
+type Peer struct {
+	Addr   string
+	authed atomic.Bool
+}
+
+// Authenticated 报告连接是否已通过 auth 握手
+func (p *Peer) Authenticated() bool { return p.authed.Load() }
+
+type peerKey struct{}
+
+// PeerFrom 返回 ctx 所属的连接；不在连接上下文中时返回 nil
+func PeerFrom(ctx context.Context) *Peer {
+	p, _ := ctx.Value(peerKey{}).(*Peer)
+	return p
+}
+
+func withPeer(ctx context.Context, p *Peer) context.Context {
+	return context.WithValue(ctx, peerKey{}, p)
+}
+
+// Decode 将消息负载解码到目标结构
+func Decode(msg proto.Message, v any) error {
+	if len(msg.Data) == 0 {
+		return errors.New("missing data")
+	}
+	return json.Unmarshal(msg.Data, v)
+}
+
+// BadRequest 构造参数错误响应
+func BadRequest(err error) proto.Response {
+	return proto.Response{Ok: false, Code: proto.CodeBadRequest, Message: err.Error()}
+}
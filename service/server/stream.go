@@ -0,0 +1,70 @@
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"go-ssh/proto"
+)
+
+// StreamHandler 接管整个连接的长时处理器（如交互式 shell）。
+// 约定：处理器负责先用 Reply 回复首条消息，之后通过 Send/Recv 双向收发，
+// 返回后连接即被关闭。
+type StreamHandler func(msg proto.Message, s *Stream) error
+
+// Stream 为被流处理器接管的连接，读写仍为一行一个 JSON
+type Stream struct {
+	conn net.Conn
+	r    *bufio.Reader
+	w    *connWriter
+}
+
+// Recv 读取下一条前端消息
+func (s *Stream) Recv() (proto.Message, error) {
+	var msg proto.Message
+	line, err := readFrame(s.r, proto.MaxFrameSize)
+	if err != nil {
+		return msg, err
+	}
+	err = json.Unmarshal(line, &msg)
+	return msg, err
+}
+
+// Reply 回复流的首条消息
+func (s *Stream) Reply(resp proto.Response) error {
+	return s.w.write(resp)
+}
+
+// Send 向前端发送一条流消息，v 编码为 Message.Data
+func (s *Stream) Send(msgType string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return s.w.write(proto.Message{Type: msgType, Data: b})
+}
+
+// Close 关闭底层连接，使阻塞中的 Recv 返回
+func (s *Stream) Close() error {
+	return s.conn.Close()
+}
+
+// errFrameTooLong 表示一行消息超过长度上限
+var errFrameTooLong = errors.New("frame too long")
+
+// readFrame 读取一行消息（含换行符）；累计超过 limit 字节时不再读取，返回 errFrameTooLong
+func readFrame(r *bufio.Reader, limit int) ([]byte, error) {
+	var line []byte
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(line)+len(chunk) > limit {
+			return nil, errFrameTooLong
+		}
+		line = append(line, chunk...)
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
@@ -1,66 +1,363 @@
 package server
 
 import (
-    "bufio"
-    "encoding/json"
-    "io"
-    "log"
-    "net"
-    "go-ssh/proto"
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ssh/proto"
+	"go-ssh/service/events"
 )
 
-// Start 启动 TCP 服务器并处理连接。
-// 约定：handler 返回已封装好的 proto.Response。
-func Start(addr string, handler func(proto.Message) (proto.Response, error)) error {
-    ln, err := net.Listen("tcp", addr)
-    if err != nil {
-        return err
-    }
-    log.Printf("TCP server listening on %s", addr)
-    for {
-        conn, err := ln.Accept()
-        if err != nil {
-            log.Printf("accept error: %v", err)
-            continue
-        }
-        go handle(conn, handler)
-    }
+// ErrServerClosed 由 Serve 在 Shutdown 之后返回
+var ErrServerClosed = errors.New("server closed")
+
+// Server 为统一的 TCP+JSON 服务器，跟踪全部监听器与客户端连接以支持优雅关闭。
+type Server struct {
+	// Router 分发请求，须在 Serve 之前设置
+	Router *Router
+	// Events 服务端推送使用的事件总线；为空时不支持 subscribe
+	Events *events.Bus
+
+	mu        sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	onClose   []func()
+	closing   atomic.Bool
+	handlers  sync.WaitGroup
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+// New 创建使用 router 分发请求的服务器
+func New(router *Router) *Server {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Server{
+		Router:    router,
+		listeners: make(map[net.Listener]struct{}),
+		conns:     make(map[net.Conn]struct{}),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Serve 监听 addr 并处理连接，直到 Shutdown 或监听器出错。
+// addr 为 TCP 地址（如 127.0.0.1:8089）或 "unix:/path/to/sock"。
+// 约定：请求经 router 的中间件链分发，处理器返回已封装好的 proto.Response。
+func (s *Server) Serve(addr string) error {
+	if s.closing.Load() {
+		return ErrServerClosed
+	}
+	ln, err := listen(addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.listeners[ln] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, ln)
+		s.mu.Unlock()
+		ln.Close()
+	}()
+	log.Printf("server listening on %s", addr)
+
+	var delay time.Duration
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.closing.Load() {
+				return ErrServerClosed
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			// 临时错误（如文件描述符耗尽）退避后重试，避免空转
+			if delay == 0 {
+				delay = 5 * time.Millisecond
+			} else if delay *= 2; delay > time.Second {
+				delay = time.Second
+			}
+			log.Printf("accept error: %v; retrying in %s", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		if !s.track(conn) {
+			conn.Close()
+			continue
+		}
+		s.handlers.Add(1)
+		go func() {
+			defer s.handlers.Done()
+			defer s.untrack(conn)
+			s.handle(conn)
+		}()
+	}
+}
+
+// OnShutdown 注册在停止接受连接之后、等待连接排空之前调用的清理函数（如关闭 SSH 会话）
+func (s *Server) OnShutdown(f func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onClose = append(s.onClose, f)
+}
+
+// Shutdown 优雅关闭：停止接受新连接，中断各连接的读取使其不再接收新请求，
+// 等待进行中的请求与会话流结束后返回。ctx 到期时强制关闭剩余连接并返回 ctx.Err()。
+func (s *Server) Shutdown(ctx context.Context) error {
+	if !s.closing.CompareAndSwap(false, true) {
+		return ErrServerClosed
+	}
+	s.mu.Lock()
+	for ln := range s.listeners {
+		ln.Close()
+	}
+	// 读截止时间立即到期：空闲连接退出读循环，已在处理的请求照常完成并写回
+	for c := range s.conns {
+		_ = c.SetReadDeadline(time.Now())
+	}
+	hooks := s.onClose
+	s.mu.Unlock()
+	for _, f := range hooks {
+		f()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		s.handlers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		// 取消处理器上下文并强制断开
+		s.cancel()
+		s.mu.Lock()
+		for c := range s.conns {
+			c.Close()
+		}
+		s.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// track 登记新连接；服务器关闭中时拒绝
+func (s *Server) track(c net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closing.Load() {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(c net.Conn) {
+	s.mu.Lock()
+	delete(s.conns, c)
+	s.mu.Unlock()
 }
 
-func handle(c net.Conn, handler func(proto.Message) (proto.Response, error)) {
-    defer c.Close()
-    r := bufio.NewReader(c)
-    for {
-        line, err := r.ReadBytes('\n')
-        if err != nil {
-            if err != io.EOF {
-                log.Printf("read error: %v", err)
-            }
-            return
-        }
-        var msg proto.Message
-        if err := json.Unmarshal(line, &msg); err != nil {
-            log.Printf("json decode error: %v", err)
-            _ = writeJSON(c, proto.Response{Ok: false, Code: proto.CodeBadRequest, Message: "invalid json"})
-            continue
-        }
-
-        resp, err := handler(msg)
-        if err != nil {
-            _ = writeJSON(c, proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()})
-            continue
-        }
-        // 正常直接返回 handler 已封装好的 Response
-        _ = writeJSON(c, resp)
-    }
+// listen 按地址类型监听；Unix 套接字先清理残留文件，并限制为仅当前用户可访问
+func listen(addr string) (net.Listener, error) {
+	network, address := proto.SplitAddr(addr)
+	if network != "unix" {
+		return net.Listen(network, address)
+	}
+	if err := os.Remove(address); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(address, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// handle 处理单个连接：无 ID 的请求按顺序应答；带 ID 的请求并发处理，
+// 响应带回同一 ID；订阅后事件与响应交错写回同一连接。
+// 未认证的连接收到 401（Auth 中间件拒绝）时立即断开。
+func (s *Server) handle(c net.Conn) {
+	defer c.Close()
+	router := s.Router
+	w := &connWriter{w: c}
+	r := bufio.NewReader(c)
+	peer := &Peer{Addr: c.RemoteAddr().String()}
+	ctx, cancel := context.WithCancel(withPeer(s.ctx, peer))
+	defer cancel()
+
+	var inflight sync.WaitGroup
+	var sub *events.Subscription
+	var fwdDone chan struct{}
+	stopEvents := func() {
+		if sub != nil {
+			sub.Close()
+			<-fwdDone
+			sub = nil
+		}
+	}
+	defer stopEvents()
+	defer inflight.Wait()
+	first := true
+
+	for {
+		limit := proto.MaxFrameSize
+		if !peer.Authenticated() {
+			limit = proto.MaxUnauthFrameSize
+		}
+		line, err := readFrame(r, limit)
+		if errors.Is(err, errFrameTooLong) {
+			log.Printf("frame from %s exceeds %d bytes, closing", c.RemoteAddr(), limit)
+			_ = w.write(proto.Response{Ok: false, Code: proto.CodeBadRequest, Message: err.Error()})
+			return
+		}
+		if err != nil {
+			if err != io.EOF && !s.closing.Load() {
+				log.Printf("read error: %v", err)
+			}
+			return
+		}
+		var msg proto.Message
+		if err := json.Unmarshal(line, &msg); err != nil {
+			log.Printf("json decode error: %v", err)
+			_ = w.write(proto.Response{Ok: false, Code: proto.CodeBadRequest, Message: "invalid json"})
+			continue
+		}
+
+		isFirst := first
+		first = false
+		// 未认证连接收到 401 时写回并断开
+		unauthorized := func(resp proto.Response) bool {
+			return resp.Code == proto.CodeUnauthorized && !peer.Authenticated()
+		}
+
+		if sh, ok := router.stream(msg.Type); ok {
+			// 流式消息：通过中间件准入后连接交由处理器独占，处理结束后关闭
+			if resp := router.admit(ctx, msg); !resp.Ok {
+				_ = w.write(resp)
+				if unauthorized(resp) {
+					log.Printf("unauthorized connection from %s", c.RemoteAddr())
+					return
+				}
+				continue
+			}
+			inflight.Wait()
+			stopEvents()
+			runStream(sh, msg, &Stream{conn: c, r: r, w: w})
+			return
+		}
+
+		if msg.Type == "subscribe" || msg.Type == "unsubscribe" {
+			if resp := router.admit(ctx, msg); !resp.Ok {
+				_ = w.write(resp)
+				if unauthorized(resp) {
+					log.Printf("unauthorized connection from %s", c.RemoteAddr())
+					return
+				}
+				continue
+			}
+			if msg.Type == "unsubscribe" {
+				stopEvents()
+				_ = w.write(proto.Response{ID: msg.ID, Ok: true, Code: proto.CodeOK})
+				continue
+			}
+			var req proto.SubscribeRequest
+			if len(msg.Data) > 0 {
+				// 过滤条件无法解析时拒绝，而不是按空过滤订阅全部事件
+				if err := json.Unmarshal(msg.Data, &req); err != nil {
+					_ = w.write(proto.Response{ID: msg.ID, Ok: false, Code: proto.CodeBadRequest, Message: "invalid subscribe request: " + err.Error()})
+					continue
+				}
+			}
+			if s.Events == nil {
+				_ = w.write(proto.Response{ID: msg.ID, Ok: false, Code: proto.CodeServerError, Message: "events not enabled"})
+				continue
+			}
+			// 重复订阅时以最新的过滤条件为准
+			stopEvents()
+			sub = s.Events.Subscribe(req.Events, 0)
+			fwdDone = make(chan struct{})
+			go forward(sub, w, fwdDone)
+			_ = w.write(proto.Response{ID: msg.ID, Ok: true, Code: proto.CodeOK})
+			continue
+		}
+
+		// 首条消息（通常为 auth）与无 ID 的请求按顺序处理
+		if msg.ID == "" || isFirst {
+			resp := router.Serve(ctx, msg)
+			_ = w.write(resp)
+			if unauthorized(resp) {
+				log.Printf("unauthorized connection from %s", c.RemoteAddr())
+				return
+			}
+			continue
+		}
+		inflight.Add(1)
+		go func(msg proto.Message) {
+			defer inflight.Done()
+			_ = w.write(router.Serve(ctx, msg))
+		}(msg)
+	}
+}
+
+// runStream 执行流处理器，panic 仅结束该流而不影响服务进程
+func runStream(sh StreamHandler, msg proto.Message, s *Stream) {
+	defer func() {
+		if v := recover(); v != nil {
+			log.Printf("panic in stream %s: %v\n%s", msg.Type, v, debug.Stack())
+		}
+	}()
+	if err := sh(msg, s); err != nil {
+		log.Printf("stream %s error: %v", msg.Type, err)
+	}
+}
+
+// forward 将订阅到的事件写回连接，直到订阅关闭
+func forward(sub *events.Subscription, w *connWriter, done chan struct{}) {
+	defer close(done)
+	for ev := range sub.C {
+		if err := w.write(ev); err != nil {
+			// 连接已不可写，继续消费直到订阅关闭，避免阻塞总线
+			continue
+		}
+	}
+}
+
+// connWriter 串行化同一连接上的并发写（响应、事件、流消息）
+type connWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (cw *connWriter) write(v any) error {
+	cw.mu.Lock()
+	defer cw.mu.Unlock()
+	return writeJSON(cw.w, v)
 }
 
 func writeJSON(w io.Writer, v any) error {
-    b, err := json.Marshal(v)
-    if err != nil {
-        return err
-    }
-    b = append(b, '\n')
-    _, err = w.Write(b)
-    return err
-}
\ No newline at end of file
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	_, err = w.Write(b)
+	return err
+}
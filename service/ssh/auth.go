@@ -0,0 +1,356 @@
+package ssh
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// ErrAuthCancelled 表示用户取消了认证输入
+var ErrAuthCancelled = errors.New("authentication cancelled")
+
+// errNotInteractive 表示认证需要用户输入，但请求未声明 Interactive
+var errNotInteractive = errors.New("input required but client is not interactive")
+
+// defaultPromptTimeout 等待前端应答 auth_prompt 的默认时长
+const defaultPromptTimeout = 2 * time.Minute
+
+// maxAuthAttempts 口令与 keyboard-interactive 在交互模式下的最多尝试次数
+const maxAuthAttempts = 3
+
+// Answer 将前端对 auth_prompt 的应答交给等待中的认证流程
+func (m *Manager) Answer(req proto.AuthAnswerRequest) error {
+	m.mu.Lock()
+	ch, ok := m.prompts[req.PromptID]
+	delete(m.prompts, req.PromptID)
+	m.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("%w: unknown or expired prompt %q", ErrInvalid, req.PromptID)
+	}
+	ch <- req
+	return nil
+}
+
+// prompt 推送 auth_prompt 并等待应答；取消、超时或连接被放弃时返回错误
+func (m *Manager) prompt(ctx context.Context, req proto.ConnectRequest, ev proto.AuthPromptEvent) ([]string, error) {
+	if !req.Interactive || m.Publish == nil {
+		return nil, errNotInteractive
+	}
+	ch := make(chan proto.AuthAnswerRequest, 1)
+	m.mu.Lock()
+	m.promptSeq++
+	ev.PromptID = req.ID + "#" + strconv.FormatUint(m.promptSeq, 10)
+	m.prompts[ev.PromptID] = ch
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		delete(m.prompts, ev.PromptID)
+		m.mu.Unlock()
+	}()
+
+	ev.ConnID, ev.Host, ev.User = req.ID, req.Host, req.User
+	// 等待用户输入的时间不计入握手超时
+	defer pauseHandshake(ctx)()
+	m.Publish(proto.EventAuthPrompt, ev)
+
+	timeout := m.PromptTimeout
+	if timeout == 0 {
+		timeout = defaultPromptTimeout
+	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case a := <-ch:
+		if a.Cancel {
+			return nil, ErrAuthCancelled
+		}
+		if len(a.Answers) != len(ev.Prompts) {
+			return nil, fmt.Errorf("%w: expected %d answers, got %d", ErrInvalid, len(ev.Prompts), len(a.Answers))
+		}
+		return a.Answers, nil
+	case <-t.C:
+		return nil, fmt.Errorf("%s prompt timed out after %s", ev.Kind, timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// authChain 一次拨号的认证方式及其附属资源
+type authChain struct {
+	methods []gossh.AuthMethod
+	notes   []string // 被跳过的方式及原因，认证失败时附加到错误中
+	closers []func()
+}
+
+func (a *authChain) note(format string, args ...any) {
+	a.notes = append(a.notes, fmt.Sprintf(format, args...))
+}
+
+func (a *authChain) close() {
+	for _, f := range a.closers {
+		f()
+	}
+}
+
+// wrap 认证失败时附加被跳过的方式，便于定位原因
+func (a *authChain) wrap(err error) error {
+	if err == nil || len(a.notes) == 0 {
+		return err
+	}
+	return fmt.Errorf("%w (%s)", err, strings.Join(a.notes, "; "))
+}
+
+// authOrder 返回要尝试的认证方式：显式指定时校验并去重，否则按可用性推断
+func authOrder(req proto.ConnectRequest) ([]string, error) {
+	if len(req.AuthMethods) == 0 {
+		var order []string
+		if os.Getenv("SSH_AUTH_SOCK") != "" {
+			order = append(order, proto.AuthAgent)
+		}
+		if req.KeyPath != "" {
+			order = append(order, proto.AuthPublicKey)
+		}
+		// keyboard-interactive 常用于密码或二次验证；非交互时只能以密码自动应答
+		if req.Interactive || req.Password != "" {
+			order = append(order, proto.AuthKeyboardInteractive)
+		}
+		if req.Password != "" {
+			order = append(order, proto.AuthPassword)
+		}
+		if len(order) == 0 {
+			return nil, fmt.Errorf("%w: password, keyPath or ssh-agent required", ErrInvalid)
+		}
+		return order, nil
+	}
+	var order []string
+	for _, name := range req.AuthMethods {
+		switch name {
+		case proto.AuthAgent, proto.AuthPublicKey, proto.AuthKeyboardInteractive, proto.AuthPassword:
+		default:
+			return nil, fmt.Errorf("%w: unknown auth method %q", ErrInvalid, name)
+		}
+		if !slices.Contains(order, name) {
+			order = append(order, name)
+		}
+	}
+	return order, nil
+}
+
+// authMethods 按 authOrder 构造认证链。agent 与私钥同属 publickey 方式，
+// 合并为一个方式（ssh 库每种方式只尝试一次），签名者顺序与配置顺序一致。
+func (m *Manager) authMethods(ctx context.Context, req proto.ConnectRequest) (*authChain, error) {
+	order, err := authOrder(req)
+	if err != nil {
+		return nil, err
+	}
+	chain := &authChain{}
+	var key *keySource
+	if slices.Contains(order, proto.AuthPublicKey) {
+		if req.KeyPath == "" {
+			chain.note("publickey: keyPath not set")
+		} else if key, err = loadKey(req); err != nil {
+			return nil, err
+		}
+	}
+
+	publicKeyAdded := false
+	for _, name := range order {
+		switch name {
+		case proto.AuthAgent, proto.AuthPublicKey:
+			if publicKeyAdded {
+				continue
+			}
+			publicKeyAdded = true
+			chain.methods = append(chain.methods, gossh.PublicKeysCallback(func() ([]gossh.Signer, error) {
+				return m.signers(ctx, req, order, key, chain), nil
+			}))
+		case proto.AuthKeyboardInteractive:
+			ki := gossh.KeyboardInteractive(m.keyboardInteractive(ctx, req))
+			if req.Interactive {
+				ki = gossh.RetryableAuthMethod(ki, maxAuthAttempts)
+			}
+			chain.methods = append(chain.methods, ki)
+		case proto.AuthPassword:
+			pw := gossh.PasswordCallback(func() (string, error) {
+				if req.Password != "" {
+					return req.Password, nil
+				}
+				answers, err := m.prompt(ctx, req, proto.AuthPromptEvent{
+					Kind:    proto.PromptPassword,
+					Prompts: []proto.AuthPrompt{{Text: fmt.Sprintf("%s@%s's password: ", req.User, req.Host)}},
+				})
+				if err != nil {
+					return "", err
+				}
+				return answers[0], nil
+			})
+			if req.Password == "" && req.Interactive {
+				pw = gossh.RetryableAuthMethod(pw, maxAuthAttempts)
+			}
+			chain.methods = append(chain.methods, pw)
+		}
+	}
+	return chain, nil
+}
+
+// signers 依配置顺序收集 agent 与私钥的签名者；不可用的来源记入 notes 后跳过
+func (m *Manager) signers(ctx context.Context, req proto.ConnectRequest, order []string, key *keySource, chain *authChain) []gossh.Signer {
+	var out []gossh.Signer
+	for _, name := range order {
+		switch name {
+		case proto.AuthAgent:
+			s, closeAgent, err := agentSigners()
+			if err != nil {
+				chain.note("agent: %v", err)
+				continue
+			}
+			chain.closers = append(chain.closers, closeAgent)
+			out = append(out, s...)
+		case proto.AuthPublicKey:
+			if key == nil {
+				continue
+			}
+			s, err := key.signer(ctx, m, req)
+			if err != nil {
+				chain.note("publickey: %v", err)
+				continue
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// agentSigners 连接 SSH_AUTH_SOCK 指向的 ssh-agent 并取得其全部签名者
+func agentSigners() ([]gossh.Signer, func(), error) {
+	sock := os.Getenv("SSH_AUTH_SOCK")
+	if sock == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK not set")
+	}
+	c, err := net.Dial("unix", sock)
+	if err != nil {
+		return nil, nil, err
+	}
+	signers, err := agent.NewClient(c).Signers()
+	if err != nil {
+		c.Close()
+		return nil, nil, err
+	}
+	return signers, func() { c.Close() }, nil
+}
+
+// keyboardInteractive 逐轮应答服务器问题：单个隐藏问题且提供了密码时以密码自动应答一次，
+// 其余情况经 auth_prompt 询问前端
+func (m *Manager) keyboardInteractive(ctx context.Context, req proto.ConnectRequest) gossh.KeyboardInteractiveChallenge {
+	passwordUsed := false
+	return func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+		if len(questions) == 0 {
+			return nil, nil
+		}
+		if len(questions) == 1 && !echos[0] && req.Password != "" && !passwordUsed {
+			passwordUsed = true
+			return []string{req.Password}, nil
+		}
+		ev := proto.AuthPromptEvent{
+			Kind:        proto.PromptKeyboardInteractive,
+			Name:        name,
+			Instruction: instruction,
+		}
+		for i, q := range questions {
+			ev.Prompts = append(ev.Prompts, proto.AuthPrompt{Text: q, Echo: echos[i]})
+		}
+		answers, err := m.prompt(ctx, req, ev)
+		if err != nil {
+			return nil, fmt.Errorf("keyboard-interactive: %w", err)
+		}
+		return answers, nil
+	}
+}
+
+// keySource 私钥文件，加密且未能用已知口令解开时在首次使用时询问口令
+type keySource struct {
+	path   string
+	pem    []byte
+	parsed gossh.Signer
+	retry  bool // 请求中的口令错误
+}
+
+// loadKey 读取私钥；文件缺失或格式错误立即失败，口令问题推迟到认证时处理
+func loadKey(req proto.ConnectRequest) (*keySource, error) {
+	path := expandHome(req.KeyPath)
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key: %w", err)
+	}
+	k := &keySource{path: path, pem: pem}
+	signer, err := gossh.ParsePrivateKey(pem)
+	var missing *gossh.PassphraseMissingError
+	switch {
+	case err == nil:
+		k.parsed = signer
+	case errors.As(err, &missing):
+		if req.Passphrase == "" {
+			break
+		}
+		signer, err = gossh.ParsePrivateKeyWithPassphrase(pem, []byte(req.Passphrase))
+		if err == nil {
+			k.parsed = signer
+		} else if errors.Is(err, x509.IncorrectPasswordError) {
+			k.retry = true
+		} else {
+			return nil, fmt.Errorf("parse key: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("parse key: %w", err)
+	}
+	return k, nil
+}
+
+// signer 返回私钥签名者，必要时经 auth_prompt 询问口令（最多 maxAuthAttempts 次）
+func (k *keySource) signer(ctx context.Context, m *Manager, req proto.ConnectRequest) (gossh.Signer, error) {
+	if k.parsed != nil {
+		return k.parsed, nil
+	}
+	retry := k.retry
+	for range maxAuthAttempts {
+		answers, err := m.prompt(ctx, req, proto.AuthPromptEvent{
+			Kind:        proto.PromptPassphrase,
+			Instruction: k.path,
+			Prompts:     []proto.AuthPrompt{{Text: fmt.Sprintf("Enter passphrase for key '%s': ", k.path)}},
+			Retry:       retry,
+		})
+		if errors.Is(err, errNotInteractive) {
+			if k.retry {
+				return nil, errors.New("incorrect passphrase")
+			}
+			return nil, errors.New("key is encrypted, passphrase required")
+		}
+		if err != nil {
+			return nil, err
+		}
+		signer, err := gossh.ParsePrivateKeyWithPassphrase(k.pem, []byte(answers[0]))
+		if err == nil {
+			k.parsed = signer
+			return signer, nil
+		}
+		if !errors.Is(err, x509.IncorrectPasswordError) {
+			return nil, err
+		}
+		log.Printf("connect %s: incorrect passphrase for %s", req.ID, k.path)
+		retry = true
+	}
+	return nil, errors.New("incorrect passphrase")
+}
@@ -0,0 +1,212 @@
+package ssh
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// envName 允许的环境变量名
+var envName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Execute 在 req.ConnID 对应的连接上执行一条命令并等待结束。
+// 超时或 ctx 取消时向远端发送 SIGKILL 并关闭会话，已收到的输出照常返回；
+// 仅在命令未能启动时返回 error
+func (m *Manager) Execute(ctx context.Context, req proto.ExecuteRequest) (proto.ExecuteResponse, error) {
+	script, err := executeScript(req)
+	if err != nil {
+		return proto.ExecuteResponse{}, err
+	}
+	timeout := time.Duration(req.TimeoutMs) * time.Millisecond
+	switch {
+	case req.TimeoutMs <= 0:
+		timeout = proto.DefaultExecuteTimeout
+	case timeout > proto.MaxExecuteTimeout:
+		timeout = proto.MaxExecuteTimeout
+	}
+	limit := req.MaxOutput
+	switch {
+	case limit <= 0:
+		limit = proto.DefaultExecuteOutput
+	case limit > proto.MaxExecuteOutput:
+		limit = proto.MaxExecuteOutput
+	}
+
+	client, err := m.Client(req.ConnID)
+	if err != nil {
+		return proto.ExecuteResponse{}, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return proto.ExecuteResponse{}, fmt.Errorf("new session: %w", err)
+	}
+	untrack := m.track(session)
+	defer untrack()
+	defer session.Close()
+
+	stdout, stderr := newCapBuffer(limit), newCapBuffer(limit)
+	session.Stdout, session.Stderr = stdout, stderr
+	if req.Stdin != "" {
+		session.Stdin = strings.NewReader(req.Stdin)
+	}
+	if req.PTY {
+		modes := gossh.TerminalModes{gossh.ECHO: 0, gossh.TTY_OP_ISPEED: 14400, gossh.TTY_OP_OSPEED: 14400}
+		if err := session.RequestPty("xterm-256color", 24, 80, modes); err != nil {
+			return proto.ExecuteResponse{}, fmt.Errorf("request pty: %w", err)
+		}
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	if err := session.Start(script); err != nil {
+		return proto.ExecuteResponse{}, fmt.Errorf("start: %w", err)
+	}
+	stop := context.AfterFunc(runCtx, func() {
+		_ = session.Signal(gossh.SIGKILL)
+		session.Close()
+	})
+	defer stop()
+	err = session.Wait()
+
+	resp := proto.ExecuteResponse{DurationMs: time.Since(start).Milliseconds()}
+	resp.Stdout, resp.StdoutEncoding, resp.StdoutBytes, resp.StdoutTruncated = stdout.result()
+	resp.Stderr, resp.StderrEncoding, resp.StderrBytes, resp.StderrTruncated = stderr.result()
+	var exitErr *gossh.ExitError
+	switch {
+	case errors.Is(runCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+		resp.ExitCode, resp.TimedOut = -1, true
+		resp.Error = fmt.Sprintf("timed out after %s", timeout)
+	case ctx.Err() != nil:
+		resp.ExitCode, resp.Error = -1, ctx.Err().Error()
+	case err == nil:
+	case errors.As(err, &exitErr):
+		resp.ExitCode, resp.Signal = exitErr.ExitStatus(), exitErr.Signal()
+		if resp.Signal != "" {
+			resp.ExitCode = -1
+		}
+	default:
+		// 没有退出状态：连接断开或远端未报告
+		resp.ExitCode, resp.Error = -1, err.Error()
+	}
+	return resp, nil
+}
+
+// executeScript 将环境变量与工作目录拼接到命令之前
+func executeScript(req proto.ExecuteRequest) (string, error) {
+	if strings.TrimSpace(req.Command) == "" {
+		return "", fmt.Errorf("%w: command is required", ErrInvalid)
+	}
+	var b strings.Builder
+	if len(req.Env) > 0 {
+		names := make([]string, 0, len(req.Env))
+		for k := range req.Env {
+			if !envName.MatchString(k) {
+				return "", fmt.Errorf("%w: invalid environment variable name %q", ErrInvalid, k)
+			}
+			names = append(names, k)
+		}
+		slices.Sort(names)
+		b.WriteString("export")
+		for _, k := range names {
+			b.WriteString(" " + k + "=" + shellQuote(req.Env[k]))
+		}
+		b.WriteString("\n")
+	}
+	if req.Dir != "" {
+		dir := shellQuote(req.Dir)
+		if req.Dir == "~" {
+			dir = `"$HOME"`
+		} else if rest, ok := strings.CutPrefix(req.Dir, "~/"); ok {
+			dir = `"$HOME"/` + shellQuote(rest)
+		}
+		// 目录不存在时以 126 退出，与 shell 无法执行命令时一致
+		b.WriteString("cd -- " + dir + " || exit 126\n")
+	}
+	b.WriteString(req.Command)
+	return b.String(), nil
+}
+
+// capBuffer 保存输出的开头与结尾各 limit/2 字节，中间部分只计数
+type capBuffer struct {
+	head, tail []byte
+	half       int
+	total      int64
+}
+
+func newCapBuffer(limit int) *capBuffer {
+	return &capBuffer{half: max(limit/2, 1)}
+}
+
+func (c *capBuffer) Write(p []byte) (int, error) {
+	c.total += int64(len(p))
+	rest := p
+	if n := c.half - len(c.head); n > 0 {
+		n = min(n, len(rest))
+		c.head = append(c.head, rest[:n]...)
+		rest = rest[n:]
+	}
+	if len(rest) == 0 {
+		return len(p), nil
+	}
+	c.tail = append(c.tail, rest...)
+	// 超过两倍时才整体前移，摊还复制成本
+	if len(c.tail) > 2*c.half {
+		c.tail = append(c.tail[:0], c.tail[len(c.tail)-c.half:]...)
+	}
+	return len(p), nil
+}
+
+// result 返回保留的文本、其编码、实际字节数与是否截断。截断点落在 UTF-8 字符
+// 中间时后移到字符边界，保留的内容仍不是合法 UTF-8（二进制输出）时以 base64 返回
+func (c *capBuffer) result() (string, string, int64, bool) {
+	head, tail := c.head, c.tail
+	if len(tail) > c.half {
+		tail = tail[len(tail)-c.half:]
+	}
+	var out []byte
+	truncated := c.total > int64(len(head)+len(tail))
+	if truncated {
+		head, tail = trimRuneEnd(head), trimRuneStart(tail)
+		omitted := c.total - int64(len(head)+len(tail))
+		out = fmt.Appendf(nil, "%s\n... [truncated %d bytes] ...\n%s", head, omitted, tail)
+	} else {
+		out = append(slices.Clip(head), tail...)
+	}
+	if !utf8.Valid(out) {
+		return base64.StdEncoding.EncodeToString(out), proto.EncodingBase64, c.total, truncated
+	}
+	return string(out), "", c.total, truncated
+}
+
+// trimRuneEnd 去掉 b 末尾不完整的 UTF-8 字符
+func trimRuneEnd(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+// trimRuneStart 去掉 b 开头被截断字符的剩余字节（至多 UTFMax-1 个续字节）
+func trimRuneStart(b []byte) []byte {
+	n := 0
+	for n < len(b) && n < utf8.UTFMax-1 && !utf8.RuneStart(b[n]) {
+		n++
+	}
+	return b[n:]
+}
@@ -0,0 +1,56 @@
+package ssh
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"testing"
+
+	"go-ssh/proto"
+)
+
+func TestCapBuffer(t *testing.T) {
+	marker := func(n int) string { return "\n... [truncated " + strconv.Itoa(n) + " bytes] ...\n" }
+	tests := []struct {
+		name      string
+		limit     int
+		writes    []string
+		want      string
+		encoding  string
+		truncated bool
+	}{
+		{"empty", 8, nil, "", "", false},
+		{"fits", 8, []string{"abcdefgh"}, "abcdefgh", "", false},
+		{"head and tail", 8, []string{"0123456789"}, "0123" + marker(2) + "6789", "", true},
+		{"across writes", 8, []string{"01", "23", "45", "67", "89", "ab"}, "0123" + marker(4) + "89ab", "", true},
+		{"tail keeps latest", 4, []string{strings.Repeat("x", 100), "yz"}, "xx" + marker(98) + "yz", "", true},
+		// "é" 为 2 字节：开头的 3 字节截在第一个 é 中间，回退到字符边界
+		{"head cut inside rune", 6, []string{"aaéé" + strings.Repeat("-", 10) + "bbb"}, "aa" + marker(14) + "bbb", "", true},
+		// 结尾保留的第一个字节是续字节，后移到下一个字符
+		{"tail cut inside rune", 8, []string{"abcd" + strings.Repeat("-", 10) + "é世"}, "abcd" + marker(12) + "世", "", true},
+		{"multibyte fits", 8, []string{"世界"}, "世界", "", false},
+		{"binary", 8, []string{"a\xffb"}, base64.StdEncoding.EncodeToString([]byte("a\xffb")), proto.EncodingBase64, false},
+		{"binary truncated", 4, []string{"\xff\xfe" + strings.Repeat("-", 10) + "ok"},
+			base64.StdEncoding.EncodeToString([]byte("\xff\xfe" + marker(10) + "ok")), proto.EncodingBase64, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCapBuffer(tt.limit)
+			var total int64
+			for _, w := range tt.writes {
+				n, err := c.Write([]byte(w))
+				if err != nil || n != len(w) {
+					t.Fatalf("Write = %d, %v", n, err)
+				}
+				total += int64(len(w))
+			}
+			got, encoding, n, truncated := c.result()
+			if got != tt.want || encoding != tt.encoding || truncated != tt.truncated {
+				t.Fatalf("result = %q, %q, truncated %v; want %q, %q, truncated %v", got, encoding, truncated, tt.want, tt.encoding, tt.truncated)
+			}
+			if n != total {
+				t.Fatalf("bytes = %d, want %d", n, total)
+			}
+		})
+	}
+}
@@ -0,0 +1,634 @@
+package ssh
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+	"go-ssh/service/server"
+	"go-ssh/service/vault"
+)
+
+// Register 注册连接管理与交互式 shell 相关的消息处理器
+func (m *Manager) Register(r *server.Router) {
+	r.Handle("connect", m.handleConnect)
+	r.Handle("disconnect", m.handleDisconnect)
+	r.Handle("list_connections", m.handleList)
+	r.Handle("auth_answer", m.handleAuthAnswer)
+	r.Handle("install_public_key", m.handleInstallKey)
+	r.Handle("create_tunnel", m.handleCreateTunnel)
+	r.Handle("list_tunnels", m.handleListTunnels)
+	r.Handle("close_tunnel", m.handleCloseTunnel)
+	r.Handle("sftp_list", m.handleSFTPList)
+	r.Handle("sftp_stat", m.handleSFTPStat)
+	r.Handle("sftp_mkdir", m.handleSFTPMkdir)
+	r.Handle("sftp_rename", m.handleSFTPRename)
+	r.Handle("sftp_remove", m.handleSFTPRemove)
+	r.Handle("sftp_chmod", m.handleSFTPChmod)
+	r.Handle("sftp_symlink", m.handleSFTPSymlink)
+	r.Handle("start_transfer", m.handleStartTransfer)
+	r.Handle("list_transfers", m.handleListTransfers)
+	r.Handle("cancel_transfer", m.handleCancelTransfer)
+	r.Handle("list_processes", m.handleListProcesses)
+	r.Handle("signal_process", m.handleSignalProcess)
+	r.Handle("renice_process", m.handleReniceProcess)
+	r.Handle("execute", m.handleExecute)
+	r.Handle("multi_execute", m.handleMultiExecute)
+	r.Handle("cancel_multi_execute", m.handleCancelMultiExecute)
+	r.Handle("list_recordings", m.handleListRecordings)
+	r.Handle("get_recording", m.handleGetRecording)
+	r.HandleStream("open_shell", m.handleShell)
+	r.HandleStream("monitor_subscribe", m.handleMonitor)
+}
+
+func (m *Manager) handleConnect(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ConnectRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	st, err := m.Connect(ctx, req)
+	if errors.Is(err, ErrInvalid) {
+		return server.BadRequest(err), nil
+	}
+	if errors.Is(err, vault.ErrLocked) {
+		return vault.LockedResponse(err), nil
+	}
+	if errors.Is(err, ErrHostKey) {
+		// 已推送 host_key_unknown / host_key_changed，前端确认后重试
+		return proto.Response{Ok: false, Code: proto.CodeHostKey, Message: err.Error(), Data: st}, nil
+	}
+	if err != nil {
+		// 失败时仍携带状态，便于前端展示 failed
+		return proto.Response{Ok: false, Code: proto.CodeConnectFailed, Message: err.Error(), Data: st}, nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: st}, nil
+}
+
+func (m *Manager) handleAuthAnswer(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.AuthAnswerRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	if err := m.Answer(req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK}, nil
+}
+
+func (m *Manager) handleInstallKey(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.InstallKeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	added, err := m.InstallKey(ctx, req)
+	if errors.Is(err, ErrInvalid) || errors.Is(err, ErrKeyNotFound) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		return proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()}, nil
+	}
+	log.Printf("install key %s on %s: added=%v", req.Name, req.ConnID, added)
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.InstallKeyResponse{Installed: added}}, nil
+}
+
+func (m *Manager) handleCreateTunnel(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.TunnelRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := m.OpenTunnel(req)
+	if errors.Is(err, ErrInvalid) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		// 端口被占用、远端拒绝 tcpip-forward 等
+		return proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()}, nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: info}, nil
+}
+
+func (m *Manager) handleListTunnels(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ListTunnelsRequest
+	if len(msg.Data) > 0 {
+		if err := server.Decode(msg, &req); err != nil {
+			return server.BadRequest(err), nil
+		}
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListTunnelsResponse{Tunnels: m.Tunnels(req.ConnID)}}, nil
+}
+
+func (m *Manager) handleCloseTunnel(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.TunnelIDRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := m.CloseTunnel(req.ID)
+	if err != nil {
+		return server.BadRequest(err), nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: info}, nil
+}
+
+// sftpResponse 将 SFTP 操作结果转换为响应：参数、连接状态与文件不存在/已存在/无权限为 400，
+// 其余（子系统不可用、远端 I/O 失败等）为 500
+func sftpResponse(data any, err error) proto.Response {
+	if errors.Is(err, ErrInvalid) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) || isFileError(err) {
+		return server.BadRequest(err)
+	}
+	if err != nil {
+		return proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()}
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: data}
+}
+
+func (m *Manager) handleSFTPList(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPPathRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return sftpResponse(m.ListDir(ctx, req)), nil
+}
+
+func (m *Manager) handleSFTPStat(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPPathRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return sftpResponse(m.Stat(req)), nil
+}
+
+func (m *Manager) handleSFTPMkdir(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPMkdirRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return sftpResponse(m.Mkdir(req)), nil
+}
+
+func (m *Manager) handleSFTPRename(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPRenameRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return sftpResponse(m.Rename(req)), nil
+}
+
+func (m *Manager) handleSFTPRemove(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPRemoveRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	err := m.Remove(ctx, req)
+	if err == nil {
+		log.Printf("sftp remove %s on %s (recursive=%v)", req.Path, req.ConnID, req.Recursive)
+	}
+	return sftpResponse(nil, err), nil
+}
+
+func (m *Manager) handleSFTPChmod(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPChmodRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return sftpResponse(m.Chmod(req)), nil
+}
+
+func (m *Manager) handleSFTPSymlink(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SFTPSymlinkRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return sftpResponse(m.Symlink(req)), nil
+}
+
+func (m *Manager) handleStartTransfer(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.TransferRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := m.StartTransfer(req)
+	if errors.Is(err, ErrInvalid) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		// 远端未提供 sftp 子系统、无法解析主目录等
+		return proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()}, nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: info}, nil
+}
+
+func (m *Manager) handleListTransfers(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ListTransfersRequest
+	if len(msg.Data) > 0 {
+		if err := server.Decode(msg, &req); err != nil {
+			return server.BadRequest(err), nil
+		}
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListTransfersResponse{Transfers: m.Transfers(req.ConnID)}}, nil
+}
+
+func (m *Manager) handleCancelTransfer(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.TransferIDRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := m.CancelTransfer(req.ID)
+	if err != nil {
+		return server.BadRequest(err), nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: info}, nil
+}
+
+func processResponse(data any, err error) proto.Response {
+	if errors.Is(err, ErrInvalid) || errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) {
+		return server.BadRequest(err)
+	}
+	if err != nil {
+		// 远端缺少 ps / renice、会话创建失败、命令无法启动等
+		return proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()}
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: data}
+}
+
+func (m *Manager) handleListProcesses(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ListProcessesRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	return processResponse(m.ListProcesses(ctx, req)), nil
+}
+
+func (m *Manager) handleSignalProcess(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.SignalProcessRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	resp, err := m.SignalProcesses(ctx, req)
+	if err == nil {
+		log.Printf("signal %s %v on %s", req.Signal, req.PIDs, req.ConnID)
+	}
+	return processResponse(resp, err), nil
+}
+
+func (m *Manager) handleReniceProcess(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ReniceRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	resp, err := m.ReniceProcesses(ctx, req)
+	if err == nil {
+		log.Printf("renice %d %v on %s", req.Nice, req.PIDs, req.ConnID)
+	}
+	return processResponse(resp, err), nil
+}
+
+func (m *Manager) handleExecute(ctx context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ExecuteRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	resp, err := m.Execute(ctx, req)
+	if err == nil {
+		log.Printf("execute on %s: exit=%d signal=%q %dms", req.ConnID, resp.ExitCode, resp.Signal, resp.DurationMs)
+	}
+	return processResponse(resp, err), nil
+}
+
+func (m *Manager) handleMultiExecute(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.MultiExecuteRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	resp, err := m.MultiExecute(req)
+	if err == nil {
+		log.Printf("multi_execute %s on %d hosts", resp.ID, len(resp.Hosts))
+	}
+	return processResponse(resp, err), nil
+}
+
+func (m *Manager) handleCancelMultiExecute(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.CancelMultiExecuteRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	resp, err := m.CancelMultiExecute(req.ID)
+	if err == nil {
+		log.Printf("cancel multi_execute %s", req.ID)
+	}
+	return processResponse(resp, err), nil
+}
+
+func (m *Manager) handleListRecordings(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ListRecordingsRequest
+	if len(msg.Data) > 0 {
+		if err := server.Decode(msg, &req); err != nil {
+			return server.BadRequest(err), nil
+		}
+	}
+	list, err := m.Recordings.List(req.ConnID)
+	return processResponse(proto.ListRecordingsResponse{Recordings: list}, err), nil
+}
+
+func (m *Manager) handleGetRecording(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.GetRecordingRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	resp, err := m.Recordings.Read(req)
+	return processResponse(resp, err), nil
+}
+
+func (m *Manager) handleDisconnect(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.DisconnectRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	st, err := m.Disconnect(req.ID)
+	if err != nil {
+		return server.BadRequest(err), nil
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: st}, nil
+}
+
+func (m *Manager) handleList(context.Context, proto.Message) (proto.Response, error) {
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListConnectionsResponse{Connections: m.List()}}, nil
+}
+
+// handleShell 处理 open_shell 会话流：回复会话 ID 后双向转发数据，
+// 远端 shell 退出时发送 exit 并结束；前端断开时关闭远端会话。
+func (m *Manager) handleShell(msg proto.Message, s *server.Stream) error {
+	var req proto.OpenShellRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return s.Reply(server.BadRequest(err))
+	}
+	sh, err := m.OpenShell(req)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) {
+		return s.Reply(server.BadRequest(err))
+	}
+	if err != nil {
+		return s.Reply(proto.Response{Ok: false, Code: proto.CodeServerError, Message: err.Error()})
+	}
+	defer sh.Close()
+	if err := s.Reply(proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.OpenShellResponse{SessionID: sh.ID}}); err != nil {
+		return err
+	}
+	log.Printf("shell %s opened", sh.ID)
+	st := m.status(sh.ConnID)
+	host := fmt.Sprintf("%s@%s:%d", st.User, st.Host, st.Port)
+	rec, err := m.Recordings.Start(sh, host, cmp.Or(req.Term, "xterm-256color"), cmp.Or(req.Cols, 80), cmp.Or(req.Rows, 24))
+	if err != nil {
+		// 录像失败不影响会话
+		log.Printf("shell %s: start recording: %v", sh.ID, err)
+	}
+	defer rec.Close()
+	textLog, err := m.openSessionLog(sh, host)
+	if err != nil {
+		log.Printf("shell %s: open session log: %v", sh.ID, err)
+	}
+
+	// 远端输出 -> 前端
+	var wg sync.WaitGroup
+	pump := func(r io.Reader, msgType string) {
+		defer wg.Done()
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := r.Read(buf)
+			if n > 0 {
+				rec.Output(buf[:n])
+				textLog.Write(buf[:n])
+				if werr := s.Send(msgType, proto.StreamChunk{Data: buf[:n]}); werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	wg.Add(2)
+	go pump(sh.Stdout, proto.StreamStdout)
+	go pump(sh.Stderr, proto.StreamStderr)
+
+	// 前端输入 -> 远端
+	go func() {
+		for {
+			in, err := s.Recv()
+			if err != nil {
+				_ = sh.Close()
+				return
+			}
+			switch in.Type {
+			case proto.StreamStdin:
+				var chunk proto.StreamChunk
+				if server.Decode(in, &chunk) == nil {
+					rec.Input(chunk.Data)
+					_, _ = sh.Stdin.Write(chunk.Data)
+				}
+			case proto.StreamResize:
+				var rs proto.ResizeRequest
+				if server.Decode(in, &rs) == nil && sh.Resize(rs.Cols, rs.Rows) == nil {
+					rec.Resize(rs.Cols, rs.Rows)
+				}
+			case proto.StreamClose:
+				_ = sh.Close()
+				return
+			}
+		}
+	}()
+
+	exit := sh.Wait()
+	wg.Wait()
+	log.Printf("shell %s exited: code=%d", sh.ID, exit.Code)
+	textLog.Close(fmt.Sprintf("exit code %d", exit.Code))
+	if m.Publish != nil {
+		m.Publish(proto.EventSessionExit, proto.SessionExitEvent{SessionID: sh.ID, ConnID: sh.ConnID, Status: exit})
+	}
+	return s.Send(proto.StreamExit, exit)
+}
+
+// Register 注册 known_hosts 管理消息处理器
+func (k *KnownHosts) Register(r *server.Router) {
+	r.Handle("list_host_keys", k.handleList)
+	r.Handle("trust_host_key", k.handleTrust)
+	r.Handle("remove_host_key", k.handleRemove)
+}
+
+func (k *KnownHosts) handleList(context.Context, proto.Message) (proto.Response, error) {
+	keys, err := k.List()
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListHostKeysResponse{Keys: keys}}, nil
+}
+
+func (k *KnownHosts) handleTrust(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.TrustHostKeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	e, err := k.Trust(req)
+	if errors.Is(err, ErrInvalid) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		return proto.Response{}, err
+	}
+	log.Printf("trusted host key %s for %s:%d", e.Fingerprint, req.Host, req.Port)
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: e}, nil
+}
+
+func (k *KnownHosts) handleRemove(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.RemoveHostKeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	n, err := k.Remove(req)
+	if errors.Is(err, ErrInvalid) {
+		return server.BadRequest(err), nil
+	}
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.RemoveHostKeyResponse{Removed: n}}, nil
+}
+
+// Register 注册受管密钥的生成、导入、列举、导出与删除处理器
+func (k *KeyStore) Register(r *server.Router) {
+	r.Handle("generate_key", k.handleGenerate)
+	r.Handle("import_key", k.handleImport)
+	r.Handle("list_keys", k.handleList)
+	r.Handle("export_public_key", k.handleExport)
+	r.Handle("delete_key", k.handleDelete)
+}
+
+// keyError 将密钥目录错误映射为响应：参数错误与密钥不存在为 400
+func keyError(err error) (proto.Response, error) {
+	if errors.Is(err, ErrInvalid) || errors.Is(err, ErrKeyNotFound) {
+		return server.BadRequest(err), nil
+	}
+	return proto.Response{}, err
+}
+
+func (k *KeyStore) handleGenerate(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.GenerateKeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := k.Generate(req)
+	if err != nil {
+		return keyError(err)
+	}
+	log.Printf("generated key %s (%s %d) %s", info.Name, info.Type, info.Bits, info.Fingerprint)
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: info}, nil
+}
+
+func (k *KeyStore) handleImport(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.ImportKeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := k.Import(req)
+	if err != nil {
+		return keyError(err)
+	}
+	log.Printf("imported key %s (%s) %s", info.Name, info.Type, info.Fingerprint)
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: info}, nil
+}
+
+func (k *KeyStore) handleList(context.Context, proto.Message) (proto.Response, error) {
+	keys, err := k.List()
+	if err != nil {
+		return proto.Response{}, err
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.ListKeysResponse{Keys: keys}}, nil
+}
+
+func (k *KeyStore) handleExport(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.KeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	info, err := k.Get(req.Name)
+	if err != nil {
+		return keyError(err)
+	}
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.PublicKeyResponse{PublicKey: info.PublicKey}}, nil
+}
+
+func (k *KeyStore) handleDelete(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.KeyRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	if err := k.Delete(req.Name); err != nil {
+		return keyError(err)
+	}
+	log.Printf("deleted key %s", req.Name)
+	return proto.Response{Ok: true, Code: proto.CodeOK}, nil
+}
+
+// handleMonitor 处理 monitor_subscribe 会话流：回复实际间隔后立即采样一次，之后按间隔推送 sample
+// （同时发布 monitor_sample 事件）。单次采样失败时推送带 error 的采样并继续；
+// 连接断开或不存在时发送 exit 并结束；前端发送 close 或断开时停止。
+func (m *Manager) handleMonitor(msg proto.Message, s *server.Stream) error {
+	var req proto.MonitorRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return s.Reply(server.BadRequest(err))
+	}
+	mon, err := m.NewMonitor(req.ConnID)
+	if err != nil {
+		return s.Reply(server.BadRequest(err))
+	}
+	interval := monitorInterval(req.IntervalMs)
+	if err := s.Reply(proto.Response{Ok: true, Code: proto.CodeOK, Data: proto.MonitorResponse{ConnID: req.ConnID, IntervalMs: int(interval.Milliseconds())}}); err != nil {
+		return err
+	}
+	log.Printf("monitor %s started: every %s", req.ConnID, interval)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			in, err := s.Recv()
+			if err != nil || in.Type == proto.StreamClose {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		sample, err := mon.Sample(ctx)
+		if ctx.Err() != nil {
+			log.Printf("monitor %s stopped", req.ConnID)
+			return nil
+		}
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrNotConnected) {
+			log.Printf("monitor %s ended: %v", req.ConnID, err)
+			return s.Send(proto.StreamExit, proto.ExitStatus{Code: -1, Error: err.Error()})
+		}
+		if err != nil {
+			sample = proto.MonitorSample{ConnID: req.ConnID, Time: time.Now().UTC(), Error: err.Error()}
+		}
+		if err := s.Send(proto.StreamSample, sample); err != nil {
+			return nil
+		}
+		if m.Publish != nil {
+			m.Publish(proto.EventMonitorSample, sample)
+		}
+		select {
+		case <-ctx.Done():
+			log.Printf("monitor %s stopped", req.ConnID)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
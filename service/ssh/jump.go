@@ -0,0 +1,158 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// HopError 标明经跳板机连接时失败的一跳，Unwrap 保留原始错误（如 ErrHostKey）
+type HopError struct {
+	Hop   int // 从 1 开始；等于 Total 时为目标主机
+	Total int
+	Host  string
+	Port  int
+	User  string
+	Err   error
+}
+
+func (e *HopError) Error() string {
+	role := fmt.Sprintf("jump %d/%d", e.Hop, e.Total-1)
+	if e.Hop == e.Total {
+		role = "target"
+	}
+	return fmt.Sprintf("%s %s@%s: %v", role, e.User, net.JoinHostPort(e.Host, strconv.Itoa(e.Port)), e.Err)
+}
+
+func (e *HopError) Unwrap() error { return e.Err }
+
+// hops 将跳板机与目标主机展开为逐跳的拨号参数；跳板机沿用连接 ID 与交互设置，
+// 认证提示与主机密钥事件因此归属同一连接，并带上该跳的主机名
+func hops(req proto.ConnectRequest) []proto.ConnectRequest {
+	out := make([]proto.ConnectRequest, 0, len(req.Jumps)+1)
+	for _, j := range req.Jumps {
+		out = append(out, proto.ConnectRequest{
+			ID:          req.ID,
+			Host:        j.Host,
+			Port:        j.Port,
+			User:        j.User,
+			Password:    j.Password,
+			KeyPath:     j.KeyPath,
+			Passphrase:  j.Passphrase,
+			AuthMethods: j.AuthMethods,
+			Interactive: req.Interactive,
+		})
+	}
+	target := req
+	target.Jumps = nil
+	return append(out, target)
+}
+
+// normalizeJumps 校验跳板机并填充默认端口与用户名
+func normalizeJumps(req *proto.ConnectRequest) error {
+	if len(req.Jumps) > proto.MaxJumps {
+		return fmt.Errorf("%w: at most %d jump hosts", ErrInvalid, proto.MaxJumps)
+	}
+	for i := range req.Jumps {
+		j := &req.Jumps[i]
+		j.Host = strings.TrimSpace(j.Host)
+		j.User = strings.TrimSpace(j.User)
+		if j.Host == "" {
+			return fmt.Errorf("%w: jump %d: host is required", ErrInvalid, i+1)
+		}
+		if j.User == "" {
+			j.User = req.User
+		}
+		if j.Port == 0 {
+			j.Port = 22
+		}
+		if j.Port < 0 || j.Port > 65535 {
+			return fmt.Errorf("%w: jump %d: port %d out of range", ErrInvalid, i+1, j.Port)
+		}
+	}
+	return nil
+}
+
+// newChain 生成跳板链的初始状态；没有跳板机时返回 nil
+func newChain(req proto.ConnectRequest) []proto.HopStatus {
+	if len(req.Jumps) == 0 {
+		return nil
+	}
+	var chain []proto.HopStatus
+	for _, h := range hops(req) {
+		chain = append(chain, proto.HopStatus{Host: h.Host, Port: h.Port, User: h.User})
+	}
+	return chain
+}
+
+// setHop 更新跳板链中第 i 跳的状态并发布连接状态
+func (m *Manager) setHop(c *conn, i int, state string, err error) {
+	m.mu.Lock()
+	if c.chain == nil {
+		m.mu.Unlock()
+		return
+	}
+	c.chain[i].State = state
+	c.chain[i].Error = ""
+	if err != nil {
+		c.chain[i].Error = err.Error()
+	}
+	current := m.conns[c.req.ID] == c
+	m.mu.Unlock()
+	if current {
+		m.notify(c.req.ID)
+	}
+}
+
+// dial 依次拨号跳板机与目标主机，每一跳经上一跳的连接转发 TCP；
+// 目标连接结束时关闭全部跳板连接。ctx 取消时中止等待中的认证输入。
+func (m *Manager) dial(ctx context.Context, c *conn) (*gossh.Client, error) {
+	hs := hops(c.req)
+	var via *gossh.Client
+	var jumps []*gossh.Client
+	closeJumps := func() {
+		for i := len(jumps) - 1; i >= 0; i-- {
+			_ = jumps[i].Close()
+		}
+	}
+	for i, h := range hs {
+		m.setHop(c, i, proto.StateConnecting, nil)
+		client, err := m.dialHop(ctx, h, via)
+		if err != nil {
+			m.setHop(c, i, proto.StateFailed, err)
+			closeJumps()
+			if len(hs) == 1 {
+				return nil, err
+			}
+			return nil, &HopError{Hop: i + 1, Total: len(hs), Host: h.Host, Port: h.Port, User: h.User, Err: err}
+		}
+		m.setHop(c, i, proto.StateConnected, nil)
+		if i < len(hs)-1 {
+			jumps = append(jumps, client)
+		}
+		via = client
+	}
+	if len(jumps) > 0 {
+		go func() {
+			_ = via.Wait()
+			closeJumps()
+		}()
+	}
+	return via, nil
+}
+
+// sameJumps 判断两条跳板链是否一致
+func sameJumps(a, b []proto.JumpHost) bool {
+	return slices.EqualFunc(a, b, func(x, y proto.JumpHost) bool {
+		return x.Host == y.Host && x.Port == y.Port && x.User == y.User &&
+			x.Password == y.Password && x.KeyPath == y.KeyPath &&
+			x.Passphrase == y.Passphrase && slices.Equal(x.AuthMethods, y.AuthMethods)
+	})
+}
@@ -0,0 +1,368 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ErrKeyNotFound 表示受管密钥不存在
+var ErrKeyNotFound = errors.New("key not found")
+
+// keyNamePattern 密钥名即文件名，只允许常见的安全字符
+var keyNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// KeyStore 管理密钥目录：每把密钥为私钥文件 <name>（0600）与公钥文件 <name>.pub，
+// 元数据均从文件解析，不另存索引。
+type KeyStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewKeyStore 以 dir 为受管密钥目录
+func NewKeyStore(dir string) *KeyStore {
+	return &KeyStore{Dir: dir}
+}
+
+// Generate 生成密钥对并写入密钥目录
+func (k *KeyStore) Generate(req proto.GenerateKeyRequest) (proto.KeyInfo, error) {
+	if err := checkKeyName(req.Name); err != nil {
+		return proto.KeyInfo{}, err
+	}
+	var priv crypto.Signer
+	var err error
+	switch req.Type {
+	case proto.KeyTypeEd25519, "":
+		_, priv, err = ed25519.GenerateKey(rand.Reader)
+	case proto.KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch req.Bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return proto.KeyInfo{}, fmt.Errorf("%w: ecdsa bits must be 256, 384 or 521", ErrInvalid)
+		}
+		priv, err = ecdsa.GenerateKey(curve, rand.Reader)
+	case proto.KeyTypeRSA:
+		bits := req.Bits
+		if bits == 0 {
+			bits = 3072
+		}
+		if bits < 2048 || bits > 8192 {
+			return proto.KeyInfo{}, fmt.Errorf("%w: rsa bits must be between 2048 and 8192", ErrInvalid)
+		}
+		priv, err = rsa.GenerateKey(rand.Reader, bits)
+	default:
+		return proto.KeyInfo{}, fmt.Errorf("%w: unknown key type %q", ErrInvalid, req.Type)
+	}
+	if err != nil {
+		return proto.KeyInfo{}, err
+	}
+	pub, err := gossh.NewPublicKey(priv.Public())
+	if err != nil {
+		return proto.KeyInfo{}, err
+	}
+	var block *pem.Block
+	if req.Passphrase != "" {
+		block, err = gossh.MarshalPrivateKeyWithPassphrase(priv, req.Comment, []byte(req.Passphrase))
+	} else {
+		block, err = gossh.MarshalPrivateKey(priv, req.Comment)
+	}
+	if err != nil {
+		return proto.KeyInfo{}, err
+	}
+	if err := k.write(req.Name, pem.EncodeToMemory(block), pub, req.Comment); err != nil {
+		return proto.KeyInfo{}, err
+	}
+	return k.Get(req.Name)
+}
+
+// Import 导入已有私钥，按原样保存（加密私钥仍保持加密）
+func (k *KeyStore) Import(req proto.ImportKeyRequest) (proto.KeyInfo, error) {
+	if err := checkKeyName(req.Name); err != nil {
+		return proto.KeyInfo{}, err
+	}
+	data := []byte(req.PrivateKey)
+	switch {
+	case req.PrivateKey != "" && req.Path != "":
+		return proto.KeyInfo{}, fmt.Errorf("%w: privateKey and path are mutually exclusive", ErrInvalid)
+	case req.Path != "":
+		b, err := os.ReadFile(expandHome(req.Path))
+		if err != nil {
+			return proto.KeyInfo{}, fmt.Errorf("%w: read key: %v", ErrInvalid, err)
+		}
+		data = b
+	case req.PrivateKey == "":
+		return proto.KeyInfo{}, fmt.Errorf("%w: privateKey or path is required", ErrInvalid)
+	}
+	pub, err := publicKeyOf(data, req.Passphrase)
+	if err != nil {
+		return proto.KeyInfo{}, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	comment := ""
+	if req.Path != "" {
+		// 私钥中不一定带注释，沿用同目录 .pub 文件中的注释
+		if b, err := os.ReadFile(expandHome(req.Path) + ".pub"); err == nil {
+			if p, c, _, _, err := gossh.ParseAuthorizedKey(b); err == nil && bytes.Equal(p.Marshal(), pub.Marshal()) {
+				comment = c
+			}
+		}
+	}
+	if err := k.write(req.Name, data, pub, comment); err != nil {
+		return proto.KeyInfo{}, err
+	}
+	return k.Get(req.Name)
+}
+
+// List 返回全部受管密钥；无法解析的文件被忽略
+func (k *KeyStore) List() ([]proto.KeyInfo, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	entries, err := os.ReadDir(k.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []proto.KeyInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []proto.KeyInfo{}
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".pub")
+		if !ok || e.IsDir() || !keyNamePattern.MatchString(name) {
+			continue
+		}
+		if info, err := k.info(name); err == nil {
+			out = append(out, info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out, nil
+}
+
+// Get 返回指定密钥的信息
+func (k *KeyStore) Get(name string) (proto.KeyInfo, error) {
+	if err := checkKeyName(name); err != nil {
+		return proto.KeyInfo{}, err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.info(name)
+}
+
+// Delete 删除私钥与公钥文件
+func (k *KeyStore) Delete(name string) error {
+	if err := checkKeyName(name); err != nil {
+		return err
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	priv, pub := k.paths(name)
+	err1 := os.Remove(priv)
+	err2 := os.Remove(pub)
+	if errors.Is(err1, os.ErrNotExist) && errors.Is(err2, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	for _, err := range []error{err1, err2} {
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// info 从公钥文件解析密钥信息，调用方持有 k.mu
+func (k *KeyStore) info(name string) (proto.KeyInfo, error) {
+	priv, pubPath := k.paths(name)
+	b, err := os.ReadFile(pubPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return proto.KeyInfo{}, fmt.Errorf("%w: %s", ErrKeyNotFound, name)
+	}
+	if err != nil {
+		return proto.KeyInfo{}, err
+	}
+	pub, comment, _, _, err := gossh.ParseAuthorizedKey(b)
+	if err != nil {
+		return proto.KeyInfo{}, fmt.Errorf("parse %s: %w", pubPath, err)
+	}
+	st, err := os.Stat(priv)
+	if err != nil {
+		return proto.KeyInfo{}, fmt.Errorf("%w: %s (private key missing)", ErrKeyNotFound, name)
+	}
+	pem, err := os.ReadFile(priv)
+	if err != nil {
+		return proto.KeyInfo{}, err
+	}
+	_, parseErr := gossh.ParseRawPrivateKey(pem)
+	var missing *gossh.PassphraseMissingError
+	return proto.KeyInfo{
+		Name:        name,
+		Type:        pub.Type(),
+		Bits:        keyBits(pub),
+		Fingerprint: gossh.FingerprintSHA256(pub),
+		Comment:     comment,
+		Encrypted:   errors.As(parseErr, &missing),
+		Path:        priv,
+		PublicKey:   strings.TrimSpace(string(b)),
+		CreatedAt:   st.ModTime().UTC(),
+	}, nil
+}
+
+// write 写入私钥与公钥文件，同名密钥已存在时失败
+func (k *KeyStore) write(name string, private []byte, pub gossh.PublicKey, comment string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := os.MkdirAll(k.Dir, 0o700); err != nil {
+		return err
+	}
+	priv, pubPath := k.paths(name)
+	if _, err := os.Stat(pubPath); err == nil {
+		return fmt.Errorf("%w: key %q already exists", ErrInvalid, name)
+	}
+	f, err := os.OpenFile(priv, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%w: key %q already exists", ErrInvalid, name)
+	}
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(private); err != nil {
+		f.Close()
+		os.Remove(priv)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(priv)
+		return err
+	}
+	line := bytes.TrimSpace(gossh.MarshalAuthorizedKey(pub))
+	if comment != "" {
+		line = append(line, ' ')
+		line = append(line, comment...)
+	}
+	if err := os.WriteFile(pubPath, append(line, '\n'), 0o644); err != nil {
+		os.Remove(priv)
+		return err
+	}
+	return nil
+}
+
+func (k *KeyStore) paths(name string) (priv, pub string) {
+	priv = filepath.Join(k.Dir, name)
+	return priv, priv + ".pub"
+}
+
+func checkKeyName(name string) error {
+	if !keyNamePattern.MatchString(name) || strings.HasSuffix(name, ".pub") {
+		return fmt.Errorf("%w: key name %q must be 1-64 letters, digits, '.', '_' or '-'", ErrInvalid, name)
+	}
+	return nil
+}
+
+// publicKeyOf 从私钥中取出公钥。OpenSSH 格式的加密私钥无需口令即可读取公钥。
+func publicKeyOf(data []byte, passphrase string) (gossh.PublicKey, error) {
+	raw, err := gossh.ParseRawPrivateKey(data)
+	var missing *gossh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if missing.PublicKey != nil && passphrase == "" {
+			return missing.PublicKey, nil
+		}
+		if passphrase == "" {
+			return nil, errors.New("key is encrypted, passphrase required to read its public key")
+		}
+		raw, err = gossh.ParseRawPrivateKeyWithPassphrase(data, []byte(passphrase))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse key: %w", err)
+	}
+	signer, err := gossh.NewSignerFromKey(raw)
+	if err != nil {
+		return nil, err
+	}
+	return signer.PublicKey(), nil
+}
+
+// keyBits 返回公钥长度
+func keyBits(pub gossh.PublicKey) int {
+	cp, ok := pub.(gossh.CryptoPublicKey)
+	if !ok {
+		return 0
+	}
+	switch k := cp.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return 256
+	}
+	return 0
+}
+
+// InstallKey 经 connID 对应的连接将受管公钥追加到远端 ~/.ssh/authorized_keys，
+// 已存在同一行时不重复追加；返回是否新增
+func (m *Manager) InstallKey(ctx context.Context, req proto.InstallKeyRequest) (bool, error) {
+	if m.Keys == nil {
+		return false, fmt.Errorf("%w: key store not configured", ErrInvalid)
+	}
+	info, err := m.Keys.Get(req.Name)
+	if err != nil {
+		return false, err
+	}
+	client, err := m.Client(req.ConnID)
+	if err != nil {
+		return false, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return false, fmt.Errorf("new session: %w", err)
+	}
+	untrack := m.track(session)
+	defer untrack()
+	defer session.Close()
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	key := shellQuote(info.PublicKey)
+	cmd := "umask 077; mkdir -p ~/.ssh && touch ~/.ssh/authorized_keys && " +
+		"if grep -qxF " + key + " ~/.ssh/authorized_keys; then echo present; " +
+		"else echo " + key + " >> ~/.ssh/authorized_keys && echo added; fi"
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	out, err := session.Output(cmd)
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		return false, fmt.Errorf("install key on %s: %s", req.ConnID, msg)
+	}
+	return strings.TrimSpace(string(out)) == "added", nil
+}
+
+// shellQuote 以单引号包裹字符串，供 POSIX shell 使用
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,413 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// ErrHostKey 表示主机密钥未受信任（未知或已变更）
+var ErrHostKey = errors.New("host key not trusted")
+
+// ErrHostKeyRevoked 表示主机密钥已被 @revoked 标记吊销
+var ErrHostKeyRevoked = errors.New("host key revoked")
+
+// KnownHosts 基于 OpenSSH known_hosts 格式校验主机密钥：支持哈希主机名、
+// 通配符模式、@cert-authority 与 @revoked 标记。后端管理的文件可读写，
+// 用户自己的 ~/.ssh/known_hosts 仅作为只读的信任来源。
+type KnownHosts struct {
+	// Path 后端管理的 known_hosts 文件，trust/remove 只修改该文件
+	Path string
+	// ReadOnly 额外的只读 known_hosts 文件，不存在的文件会被忽略
+	ReadOnly []string
+	// Publish 主机密钥未受信任时推送事件，可为空
+	Publish func(event string, data any)
+
+	mu sync.Mutex
+}
+
+// NewKnownHosts 以 path 为管理文件创建校验器，并将 ~/.ssh/known_hosts 作为只读来源
+func NewKnownHosts(path string) *KnownHosts {
+	k := &KnownHosts{Path: path}
+	if home, err := os.UserHomeDir(); err == nil {
+		k.ReadOnly = []string{filepath.Join(home, ".ssh", "known_hosts")}
+	}
+	return k
+}
+
+// probeKey 用于探测主机已记录的密钥类型，不会与任何真实密钥相同
+var probeKey = func() gossh.PublicKey {
+	k, err := gossh.NewPublicKey(ed25519.PublicKey(make([]byte, ed25519.PublicKeySize)))
+	if err != nil {
+		panic(err)
+	}
+	return k
+}()
+
+// Verifier 为一次拨号生成主机密钥回调与首选算法列表。
+// 已记录该主机的密钥时，只协商这些密钥类型，避免服务器换用另一种密钥导致误报变更。
+func (k *KnownHosts) Verifier(connID, host string, port int) (gossh.HostKeyCallback, []string, error) {
+	files, err := k.files()
+	if err != nil {
+		return nil, nil, err
+	}
+	db, err := knownhosts.New(files...)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := k.entries(files)
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	cb := func(hostname string, remote net.Addr, key gossh.PublicKey) error {
+		err := db(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+		var revoked *knownhosts.RevokedError
+		if errors.As(err, &revoked) {
+			return fmt.Errorf("%w: %s (%s:%d)", ErrHostKeyRevoked, gossh.FingerprintSHA256(key), revoked.Revoked.Filename, revoked.Revoked.Line)
+		}
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+		ev := proto.HostKeyEvent{
+			ConnID:      connID,
+			Host:        host,
+			Port:        port,
+			KeyType:     key.Type(),
+			Fingerprint: gossh.FingerprintSHA256(key),
+			Key:         strings.TrimSpace(string(gossh.MarshalAuthorizedKey(key))),
+		}
+		event := proto.EventHostKeyUnknown
+		for _, w := range keyErr.Want {
+			if e, ok := entries[entryKey(w.Filename, w.Line)]; ok && e.Marker == "" {
+				ev.Known = append(ev.Known, e)
+			}
+		}
+		if len(ev.Known) > 0 {
+			event = proto.EventHostKeyChanged
+		}
+		if k.Publish != nil {
+			k.Publish(event, ev)
+		}
+		if event == proto.EventHostKeyChanged {
+			return fmt.Errorf("%w: %s changed to %s", ErrHostKey, knownhosts.Normalize(addr), ev.Fingerprint)
+		}
+		return fmt.Errorf("%w: %s is unknown (%s)", ErrHostKey, knownhosts.Normalize(addr), ev.Fingerprint)
+	}
+
+	// 以探测密钥取得该主机已记录的密钥（证书颁发机构条目除外）
+	var algos []string
+	err = db(addr, &net.TCPAddr{IP: net.IPv4zero}, probeKey)
+	var keyErr *knownhosts.KeyError
+	if errors.As(err, &keyErr) {
+		seen := map[string]bool{}
+		for _, w := range keyErr.Want {
+			if entries[entryKey(w.Filename, w.Line)].Marker != "" {
+				// 存在匹配的 @cert-authority，不限制算法以便协商证书
+				return cb, nil, nil
+			}
+			for _, a := range algorithmsFor(w.Key.Type()) {
+				if !seen[a] {
+					seen[a] = true
+					algos = append(algos, a)
+				}
+			}
+		}
+	}
+	return cb, algos, nil
+}
+
+// List 返回全部 known_hosts 条目（管理文件在前）
+func (k *KnownHosts) List() ([]proto.HostKeyEntry, error) {
+	files, err := k.files()
+	if err != nil {
+		return nil, err
+	}
+	out := []proto.HostKeyEntry{}
+	for _, f := range files {
+		es, _ := k.parseFile(f)
+		out = append(out, es...)
+	}
+	return out, nil
+}
+
+// Trust 将主机密钥追加到管理文件
+func (k *KnownHosts) Trust(req proto.TrustHostKeyRequest) (proto.HostKeyEntry, error) {
+	req.Host = strings.TrimSpace(req.Host)
+	if req.Host == "" {
+		return proto.HostKeyEntry{}, fmt.Errorf("%w: host is required", ErrInvalid)
+	}
+	if req.Port == 0 {
+		req.Port = 22
+	}
+	key, _, _, _, err := gossh.ParseAuthorizedKey([]byte(req.Key))
+	if err != nil {
+		return proto.HostKeyEntry{}, fmt.Errorf("%w: parse key: %v", ErrInvalid, err)
+	}
+	host := knownhosts.Normalize(net.JoinHostPort(req.Host, strconv.Itoa(req.Port)))
+	if req.Hash {
+		host = knownhosts.HashHostname(host)
+	}
+	line := host + " " + strings.TrimSpace(string(gossh.MarshalAuthorizedKey(key)))
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	lines, err := k.readLines()
+	if err != nil {
+		return proto.HostKeyEntry{}, err
+	}
+	if req.Replace {
+		lines = removeMatching(lines, req.Host, req.Port, 0)
+	}
+	lines = append(lines, line)
+	if err := k.writeLines(lines); err != nil {
+		return proto.HostKeyEntry{}, err
+	}
+	e, _ := parseEntry(line)
+	e.File, e.Line = k.Path, len(lines)
+	return e, nil
+}
+
+// Remove 从管理文件删除条目：line>0 时删除该行，否则删除 host:port 的全部普通条目
+func (k *KnownHosts) Remove(req proto.RemoveHostKeyRequest) (int, error) {
+	if req.Line <= 0 && strings.TrimSpace(req.Host) == "" {
+		return 0, fmt.Errorf("%w: host or line is required", ErrInvalid)
+	}
+	if req.Port == 0 {
+		req.Port = 22
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	lines, err := k.readLines()
+	if err != nil {
+		return 0, err
+	}
+	kept := removeMatching(lines, strings.TrimSpace(req.Host), req.Port, req.Line)
+	removed := countEntries(lines) - countEntries(kept)
+	if removed == 0 {
+		return 0, fmt.Errorf("%w: no matching known_hosts entry", ErrInvalid)
+	}
+	return removed, k.writeLines(kept)
+}
+
+// files 返回参与校验的文件，管理文件不存在时创建
+func (k *KnownHosts) files() ([]string, error) {
+	if err := os.MkdirAll(filepath.Dir(k.Path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(k.Path, os.O_CREATE|os.O_RDONLY, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	files := []string{k.Path}
+	for _, p := range k.ReadOnly {
+		if _, err := os.Stat(p); err == nil {
+			files = append(files, p)
+		}
+	}
+	return files, nil
+}
+
+// entries 按 "文件:行" 索引全部条目
+func (k *KnownHosts) entries(files []string) map[string]proto.HostKeyEntry {
+	m := map[string]proto.HostKeyEntry{}
+	for _, f := range files {
+		es, _ := k.parseFile(f)
+		for _, e := range es {
+			m[entryKey(e.File, e.Line)] = e
+		}
+	}
+	return m
+}
+
+func (k *KnownHosts) parseFile(path string) ([]proto.HostKeyEntry, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var out []proto.HostKeyEntry
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	n := 0
+	for sc.Scan() {
+		n++
+		e, ok := parseEntry(sc.Text())
+		if !ok {
+			continue
+		}
+		e.File, e.Line, e.ReadOnly = path, n, path != k.Path
+		out = append(out, e)
+	}
+	return out, sc.Err()
+}
+
+func (k *KnownHosts) readLines() ([]string, error) {
+	b, err := os.ReadFile(k.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := strings.TrimRight(string(b), "\n")
+	if s == "" {
+		return nil, nil
+	}
+	return strings.Split(s, "\n"), nil
+}
+
+// writeLines 原子写入管理文件
+func (k *KnownHosts) writeLines(lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(k.Path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(k.Path), "known_hosts-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	data := strings.Join(lines, "\n")
+	if data != "" {
+		data += "\n"
+	}
+	if _, err := tmp.WriteString(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), k.Path)
+}
+
+// parseEntry 解析一行 known_hosts；空行与注释返回 false
+func parseEntry(line string) (proto.HostKeyEntry, bool) {
+	t := strings.TrimSpace(line)
+	if t == "" || strings.HasPrefix(t, "#") {
+		return proto.HostKeyEntry{}, false
+	}
+	marker, hosts, key, comment, _, err := gossh.ParseKnownHosts([]byte(t))
+	if err != nil {
+		return proto.HostKeyEntry{}, false
+	}
+	e := proto.HostKeyEntry{
+		Hosts:       hosts,
+		KeyType:     key.Type(),
+		Fingerprint: gossh.FingerprintSHA256(key),
+		Comment:     comment,
+	}
+	if marker != "" {
+		e.Marker = "@" + marker
+	}
+	for _, h := range hosts {
+		if strings.HasPrefix(h, "|") {
+			e.Hashed = true
+		}
+	}
+	return e, true
+}
+
+// removeMatching 删除第 line 行（line>0），或删除精确匹配 host:port 的普通条目（含哈希条目）
+func removeMatching(lines []string, host string, port, line int) []string {
+	target := knownhosts.Normalize(net.JoinHostPort(host, strconv.Itoa(port)))
+	out := make([]string, 0, len(lines))
+	for i, l := range lines {
+		if line > 0 {
+			if i+1 == line {
+				if _, ok := parseEntry(l); ok {
+					continue
+				}
+			}
+			out = append(out, l)
+			continue
+		}
+		e, ok := parseEntry(l)
+		if ok && e.Marker == "" && hostsMatch(e.Hosts, target) {
+			continue
+		}
+		out = append(out, l)
+	}
+	return out
+}
+
+func countEntries(lines []string) int {
+	n := 0
+	for _, l := range lines {
+		if _, ok := parseEntry(l); ok {
+			n++
+		}
+	}
+	return n
+}
+
+// hostsMatch 判断条目的主机列表是否包含 target（已按 known_hosts 规范化）
+func hostsMatch(hosts []string, target string) bool {
+	for _, h := range hosts {
+		if strings.HasPrefix(h, "|") {
+			if hashedMatch(h, target) {
+				return true
+			}
+			continue
+		}
+		if knownhosts.Normalize(h) == target || h == target {
+			return true
+		}
+	}
+	return false
+}
+
+// hashedMatch 校验 |1|salt|hash 形式的哈希主机名
+func hashedMatch(entry, target string) bool {
+	parts := strings.Split(entry, "|")
+	if len(parts) != 4 || parts[1] != "1" {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	want, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(target))
+	return hmac.Equal(mac.Sum(nil), want)
+}
+
+// algorithmsFor 将密钥类型映射为可协商的主机密钥算法
+func algorithmsFor(keyType string) []string {
+	if keyType == gossh.KeyAlgoRSA {
+		return []string{gossh.KeyAlgoRSASHA512, gossh.KeyAlgoRSASHA256, gossh.KeyAlgoRSA}
+	}
+	return []string{keyType}
+}
+
+func entryKey(file string, line int) string {
+	return file + ":" + strconv.Itoa(line)
+}
@@ -0,0 +1,179 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// testSigner 由固定种子生成 ed25519 签名器，seed 不同则密钥不同
+func testSigner(t *testing.T, seed byte) gossh.Signer {
+	t.Helper()
+	b := make([]byte, ed25519.SeedSize)
+	b[0] = seed
+	s, err := gossh.NewSignerFromKey(ed25519.NewKeyFromSeed(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func authorized(k gossh.PublicKey) string {
+	return strings.TrimSpace(string(gossh.MarshalAuthorizedKey(k)))
+}
+
+func TestHostsMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		hosts  []string
+		target string
+		want   bool
+	}{
+		{"plain", []string{"example.com"}, "example.com", true},
+		{"list", []string{"a.example", "example.com"}, "example.com", true},
+		{"bracketed port", []string{"[example.com]:2222"}, "[example.com]:2222", true},
+		{"default port normalized", []string{"example.com:22"}, "example.com", true},
+		{"other port", []string{"example.com"}, "[example.com]:2222", false},
+		{"hashed", []string{knownhosts.HashHostname("example.com")}, "example.com", true},
+		{"hashed with port", []string{knownhosts.HashHostname("[example.com]:2222")}, "[example.com]:2222", true},
+		{"hashed other host", []string{knownhosts.HashHostname("example.com")}, "example.org", false},
+		{"hashed malformed", []string{"|1|not base64|x"}, "example.com", false},
+		{"hashed unknown version", []string{"|2|c2FsdA==|aGFzaA=="}, "example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hostsMatch(tt.hosts, tt.target); got != tt.want {
+				t.Fatalf("hostsMatch(%q, %q) = %v, want %v", tt.hosts, tt.target, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveMatching(t *testing.T) {
+	key := authorized(testSigner(t, 1).PublicKey())
+	lines := []string{
+		"# managed by go-ssh",
+		"example.com " + key,
+		knownhosts.HashHostname("example.com") + " " + key,
+		"[example.com]:2222 " + key,
+		"@revoked example.com " + key,
+		"@cert-authority *.example.com " + key,
+		"other.example " + key,
+	}
+	tests := []struct {
+		name string
+		host string
+		port int
+		line int
+		want []int // 保留的行下标
+	}{
+		{"plain and hashed", "example.com", 22, 0, []int{0, 3, 4, 5, 6}},
+		{"non-default port", "example.com", 2222, 0, []int{0, 1, 2, 4, 5, 6}},
+		{"no match", "nowhere.example", 22, 0, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"by line", "", 0, 5, []int{0, 1, 2, 3, 5, 6}},
+		{"by line keeps comments", "", 0, 1, []int{0, 1, 2, 3, 4, 5, 6}},
+		{"line out of range", "", 0, 99, []int{0, 1, 2, 3, 4, 5, 6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := removeMatching(lines, tt.host, tt.port, tt.line)
+			var want []string
+			for _, i := range tt.want {
+				want = append(want, lines[i])
+			}
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("removeMatching kept\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+			}
+		})
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	trusted := testSigner(t, 1).PublicKey()
+	revoked := testSigner(t, 2).PublicKey()
+	changed := testSigner(t, 3).PublicKey()
+	ca := testSigner(t, 4)
+	hostKey := testSigner(t, 5).PublicKey()
+
+	cert := func(principal string, signer gossh.Signer) gossh.PublicKey {
+		c := &gossh.Certificate{
+			Key:             hostKey,
+			CertType:        gossh.HostCert,
+			ValidPrincipals: []string{principal},
+			ValidBefore:     gossh.CertTimeInfinity,
+		}
+		if err := c.SignCert(rand.Reader, signer); err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	content := strings.Join([]string{
+		knownhosts.HashHostname("hashed.example") + " " + authorized(trusted),
+		"plain.example " + authorized(trusted),
+		"@revoked * " + authorized(revoked),
+		"@cert-authority *.ca.example " + authorized(ca.PublicKey()),
+	}, "\n") + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// errAny 只要求回调拒绝，不限定错误类型（证书由 knownhosts 的 CertChecker 校验）
+	errAny := errors.New("any error")
+	tests := []struct {
+		name      string
+		host      string
+		key       gossh.PublicKey
+		wantErr   error
+		wantEvent string
+		wantAlgos bool // 是否按已记录的密钥限定算法
+	}{
+		{"hashed trusted", "hashed.example", trusted, nil, "", true},
+		{"plain trusted", "plain.example", trusted, nil, "", true},
+		{"changed", "plain.example", changed, ErrHostKey, proto.EventHostKeyChanged, true},
+		{"unknown", "new.example", changed, ErrHostKey, proto.EventHostKeyUnknown, false},
+		{"revoked", "plain.example", revoked, ErrHostKeyRevoked, "", true},
+		{"revoked on unknown host", "new.example", revoked, ErrHostKeyRevoked, "", false},
+		{"cert from ca", "h.ca.example", cert("h.ca.example", ca), nil, "", false},
+		{"cert for other principal", "h.ca.example", cert("x.ca.example", ca), errAny, "", false},
+		{"cert from other ca", "h.ca.example", cert("h.ca.example", testSigner(t, 6)), errAny, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []string
+			k := &KnownHosts{Path: path, Publish: func(event string, _ any) { events = append(events, event) }}
+			cb, algos, err := k.Verifier("c1", tt.host, 22)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (len(algos) > 0) != tt.wantAlgos {
+				t.Errorf("algorithms = %v, want restricted %v", algos, tt.wantAlgos)
+			}
+			err = cb(net.JoinHostPort(tt.host, "22"), &net.TCPAddr{IP: net.IPv4(192, 0, 2, 1), Port: 22}, tt.key)
+			switch {
+			case tt.wantErr == nil && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case tt.wantErr != nil && err == nil:
+				t.Fatalf("expected %v, got nil", tt.wantErr)
+			case tt.wantErr == ErrHostKey || tt.wantErr == ErrHostKeyRevoked:
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if got := strings.Join(events, ","); got != tt.wantEvent {
+				t.Errorf("events = %q, want %q", got, tt.wantEvent)
+			}
+		})
+	}
+}
@@ -0,0 +1,574 @@
+package ssh
+
+// Manager 负责 SSH 连接的创建、复用与释放，前端只通过连接 ID 引用连接。
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+	"go-ssh/service/vault"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ErrNotFound 表示指定 ID 的连接不存在
+var ErrNotFound = errors.New("connection not found")
+
+// ErrNotConnected 表示连接存在但当前不可用
+var ErrNotConnected = errors.New("connection not connected")
+
+// ErrInvalid 表示请求参数不合法
+var ErrInvalid = errors.New("invalid request")
+
+// conn 是单个受管连接的内部状态
+type conn struct {
+	req    proto.ConnectRequest
+	state  string
+	err    string
+	cause  error             // 最近一次拨号的原始错误，保留错误类型供调用方区分
+	chain  []proto.HopStatus // 经跳板机连接时各跳的状态，无跳板机时为 nil
+	client *gossh.Client
+	ready  chan struct{}      // 拨号结束（成功或失败）时关闭
+	cancel context.CancelFunc // 放弃拨号（断开或被替换），中止等待中的认证输入
+}
+
+// Manager 以 ConnectRequest.ID 为键管理所有 SSH 客户端连接。
+type Manager struct {
+	// DialTimeout 为每一跳 TCP 拨号与 SSH 握手（含认证）各自的超时，默认 10s；
+	// 等待用户应答认证提示的时间不计入，应答后重新计时
+	DialTimeout time.Duration
+	// HostKeys 按 known_hosts 校验服务器主机密钥
+	HostKeys *KnownHosts
+	// HostKeyCallback 自定义主机密钥校验，设置后优先于 HostKeys；两者均为空时拒绝连接
+	HostKeyCallback gossh.HostKeyCallback
+	// Publish 状态变化与认证提示时回调（通常接入事件总线）；为空时无法交互认证
+	Publish func(event string, data any)
+	// PromptTimeout 等待前端应答 auth_prompt 的时长，默认 2 分钟
+	PromptTimeout time.Duration
+	// Vault 解析 PasswordRef/PassphraseRef 引用的凭据，可为空
+	Vault *vault.Vault
+	// TransferConcurrency 同时进行的文件传输数，默认 DefaultTransferConcurrency
+	TransferConcurrency int
+	// TransferChunkSize 文件传输每次读写的块大小，默认 DefaultTransferChunkSize
+	TransferChunkSize int
+	// Keys 受管密钥目录，install_public_key 从中读取公钥，可为空
+	Keys *KeyStore
+	// Profile 按 ID 返回已保存的连接配置，connect 据此校验凭据引用的归属；为空时不解析凭据引用
+	Profile func(id string) (proto.Profile, error)
+	// Profiles 返回全部连接配置（含旧式明文密码），multi_execute 据此解析目标主机，可为空
+	Profiles func() ([]proto.Profile, error)
+	// Recordings 会话录像目录，open_shell 按其 Mode 录像，可为空
+	Recordings *RecordingStore
+	// SessionLogs 会话文本日志目录，启用了 SessionLog 的连接配置的 shell 会话写入其中，可为空
+	SessionLogs *SessionLogStore
+
+	mu        sync.Mutex
+	conns     map[string]*conn
+	sessions  map[io.Closer]struct{} // 依附于连接的会话（shell、转发等），CloseAll 时先行关闭
+	tunnels   map[string]*Tunnel
+	sftps     map[string]*sftpConn
+	transfers []*Transfer // 按创建顺序，含最近结束的
+	prompts   map[string]chan proto.AuthAnswerRequest
+	promptSeq uint64
+	execJobs  map[string]*execJob // 进行中的批量执行任务
+}
+
+// NewManager 创建空的连接管理器
+func NewManager() *Manager {
+	return &Manager{
+		conns:    make(map[string]*conn),
+		sessions: make(map[io.Closer]struct{}),
+		tunnels:  make(map[string]*Tunnel),
+		sftps:    make(map[string]*sftpConn),
+		prompts:  make(map[string]chan proto.AuthAnswerRequest),
+		execJobs: make(map[string]*execJob),
+	}
+}
+
+// Connect 建立（或复用）req.ID 对应的连接并返回其状态。
+// 同一 ID 已连接且目标一致时直接复用；正在连接时等待其结果；
+// 目标变化或此前失败/断开时重新拨号。凭据引用只在 req.ID 为拥有它们的已保存配置时解析。
+// ctx 取消（如请求超时）时中止拨号、握手与等待中的认证输入，连接记为失败。
+func (m *Manager) Connect(ctx context.Context, req proto.ConnectRequest) (proto.ConnectResponse, error) {
+	return m.connect(ctx, req, req.ID)
+}
+
+// connect 同 Connect，凭据引用按 profileID 对应的已保存配置校验（批量执行的连接 ID 与配置 ID 不同）
+func (m *Manager) connect(parent context.Context, req proto.ConnectRequest, profileID string) (proto.ConnectResponse, error) {
+	if err := normalize(&req); err != nil {
+		return proto.ConnectResponse{}, err
+	}
+	if err := m.resolveSecrets(&req, profileID); err != nil {
+		return proto.ConnectResponse{}, err
+	}
+
+	m.mu.Lock()
+	c, ok := m.conns[req.ID]
+	if ok && sameTarget(c.req, req) {
+		switch c.state {
+		case proto.StateConnected:
+			m.mu.Unlock()
+			return m.status(req.ID), nil
+		case proto.StateConnecting:
+			ready := c.ready
+			m.mu.Unlock()
+			select {
+			case <-ready:
+			case <-parent.Done():
+				return proto.ConnectResponse{}, fmt.Errorf("connect %s: %w", req.ID, parent.Err())
+			}
+			return m.result(req.ID)
+		}
+	}
+	if ok {
+		// 目标变化：放弃旧的拨号或关闭旧连接后重新拨号
+		c.cancel()
+		if c.client != nil {
+			_ = c.client.Close()
+		}
+	}
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+	c = &conn{req: req, state: proto.StateConnecting, chain: newChain(req), ready: make(chan struct{}), cancel: cancel}
+	m.conns[req.ID] = c
+	m.mu.Unlock()
+	m.notify(req.ID)
+
+	client, err := m.dial(ctx, c)
+	if err != nil && parent.Err() != nil {
+		err = fmt.Errorf("connect aborted: %w", parent.Err())
+	}
+
+	m.mu.Lock()
+	if m.conns[req.ID] != c {
+		// 拨号期间被断开或被新的请求替换
+		m.mu.Unlock()
+		if client != nil {
+			_ = client.Close()
+		}
+		close(c.ready)
+		return proto.ConnectResponse{}, fmt.Errorf("connect %s: superseded", req.ID)
+	}
+	if err != nil {
+		c.state = proto.StateFailed
+		c.err = err.Error()
+		c.cause = err
+	} else {
+		c.state = proto.StateConnected
+		c.client = client
+		go m.watch(req.ID, c)
+	}
+	m.mu.Unlock()
+	close(c.ready)
+	m.notify(req.ID)
+
+	return m.result(req.ID)
+}
+
+// Disconnect 关闭并移除指定连接
+func (m *Manager) Disconnect(id string) (proto.ConnectResponse, error) {
+	m.mu.Lock()
+	c, ok := m.conns[id]
+	if !ok {
+		m.mu.Unlock()
+		return proto.ConnectResponse{}, ErrNotFound
+	}
+	delete(m.conns, id)
+	client := c.client
+	m.mu.Unlock()
+
+	c.cancel()
+	if client != nil {
+		_ = client.Close()
+	}
+	st := proto.ConnectResponse{ID: id, Host: c.req.Host, Port: c.req.Port, User: c.req.User, State: proto.StateDisconnected}
+	m.publish(st)
+	return st, nil
+}
+
+// List 返回所有受管连接的状态，按 ID 排序
+func (m *Manager) List() []proto.ConnectResponse {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.conns))
+	for id := range m.conns {
+		ids = append(ids, id)
+	}
+	m.mu.Unlock()
+
+	sort.Strings(ids)
+	out := make([]proto.ConnectResponse, 0, len(ids))
+	for _, id := range ids {
+		out = append(out, m.status(id))
+	}
+	return out
+}
+
+// Client 返回已连接的 *ssh.Client，供会话、SFTP 等子系统复用
+func (m *Manager) Client(id string) (*gossh.Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.conns[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if c.state != proto.StateConnected || c.client == nil {
+		return nil, ErrNotConnected
+	}
+	return c.client, nil
+}
+
+// CloseAll 先关闭所有会话再关闭所有连接，用于进程退出；可重复调用
+func (m *Manager) CloseAll() {
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = make(map[io.Closer]struct{})
+	conns := m.conns
+	m.conns = make(map[string]*conn)
+	m.mu.Unlock()
+
+	for s := range sessions {
+		_ = s.Close()
+	}
+	for id, c := range conns {
+		c.cancel()
+		if c.client != nil {
+			_ = c.client.Close()
+		}
+		m.publish(proto.ConnectResponse{ID: id, Host: c.req.Host, Port: c.req.Port, User: c.req.User, State: proto.StateDisconnected})
+	}
+}
+
+// track 登记一个会话，返回注销函数
+func (m *Manager) track(s io.Closer) (untrack func()) {
+	m.mu.Lock()
+	m.sessions[s] = struct{}{}
+	m.mu.Unlock()
+	return func() {
+		m.mu.Lock()
+		delete(m.sessions, s)
+		m.mu.Unlock()
+	}
+}
+
+// status 生成连接的状态快照；连接不存在时视为已断开
+func (m *Manager) status(id string) proto.ConnectResponse {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.conns[id]
+	if !ok {
+		return proto.ConnectResponse{ID: id, State: proto.StateDisconnected}
+	}
+	return proto.ConnectResponse{
+		ID:    id,
+		Host:  c.req.Host,
+		Port:  c.req.Port,
+		User:  c.req.User,
+		State: c.state,
+		Error: c.err,
+		Chain: slices.Clone(c.chain),
+	}
+}
+
+// result 将拨号结果转换为返回值：失败时同时返回错误
+func (m *Manager) result(id string) (proto.ConnectResponse, error) {
+	st := m.status(id)
+	if st.State == proto.StateFailed {
+		m.mu.Lock()
+		var cause error
+		if c, ok := m.conns[id]; ok {
+			cause = c.cause
+		}
+		m.mu.Unlock()
+		if cause != nil {
+			return st, cause
+		}
+		return st, errors.New(st.Error)
+	}
+	return st, nil
+}
+
+// watch 等待底层连接结束，并将状态标记为 disconnected
+func (m *Manager) watch(id string, c *conn) {
+	_ = c.client.Wait()
+	m.mu.Lock()
+	changed := m.conns[id] == c && c.state == proto.StateConnected
+	if changed {
+		c.state = proto.StateDisconnected
+		c.client = nil
+		for i := range c.chain {
+			if c.chain[i].State == proto.StateConnected {
+				c.chain[i].State = proto.StateDisconnected
+			}
+		}
+	}
+	m.mu.Unlock()
+	if changed {
+		m.notify(id)
+	}
+}
+
+// notify 发布指定连接的当前状态
+func (m *Manager) notify(id string) {
+	m.publish(m.status(id))
+}
+
+func (m *Manager) publish(st proto.ConnectResponse) {
+	if m.Publish != nil {
+		m.Publish(proto.EventConnectionState, st)
+	}
+}
+
+// dialHop 完成一跳的 TCP 拨号与 SSH 握手；via 非空时经其转发 TCP 连接。
+// 拨号与握手各自受 DialTimeout 限制，握手计时不含等待用户应答认证提示的时间
+func (m *Manager) dialHop(ctx context.Context, req proto.ConnectRequest, via *gossh.Client) (*gossh.Client, error) {
+	timeout := m.DialTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	hs := &handshakeTimer{timeout: timeout}
+	ctx = context.WithValue(ctx, handshakeKey{}, hs)
+	auths, err := m.authMethods(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer auths.close()
+	cfg := &gossh.ClientConfig{
+		User:            req.User,
+		Auth:            auths.methods,
+		HostKeyCallback: m.HostKeyCallback,
+	}
+	if cfg.HostKeyCallback == nil {
+		if m.HostKeys == nil {
+			return nil, errors.New("no host key verification configured")
+		}
+		cfg.HostKeyCallback, cfg.HostKeyAlgorithms, err = m.HostKeys.Verifier(req.ID, req.Host, req.Port)
+		if err != nil {
+			return nil, fmt.Errorf("load known_hosts: %w", err)
+		}
+	}
+	addr := net.JoinHostPort(req.Host, strconv.Itoa(req.Port))
+	dctx, cancel := context.WithTimeout(ctx, timeout)
+	var nc net.Conn
+	if via == nil {
+		var d net.Dialer
+		nc, err = d.DialContext(dctx, "tcp", addr)
+	} else {
+		nc, err = via.DialContext(dctx, "tcp", addr)
+		if err != nil {
+			err = fmt.Errorf("dial %s: %w", addr, err)
+		}
+	}
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	// 超时或放弃拨号时关闭连接以中止握手；经跳板转发的连接不支持 SetDeadline，因此以关闭代替
+	hs.arm(nc)
+	stop := context.AfterFunc(ctx, func() { _ = nc.Close() })
+	cc, chans, reqs, err := gossh.NewClientConn(nc, addr, cfg)
+	stop()
+	if hs.stop() && err != nil {
+		err = fmt.Errorf("ssh handshake with %s timed out after %s", addr, timeout)
+	}
+	if err != nil {
+		_ = nc.Close()
+		return nil, auths.wrap(err)
+	}
+	return gossh.NewClient(cc, chans, reqs), nil
+}
+
+type handshakeKey struct{}
+
+// handshakeTimer 限制一次 SSH 握手的时长，到期时关闭连接；
+// 等待用户应答认证提示期间暂停，应答后重新计时
+type handshakeTimer struct {
+	timeout time.Duration
+
+	mu      sync.Mutex
+	t       *time.Timer
+	expired bool
+}
+
+// arm 开始计时，到期时关闭 c
+func (h *handshakeTimer) arm(c net.Conn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.t = time.AfterFunc(h.timeout, func() {
+		h.mu.Lock()
+		h.expired = true
+		h.mu.Unlock()
+		_ = c.Close()
+	})
+}
+
+func (h *handshakeTimer) pause() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.t != nil {
+		h.t.Stop()
+	}
+}
+
+func (h *handshakeTimer) resume() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.t != nil && !h.expired {
+		h.t.Reset(h.timeout)
+	}
+}
+
+// stop 结束计时，返回握手是否因超时被中止
+func (h *handshakeTimer) stop() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.t != nil {
+		h.t.Stop()
+		h.t = nil
+	}
+	return h.expired
+}
+
+// pauseHandshake 在等待用户输入期间暂停 ctx 所属握手的计时，返回恢复计时的函数
+func pauseHandshake(ctx context.Context) (resume func()) {
+	h, ok := ctx.Value(handshakeKey{}).(*handshakeTimer)
+	if !ok {
+		return func() {}
+	}
+	h.pause()
+	return h.resume
+}
+
+// normalize 校验必填字段并填充默认端口
+func normalize(req *proto.ConnectRequest) error {
+	req.ID = strings.TrimSpace(req.ID)
+	req.Host = strings.TrimSpace(req.Host)
+	req.User = strings.TrimSpace(req.User)
+	switch {
+	case req.ID == "":
+		return fmt.Errorf("%w: id is required", ErrInvalid)
+	case req.Host == "":
+		return fmt.Errorf("%w: host is required", ErrInvalid)
+	case req.User == "":
+		return fmt.Errorf("%w: user is required", ErrInvalid)
+	}
+	if req.Port == 0 {
+		req.Port = 22
+	}
+	if req.Port < 0 || req.Port > 65535 {
+		return fmt.Errorf("%w: port %d out of range", ErrInvalid, req.Port)
+	}
+	return normalizeJumps(req)
+}
+
+// resolveSecrets 以凭据库中的值填充未直接提供的密码与口令。引用必须属于 profileID 对应的已保存配置，
+// 且出现在同一位置（目标主机或第几跳跳板机）、指向同一主机、端口与用户，
+// 否则调用方可借任意引用把凭据发往自选的主机
+func (m *Manager) resolveSecrets(req *proto.ConnectRequest, profileID string) error {
+	var owned []secretSlot
+	for i, slot := range secretSlots(req) {
+		if slot.ref == "" || *slot.value != "" {
+			continue
+		}
+		if owned == nil {
+			stored, err := m.storedProfile(profileID)
+			if err != nil {
+				return err
+			}
+			owned = secretSlots(&stored)
+		}
+		if i >= len(owned) || !slot.sameAs(owned[i]) {
+			return fmt.Errorf("%w: secret %s is not owned by profile %s for this host", ErrInvalid, slot.ref, profileID)
+		}
+		if m.Vault == nil {
+			return fmt.Errorf("%w: no credential vault for secret %s", ErrInvalid, slot.ref)
+		}
+		v, err := m.Vault.Get(slot.ref)
+		if errors.Is(err, vault.ErrNotFound) {
+			return fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if err != nil {
+			return err
+		}
+		*slot.value = v
+	}
+	return nil
+}
+
+// secretSlot 连接请求中的一处凭据及其所属一跳的目标
+type secretSlot struct {
+	host  string
+	port  int
+	user  string
+	ref   string
+	value *string
+}
+
+func (s secretSlot) sameAs(o secretSlot) bool {
+	return s.host == o.host && s.port == o.port && s.user == o.user && s.ref == o.ref
+}
+
+// secretSlots 按固定顺序列出目标主机与各跳板机的密码、口令
+func secretSlots(r *proto.ConnectRequest) []secretSlot {
+	out := []secretSlot{
+		{r.Host, r.Port, r.User, r.PasswordRef, &r.Password},
+		{r.Host, r.Port, r.User, r.PassphraseRef, &r.Passphrase},
+	}
+	for i := range r.Jumps {
+		j := &r.Jumps[i]
+		out = append(out,
+			secretSlot{j.Host, j.Port, j.User, j.PasswordRef, &j.Password},
+			secretSlot{j.Host, j.Port, j.User, j.PassphraseRef, &j.Passphrase},
+		)
+	}
+	return out
+}
+
+// storedProfile 取已保存的配置并按连接请求规范化（默认端口、跳板机用户名），以便与请求比较
+func (m *Manager) storedProfile(id string) (proto.ConnectRequest, error) {
+	if m.Profile == nil {
+		return proto.ConnectRequest{}, fmt.Errorf("%w: secret references require a saved profile", ErrInvalid)
+	}
+	p, err := m.Profile(id)
+	if err != nil {
+		return proto.ConnectRequest{}, fmt.Errorf("%w: profile %s: %v", ErrInvalid, id, err)
+	}
+	req := proto.ConnectRequest{
+		ID: p.ID, Host: p.Host, Port: p.Port, User: p.User,
+		PasswordRef: p.PasswordRef, PassphraseRef: p.PassphraseRef,
+		Jumps: slices.Clone(p.Jumps),
+	}
+	if err := normalize(&req); err != nil {
+		return proto.ConnectRequest{}, err
+	}
+	return req, nil
+}
+
+// sameTarget 判断两次请求是否指向同一目标与身份
+func sameTarget(a, b proto.ConnectRequest) bool {
+	return a.Host == b.Host && a.Port == b.Port && a.User == b.User &&
+		a.Password == b.Password && a.KeyPath == b.KeyPath &&
+		a.Passphrase == b.Passphrase && slices.Equal(a.AuthMethods, b.AuthMethods) &&
+		sameJumps(a.Jumps, b.Jumps)
+}
+
+// expandHome 展开路径开头的 ~
+func expandHome(p string) string {
+	if p == "~" || strings.HasPrefix(p, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(p, "~"))
+		}
+	}
+	return p
+}
@@ -0,0 +1,87 @@
+package ssh
+
+import (
+	"errors"
+	"testing"
+
+	"go-ssh/proto"
+	"go-ssh/service/vault"
+)
+
+func TestResolveSecrets(t *testing.T) {
+	v, err := vault.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Init("master"); err != nil {
+		t.Fatal(err)
+	}
+	put := func(value string) string {
+		id, err := v.Put("", "", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	mine, myJump, foreign := put("mine"), put("jump"), put("foreign")
+
+	profiles := map[string]proto.Profile{
+		"p1": {ID: "p1", Host: "a.example", User: "u", PasswordRef: mine,
+			Jumps: []proto.JumpHost{{Host: "bastion", PasswordRef: myJump}}},
+		"p2": {ID: "p2", Host: "b.example", User: "u", PasswordRef: foreign},
+	}
+	m := NewManager()
+	m.Vault = v
+	m.Profile = func(id string) (proto.Profile, error) {
+		p, ok := profiles[id]
+		if !ok {
+			return proto.Profile{}, errors.New("not found")
+		}
+		return p, nil
+	}
+
+	target := func(id, host, ref string, jumps ...proto.JumpHost) proto.ConnectRequest {
+		return proto.ConnectRequest{ID: id, Host: host, Port: 22, User: "u", PasswordRef: ref, Jumps: jumps}
+	}
+	tests := []struct {
+		name      string
+		req       proto.ConnectRequest
+		profile   string
+		want      string // 解析后的目标主机密码
+		wantJump  string
+		wantError bool
+	}{
+		{"owned", target("p1", "a.example", mine), "p1", "mine", "", false},
+		{"owned with jump", target("p1", "a.example", mine, proto.JumpHost{Host: "bastion", Port: 22, User: "u", PasswordRef: myJump}), "p1", "mine", "jump", false},
+		{"foreign ref", target("p1", "a.example", foreign), "p1", "", "", true},
+		{"owned ref sent elsewhere", target("p1", "evil.example", mine), "p1", "", "", true},
+		{"jump ref used for target", target("p1", "a.example", myJump), "p1", "", "", true},
+		{"target ref used for jump", target("p1", "a.example", "", proto.JumpHost{Host: "bastion", Port: 22, User: "u", PasswordRef: mine}), "p1", "", "", true},
+		{"jump ref on other jump host", target("p1", "a.example", "", proto.JumpHost{Host: "evil", Port: 22, User: "u", PasswordRef: myJump}), "p1", "", "", true},
+		{"unsaved profile", target("adhoc", "b.example", foreign), "adhoc", "", "", true},
+		{"batch connection of owner", target("batch:j:p2", "b.example", foreign), "p2", "foreign", "", false},
+		{"explicit password wins", proto.ConnectRequest{ID: "adhoc", Host: "x", Port: 22, User: "u", Password: "typed", PasswordRef: foreign}, "adhoc", "typed", "", false},
+		{"no refs", target("adhoc", "x", ""), "adhoc", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := tt.req
+			err := m.resolveSecrets(&req, tt.profile)
+			if tt.wantError {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("resolveSecrets = %v, want ErrInvalid (password %q)", err, req.Password)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Password != tt.want {
+				t.Errorf("password = %q, want %q", req.Password, tt.want)
+			}
+			if len(req.Jumps) > 0 && req.Jumps[0].Password != tt.wantJump {
+				t.Errorf("jump password = %q, want %q", req.Jumps[0].Password, tt.wantJump)
+			}
+		})
+	}
+}
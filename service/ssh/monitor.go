@@ -0,0 +1,332 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// monitorScript 一次读取采样所需的全部 /proc 等只读信息，各段以 @@名称 分隔；
+// 远端只需 POSIX sh 与 coreutils，不安装任何程序。缺失的文件或命令只使对应段为空（stderr 被丢弃）
+const monitorScript = `export LC_ALL=C
+echo @@stat; head -n 1 /proc/stat
+echo @@cpuinfo; grep -E '^(processor|model name|cpu MHz|Hardware)' /proc/cpuinfo
+echo @@meminfo; cat /proc/meminfo
+echo @@loadavg; cat /proc/loadavg
+echo @@uptime; cat /proc/uptime
+echo @@netdev; cat /proc/net/dev
+echo @@hostname; cat /proc/sys/kernel/hostname
+echo @@kernel; cat /proc/sys/kernel/osrelease
+echo @@os; cat /etc/os-release
+echo @@mac; for i in /sys/class/net/*; do echo "${i##*/} $(cat "$i/address")"; done
+echo @@ip; ip -o addr show scope global || hostname -I
+echo @@df; df -kP / | tail -n 1
+` + "exit 0"
+
+// Monitor 周期采样一个连接的远端系统状态，保存上一次的计数器以计算使用率与速率
+type Monitor struct {
+	m      *Manager
+	connID string
+
+	prevCPU []uint64
+	prevNet map[string][2]uint64
+	prevAt  time.Time
+}
+
+// NewMonitor 为 connID 对应的连接创建采样器
+func (m *Manager) NewMonitor(connID string) (*Monitor, error) {
+	if _, err := m.Client(connID); err != nil {
+		return nil, err
+	}
+	return &Monitor{m: m, connID: connID}, nil
+}
+
+// Sample 在远端执行一次采样；连接不存在或已断开时返回 ErrNotFound / ErrNotConnected
+func (mon *Monitor) Sample(ctx context.Context) (proto.MonitorSample, error) {
+	client, err := mon.m.Client(mon.connID)
+	if err != nil {
+		return proto.MonitorSample{}, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return proto.MonitorSample{}, fmt.Errorf("new session: %w", err)
+	}
+	untrack := mon.m.track(session)
+	defer untrack()
+	defer session.Close()
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	out, err := session.Output(monitorScript)
+	if err != nil {
+		return proto.MonitorSample{}, fmt.Errorf("collect: %w", err)
+	}
+	now := time.Now()
+	sections := splitSections(out)
+	if len(sections["stat"]) == 0 {
+		return proto.MonitorSample{}, errors.New("remote host has no /proc/stat (not Linux?)")
+	}
+	s := proto.MonitorSample{ConnID: mon.connID, Time: now.UTC()}
+	s.Hostname = firstLine(sections["hostname"])
+	s.Kernel = firstLine(sections["kernel"])
+	s.OS = osPrettyName(sections["os"])
+	if f := strings.Fields(firstLine(sections["uptime"])); len(f) > 0 {
+		up, _ := strconv.ParseFloat(f[0], 64)
+		s.Uptime = int64(up)
+	}
+	if f := strings.Fields(firstLine(sections["loadavg"])); len(f) >= 3 {
+		for i := range s.Load {
+			s.Load[i], _ = strconv.ParseFloat(f[i], 64)
+		}
+	}
+	s.CPU = parseCPUInfo(sections["cpuinfo"])
+	s.Memory, s.Swap = parseMeminfo(sections["meminfo"])
+	s.Disk = parseDF(sections["df"])
+	s.Addresses = parseAddresses(sections["ip"])
+
+	elapsed := now.Sub(mon.prevAt).Seconds()
+	cpu := parseCPUTimes(firstLine(sections["stat"]))
+	s.CPU.Usage = cpuUsage(mon.prevCPU, cpu)
+	net := parseNetDev(sections["netdev"])
+	macs := parseMACs(sections["mac"])
+	for _, n := range net {
+		n.MAC = macs[n.Name]
+		if prev, ok := mon.prevNet[n.Name]; ok && elapsed > 0 {
+			n.RxRate = rate(prev[0], n.RxBytes, elapsed)
+			n.TxRate = rate(prev[1], n.TxBytes, elapsed)
+		}
+		s.Net = append(s.Net, n)
+	}
+	mon.prevCPU, mon.prevAt = cpu, now
+	mon.prevNet = make(map[string][2]uint64, len(net))
+	for _, n := range net {
+		mon.prevNet[n.Name] = [2]uint64{n.RxBytes, n.TxBytes}
+	}
+	return s, nil
+}
+
+// monitorInterval 将请求的间隔截断到允许范围
+func monitorInterval(ms int) time.Duration {
+	d := time.Duration(ms) * time.Millisecond
+	switch {
+	case ms == 0:
+		return proto.DefaultMonitorInterval
+	case d < proto.MinMonitorInterval:
+		return proto.MinMonitorInterval
+	case d > proto.MaxMonitorInterval:
+		return proto.MaxMonitorInterval
+	}
+	return d
+}
+
+// splitSections 按 @@名称 行拆分脚本输出
+func splitSections(out []byte) map[string][]string {
+	sections := make(map[string][]string)
+	name := ""
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	sc.Buffer(make([]byte, 64*1024), 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.HasPrefix(line, "@@") {
+			name = line[2:]
+			continue
+		}
+		if name != "" {
+			sections[name] = append(sections[name], line)
+		}
+	}
+	return sections
+}
+
+func firstLine(lines []string) string {
+	if len(lines) == 0 {
+		return ""
+	}
+	return strings.TrimSpace(lines[0])
+}
+
+func osPrettyName(lines []string) string {
+	for _, l := range lines {
+		if v, ok := strings.CutPrefix(l, "PRETTY_NAME="); ok {
+			return strings.Trim(v, `"'`)
+		}
+	}
+	return ""
+}
+
+// parseCPUTimes 解析 /proc/stat 的汇总 cpu 行
+func parseCPUTimes(line string) []uint64 {
+	f := strings.Fields(line)
+	if len(f) < 5 || f[0] != "cpu" {
+		return nil
+	}
+	times := make([]uint64, 0, len(f)-1)
+	for _, v := range f[1:] {
+		n, _ := strconv.ParseUint(v, 10, 64)
+		times = append(times, n)
+	}
+	return times
+}
+
+// cpuUsage 由两次 /proc/stat 计数计算使用率：idle 与 iowait 视为空闲
+func cpuUsage(prev, cur []uint64) float64 {
+	if len(prev) < 4 || len(prev) != len(cur) {
+		return 0
+	}
+	var total, idle uint64
+	for i := range cur {
+		if cur[i] < prev[i] {
+			return 0
+		}
+		d := cur[i] - prev[i]
+		// guest 与 guest_nice 已计入 user / nice
+		if i < 8 {
+			total += d
+		}
+		if i == 3 || i == 4 {
+			idle += d
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(total-idle) * 100 / float64(total)
+}
+
+func parseCPUInfo(lines []string) proto.CPUStat {
+	var c proto.CPUStat
+	var mhz float64
+	var mhzN int
+	for _, l := range lines {
+		k, v, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch k {
+		case "processor":
+			c.Cores++
+		case "model name", "Hardware":
+			if c.Model == "" {
+				c.Model = v
+			}
+		case "cpu MHz":
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				mhz += f
+				mhzN++
+			}
+		}
+	}
+	if mhzN > 0 {
+		c.MHz = mhz / float64(mhzN)
+	}
+	return c
+}
+
+// parseMeminfo 解析 /proc/meminfo（单位 kB）；Used 为 Total - Available，
+// 旧内核没有 MemAvailable 时以 Free + Buffers + Cached 估算
+func parseMeminfo(lines []string) (mem, swap proto.MemoryStat) {
+	kv := make(map[string]uint64)
+	for _, l := range lines {
+		k, v, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		f := strings.Fields(v)
+		if len(f) == 0 {
+			continue
+		}
+		n, _ := strconv.ParseUint(f[0], 10, 64)
+		kv[k] = n * 1024
+	}
+	mem.Total = kv["MemTotal"]
+	mem.Cached = kv["Buffers"] + kv["Cached"] + kv["SReclaimable"]
+	mem.Available = kv["MemAvailable"]
+	if _, ok := kv["MemAvailable"]; !ok {
+		mem.Available = kv["MemFree"] + kv["Buffers"] + kv["Cached"]
+	}
+	if mem.Available <= mem.Total {
+		mem.Used = mem.Total - mem.Available
+	}
+	swap.Total = kv["SwapTotal"]
+	if kv["SwapFree"] <= swap.Total {
+		swap.Used = swap.Total - kv["SwapFree"]
+	}
+	return mem, swap
+}
+
+// parseDF 解析 df -kP 的数据行：文件系统 总量 已用 可用 使用率 挂载点
+func parseDF(lines []string) proto.DiskStat {
+	f := strings.Fields(firstLine(lines))
+	if len(f) < 6 {
+		return proto.DiskStat{}
+	}
+	total, _ := strconv.ParseUint(f[1], 10, 64)
+	used, _ := strconv.ParseUint(f[2], 10, 64)
+	return proto.DiskStat{Mount: f[5], Total: total * 1024, Used: used * 1024}
+}
+
+// parseNetDev 解析 /proc/net/dev，跳过回环接口
+func parseNetDev(lines []string) []proto.NetStat {
+	var out []proto.NetStat
+	for _, l := range lines {
+		name, rest, ok := strings.Cut(l, ":")
+		if !ok {
+			continue
+		}
+		name = strings.TrimSpace(name)
+		f := strings.Fields(rest)
+		if name == "lo" || len(f) < 9 {
+			continue
+		}
+		rx, _ := strconv.ParseUint(f[0], 10, 64)
+		tx, _ := strconv.ParseUint(f[8], 10, 64)
+		out = append(out, proto.NetStat{Name: name, RxBytes: rx, TxBytes: tx})
+	}
+	return out
+}
+
+func parseMACs(lines []string) map[string]string {
+	macs := make(map[string]string)
+	for _, l := range lines {
+		if f := strings.Fields(l); len(f) == 2 && f[1] != "00:00:00:00:00:00" {
+			macs[f[0]] = f[1]
+		}
+	}
+	return macs
+}
+
+// parseAddresses 解析 ip -o addr 的 inet / inet6 字段，或 hostname -I 的地址列表
+func parseAddresses(lines []string) []string {
+	var out []string
+	for _, l := range lines {
+		f := strings.Fields(l)
+		if len(f) > 0 && strings.HasSuffix(f[0], ":") {
+			// ip -o：序号: 接口 inet 地址/前缀 ...
+			for i := 0; i+1 < len(f); i++ {
+				if f[i] == "inet" || f[i] == "inet6" {
+					addr, _, _ := strings.Cut(f[i+1], "/")
+					out = append(out, addr)
+				}
+			}
+			continue
+		}
+		out = append(out, f...)
+	}
+	return out
+}
+
+// rate 由两次累计计数计算每秒速率，计数回绕或接口重置时为 0
+func rate(prev, cur uint64, seconds float64) uint64 {
+	if cur < prev {
+		return 0
+	}
+	return uint64(float64(cur-prev) / seconds)
+}
@@ -0,0 +1,196 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go-ssh/proto"
+)
+
+// multiExecSeq 用于生成进程内唯一的批量执行任务 ID
+var multiExecSeq atomic.Uint64
+
+// execJob 一个进行中的批量执行任务；Close 取消任务，经 track 登记后 CloseAll 时一并取消
+type execJob struct {
+	resp   proto.MultiExecuteResponse
+	cancel context.CancelFunc
+}
+
+func (j *execJob) Close() error {
+	j.cancel()
+	return nil
+}
+
+// MultiExecute 校验请求并解析目标主机后立即返回，各主机在后台按并发上限依次连接并执行，
+// 开始与结束时经 execute_result 事件推送；任务可由 CancelMultiExecute 取消
+func (m *Manager) MultiExecute(req proto.MultiExecuteRequest) (proto.MultiExecuteResponse, error) {
+	if _, err := executeScript(req.Exec("")); err != nil {
+		return proto.MultiExecuteResponse{}, err
+	}
+	if m.Profiles == nil {
+		return proto.MultiExecuteResponse{}, fmt.Errorf("%w: profile store not configured", ErrInvalid)
+	}
+	profiles, err := m.Profiles()
+	if err != nil {
+		return proto.MultiExecuteResponse{}, err
+	}
+	targets, err := selectProfiles(profiles, req.ProfileIDs, req.Folder)
+	if err != nil {
+		return proto.MultiExecuteResponse{}, err
+	}
+	if req.ID == "" {
+		req.ID = fmt.Sprintf("exec-%d", multiExecSeq.Add(1))
+	}
+	resp := proto.MultiExecuteResponse{ID: req.ID, Hosts: make([]proto.ExecuteHost, len(targets))}
+	for i, p := range targets {
+		resp.Hosts[i] = executeHost(p)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &execJob{resp: resp, cancel: cancel}
+	m.mu.Lock()
+	if _, dup := m.execJobs[req.ID]; dup {
+		m.mu.Unlock()
+		cancel()
+		return proto.MultiExecuteResponse{}, fmt.Errorf("%w: job %q is already running", ErrInvalid, req.ID)
+	}
+	m.execJobs[req.ID] = job
+	m.mu.Unlock()
+	untrack := m.track(job)
+	go func() {
+		defer func() {
+			untrack()
+			cancel()
+			m.mu.Lock()
+			delete(m.execJobs, req.ID)
+			m.mu.Unlock()
+		}()
+		m.runMultiExecute(ctx, req, targets)
+	}()
+	return resp, nil
+}
+
+// CancelMultiExecute 取消进行中的批量执行任务，返回其目标主机
+func (m *Manager) CancelMultiExecute(id string) (proto.MultiExecuteResponse, error) {
+	m.mu.Lock()
+	job := m.execJobs[id]
+	m.mu.Unlock()
+	if job == nil {
+		return proto.MultiExecuteResponse{}, fmt.Errorf("%w: job %q not found or already finished", ErrInvalid, id)
+	}
+	job.cancel()
+	return job.resp, nil
+}
+
+func (m *Manager) runMultiExecute(ctx context.Context, req proto.MultiExecuteRequest, targets []proto.Profile) {
+	n := req.Concurrency
+	switch {
+	case n <= 0:
+		n = proto.DefaultMultiExecuteConcurrency
+	case n > proto.MaxMultiExecuteConcurrency:
+		n = proto.MaxMultiExecuteConcurrency
+	}
+	sem := make(chan struct{}, n)
+	var wg sync.WaitGroup
+	var done atomic.Int64
+	for _, p := range targets {
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if acquired {
+				defer func() { <-sem }()
+			}
+			ev := proto.ExecuteResult{JobID: req.ID, ExecuteHost: executeHost(p), Total: len(targets)}
+			if !acquired || ctx.Err() != nil {
+				// 取消后尚未开始的主机不再连接
+				ev.State, ev.Error = proto.ExecuteFailed, "cancelled"
+				ev.Done = int(done.Add(1))
+				m.publishResult(ev)
+				return
+			}
+			ev.State, ev.Done = proto.ExecuteRunning, int(done.Load())
+			m.publishResult(ev)
+
+			res, err := m.executeOn(ctx, p, req)
+			if err != nil {
+				ev.State, ev.Error = proto.ExecuteFailed, err.Error()
+			} else {
+				ev.State, ev.Result = proto.ExecuteCompleted, &res
+			}
+			ev.Done = int(done.Add(1))
+			m.publishResult(ev)
+		}()
+	}
+	wg.Wait()
+}
+
+// executeOn 在 p 上执行命令：配置 ID 对应的连接已建立时复用，否则新建任务私有的连接并在结束后断开，
+// 以免断开同时打开的终端或其他任务；KeepConnected 时改为以配置 ID 连接并保留
+func (m *Manager) executeOn(ctx context.Context, p proto.Profile, req proto.MultiExecuteRequest) (proto.ExecuteResponse, error) {
+	connID := p.ID
+	if m.status(p.ID).State != proto.StateConnected && !req.KeepConnected {
+		connID = "batch:" + req.ID + ":" + p.ID
+		// 连接失败时同样移除，避免在连接列表中留下 failed 条目
+		defer func() { _, _ = m.Disconnect(connID) }()
+	}
+	if _, err := m.connect(ctx, proto.ConnectRequest{
+		ID: connID, Host: p.Host, Port: p.Port, User: p.User,
+		Password: p.Password, KeyPath: p.KeyPath, AuthMethods: p.AuthMethods,
+		PasswordRef: p.PasswordRef, PassphraseRef: p.PassphraseRef,
+		Jumps: p.Jumps,
+	}, p.ID); err != nil {
+		if ctx.Err() != nil {
+			return proto.ExecuteResponse{}, ctx.Err()
+		}
+		return proto.ExecuteResponse{}, fmt.Errorf("connect: %w", err)
+	}
+	return m.Execute(ctx, req.Exec(connID))
+}
+
+func (m *Manager) publishResult(ev proto.ExecuteResult) {
+	if m.Publish != nil {
+		m.Publish(proto.EventExecuteResult, ev)
+	}
+}
+
+// selectProfiles 返回 ids 与 folder 选中的配置，按配置列表中的顺序去重
+func selectProfiles(profiles []proto.Profile, ids []string, folder string) ([]proto.Profile, error) {
+	want := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		want[id] = true
+	}
+	folder = strings.Trim(folder, "/")
+	var out []proto.Profile
+	for _, p := range profiles {
+		inFolder := folder != "" && (p.Folder == folder || strings.HasPrefix(p.Folder, folder+"/"))
+		if want[p.ID] || inFolder {
+			out = append(out, p)
+			delete(want, p.ID)
+		}
+	}
+	for _, id := range ids {
+		if want[id] {
+			return nil, fmt.Errorf("%w: profile %q not found", ErrInvalid, id)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("%w: no target hosts", ErrInvalid)
+	}
+	return out, nil
+}
+
+func executeHost(p proto.Profile) proto.ExecuteHost {
+	port := p.Port
+	if port == 0 {
+		port = 22
+	}
+	return proto.ExecuteHost{ProfileID: p.ID, Name: p.Name, Host: fmt.Sprintf("%s@%s:%d", p.User, p.Host, port)}
+}
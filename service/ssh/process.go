@@ -0,0 +1,233 @@
+package ssh
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-ssh/proto"
+)
+
+// processScript 输出远端当前时间与全部进程；etimes 为已运行秒数，与 date 相减得到启动时间，
+// 避免解析随 locale 变化的 lstart。args 含空格，必须放在最后
+const processScript = `export LC_ALL=C; date +%s; ps -eo pid=,ppid=,user:32=,pcpu=,pmem=,rss=,stat=,ni=,etimes=,args=`
+
+// ListProcesses 在远端执行 ps 并按请求排序、过滤
+func (m *Manager) ListProcesses(ctx context.Context, req proto.ListProcessesRequest) (proto.ListProcessesResponse, error) {
+	sortKey, desc, err := processSort(req.Sort)
+	if err != nil {
+		return proto.ListProcessesResponse{}, err
+	}
+	out, err := m.output(ctx, req.ConnID, processScript)
+	if err != nil {
+		return proto.ListProcessesResponse{}, fmt.Errorf("ps: %w", err)
+	}
+	now, procs, err := parsePS(out)
+	if err != nil {
+		return proto.ListProcessesResponse{}, err
+	}
+
+	filter := strings.ToLower(strings.TrimSpace(req.Filter))
+	kept := procs[:0]
+	for _, p := range procs {
+		if req.User != "" && p.User != req.User {
+			continue
+		}
+		if filter != "" && strconv.Itoa(p.PID) != filter &&
+			!strings.Contains(strings.ToLower(p.Command), filter) &&
+			!strings.Contains(strings.ToLower(p.User), filter) {
+			continue
+		}
+		kept = append(kept, p)
+	}
+	slices.SortStableFunc(kept, func(a, b proto.ProcessInfo) int {
+		c := compareProcess(sortKey, a, b)
+		if c == 0 {
+			c = a.PID - b.PID
+		} else if desc {
+			c = -c
+		}
+		return c
+	})
+	resp := proto.ListProcessesResponse{Processes: kept, Total: len(kept), Time: now}
+	if req.Limit > 0 && len(kept) > req.Limit {
+		resp.Processes = kept[:req.Limit]
+	}
+	return resp, nil
+}
+
+// SignalProcesses 向远端进程发送信号，逐个返回结果
+func (m *Manager) SignalProcesses(ctx context.Context, req proto.SignalProcessRequest) (proto.ProcessActionResponse, error) {
+	sig := strings.TrimPrefix(strings.ToUpper(req.Signal), "SIG")
+	if sig == "" {
+		sig = "TERM"
+	}
+	if !slices.Contains(proto.ProcessSignals, sig) {
+		return proto.ProcessActionResponse{}, fmt.Errorf("%w: unsupported signal %q", ErrInvalid, req.Signal)
+	}
+	return m.processAction(ctx, req.ConnID, req.PIDs, "kill -s "+sig+" --")
+}
+
+// ReniceProcesses 调整远端进程的 nice 值，逐个返回结果
+func (m *Manager) ReniceProcesses(ctx context.Context, req proto.ReniceRequest) (proto.ProcessActionResponse, error) {
+	if req.Nice < -20 || req.Nice > 19 {
+		return proto.ProcessActionResponse{}, fmt.Errorf("%w: nice must be between -20 and 19", ErrInvalid)
+	}
+	return m.processAction(ctx, req.ConnID, req.PIDs, "renice -n "+strconv.Itoa(req.Nice)+" -p")
+}
+
+// processAction 对每个 PID 执行 cmd <pid>，每行输出 "pid ok" 或 "pid 错误信息"
+func (m *Manager) processAction(ctx context.Context, connID string, pids []int, cmd string) (proto.ProcessActionResponse, error) {
+	if len(pids) == 0 {
+		return proto.ProcessActionResponse{}, fmt.Errorf("%w: pids is required", ErrInvalid)
+	}
+	list := make([]string, len(pids))
+	for i, pid := range pids {
+		if pid <= 0 {
+			return proto.ProcessActionResponse{}, fmt.Errorf("%w: invalid pid %d", ErrInvalid, pid)
+		}
+		list[i] = strconv.Itoa(pid)
+	}
+	script := "export LC_ALL=C; for p in " + strings.Join(list, " ") + "; do " +
+		"if out=$(" + cmd + ` "$p" 2>&1); then echo "$p ok"; ` +
+		`else echo "$p $(echo "$out" | tail -n 1)"; fi; done`
+	out, err := m.output(ctx, connID, script)
+	if err != nil {
+		return proto.ProcessActionResponse{}, err
+	}
+	errs := make(map[int]string, len(pids))
+	for _, line := range strings.Split(string(out), "\n") {
+		pid, msg, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(pid); err == nil && msg != "ok" {
+			errs[n] = msg
+		}
+	}
+	resp := proto.ProcessActionResponse{Results: make([]proto.ProcessActionResult, len(pids))}
+	for i, pid := range pids {
+		resp.Results[i] = proto.ProcessActionResult{PID: pid, Error: errs[pid]}
+	}
+	return resp, nil
+}
+
+// output 在连接上执行一条命令并返回 stdout；失败时以 stderr 作为错误信息
+func (m *Manager) output(ctx context.Context, connID, cmd string) ([]byte, error) {
+	client, err := m.Client(connID)
+	if err != nil {
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("new session: %w", err)
+	}
+	untrack := m.track(session)
+	defer untrack()
+	defer session.Close()
+	stop := context.AfterFunc(ctx, func() { session.Close() })
+	defer stop()
+
+	var stderr bytes.Buffer
+	session.Stderr = &stderr
+	out, err := session.Output(cmd)
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, errors.New(msg)
+		}
+		return nil, err
+	}
+	return out, nil
+}
+
+// processSort 解析排序字段，默认按 CPU 降序
+func processSort(s string) (string, bool, error) {
+	if s == "" {
+		return proto.ProcessSortCPU, true, nil
+	}
+	key, desc := strings.CutPrefix(s, "-")
+	switch key {
+	case proto.ProcessSortPID, proto.ProcessSortUser, proto.ProcessSortCPU,
+		proto.ProcessSortMem, proto.ProcessSortStart, proto.ProcessSortCommand:
+		return key, desc, nil
+	}
+	return "", false, fmt.Errorf("%w: unknown sort %q", ErrInvalid, s)
+}
+
+func compareProcess(key string, a, b proto.ProcessInfo) int {
+	switch key {
+	case proto.ProcessSortUser:
+		return strings.Compare(a.User, b.User)
+	case proto.ProcessSortCPU:
+		return cmp.Compare(a.CPU, b.CPU)
+	case proto.ProcessSortMem:
+		return cmp.Compare(a.RSS, b.RSS)
+	case proto.ProcessSortStart:
+		return a.StartTime.Compare(b.StartTime)
+	case proto.ProcessSortCommand:
+		return strings.Compare(a.Command, b.Command)
+	}
+	return a.PID - b.PID
+}
+
+// parsePS 解析 processScript 的输出：首行为远端 Unix 时间，其后每行一个进程
+func parsePS(out []byte) (time.Time, []proto.ProcessInfo, error) {
+	lines := strings.Split(strings.TrimRight(string(out), "\n"), "\n")
+	sec, err := strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("unexpected ps output: %q", lines[0])
+	}
+	now := time.Unix(sec, 0).UTC()
+	procs := make([]proto.ProcessInfo, 0, len(lines)-1)
+	for _, l := range lines[1:] {
+		f := strings.Fields(l)
+		if len(f) < 9 {
+			continue
+		}
+		p := proto.ProcessInfo{User: f[2], State: f[6]}
+		p.PID, _ = strconv.Atoi(f[0])
+		p.PPID, _ = strconv.Atoi(f[1])
+		p.CPU, _ = strconv.ParseFloat(f[3], 64)
+		p.Mem, _ = strconv.ParseFloat(f[4], 64)
+		rss, _ := strconv.ParseUint(f[5], 10, 64)
+		p.RSS = rss * 1024
+		// 实时调度的进程 ni 为 "-"
+		p.Nice, _ = strconv.Atoi(f[7])
+		etimes, _ := strconv.ParseInt(f[8], 10, 64)
+		p.StartTime = now.Add(-time.Duration(etimes) * time.Second)
+		// args 保留原样的空白：在第 9 个字段之后截取
+		p.Command = strings.TrimSpace(cutFields(l, 9))
+		p.Name = processName(p.Command)
+		procs = append(procs, p)
+	}
+	return now, procs, nil
+}
+
+// cutFields 返回跳过前 n 个空白分隔字段后的剩余部分
+func cutFields(s string, n int) string {
+	for range n {
+		s = strings.TrimLeft(s, " \t")
+		i := strings.IndexAny(s, " \t")
+		if i < 0 {
+			return ""
+		}
+		s = s[i:]
+	}
+	return s
+}
+
+// processName 取命令行第一个词的文件名；内核线程的 [名称] 原样返回
+func processName(cmd string) string {
+	if strings.HasPrefix(cmd, "[") {
+		return cmd
+	}
+	argv0, _, _ := strings.Cut(cmd, " ")
+	return path.Base(argv0)
+}
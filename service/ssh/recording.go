@@ -0,0 +1,292 @@
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"go-ssh/proto"
+)
+
+// recordingID 录像 ID 只含文件名安全的字符，防止 get_recording 越出录像目录
+var recordingID = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// RecordingStore 管理会话录像目录：每个 shell 会话一个 asciicast v2 文件 <ID>.cast，
+// 元数据写在文件首行的头部中，不另存索引。
+type RecordingStore struct {
+	Dir string
+	// Mode 为 proto.RecordOff、RecordOutput 或 RecordInput
+	Mode string
+
+	mu     sync.Mutex
+	active map[string]bool
+}
+
+// NewRecordingStore 以 dir 为录像目录
+func NewRecordingStore(dir, mode string) *RecordingStore {
+	return &RecordingStore{Dir: dir, Mode: mode, active: make(map[string]bool)}
+}
+
+// castHeader asciicast v2 头部；session_id 等为本项目的扩展字段，播放器会忽略
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+	SessionID string            `json:"session_id,omitempty"`
+	ConnID    string            `json:"conn_id,omitempty"`
+	Input     bool              `json:"input,omitempty"`
+}
+
+// Recorder 将一个 shell 会话写入 .cast 文件；nil 表示不录像，各方法均可安全调用
+type Recorder struct {
+	store   *RecordingStore
+	id      string
+	f       *os.File
+	start   time.Time
+	input   bool
+	mu      sync.Mutex
+	pending map[string][]byte // 各事件类型尚未凑成完整 UTF-8 字符的尾部字节
+}
+
+// Start 为会话创建录像文件；Mode 为 off 时返回 nil
+func (r *RecordingStore) Start(sh *Shell, host string, term string, cols, rows int) (*Recorder, error) {
+	if r == nil || r.Mode == "" || r.Mode == proto.RecordOff {
+		return nil, nil
+	}
+	if err := os.MkdirAll(r.Dir, 0o700); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	id := now.UTC().Format("20060102-150405") + "-" + safeName(sh.ID)
+	f, err := os.OpenFile(filepath.Join(r.Dir, id+".cast"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	h := castHeader{
+		Version: 2, Width: cols, Height: rows, Timestamp: now.Unix(),
+		Title: host, Env: map[string]string{"TERM": term},
+		SessionID: sh.ID, ConnID: sh.ConnID, Input: r.Mode == proto.RecordInput,
+	}
+	line, _ := json.Marshal(h)
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.mu.Lock()
+	r.active[id] = true
+	r.mu.Unlock()
+	return &Recorder{store: r, id: id, f: f, start: now, input: h.Input, pending: make(map[string][]byte)}, nil
+}
+
+// Output 记录远端输出
+func (rec *Recorder) Output(p []byte) { rec.event("o", p) }
+
+// Input 记录键盘输入；Mode 不是 input 时忽略
+func (rec *Recorder) Input(p []byte) {
+	if rec != nil && rec.input {
+		rec.event("i", p)
+	}
+}
+
+// Resize 记录终端尺寸变化
+func (rec *Recorder) Resize(cols, rows int) {
+	rec.event("r", []byte(fmt.Sprintf("%dx%d", cols, rows)))
+}
+
+// Close 写入剩余字节并关闭文件
+func (rec *Recorder) Close() {
+	if rec == nil {
+		return
+	}
+	rec.mu.Lock()
+	for code, rest := range rec.pending {
+		rec.write(code, rest)
+	}
+	rec.f.Close()
+	rec.f = nil
+	rec.mu.Unlock()
+	rec.store.mu.Lock()
+	delete(rec.store.active, rec.id)
+	rec.store.mu.Unlock()
+}
+
+func (rec *Recorder) event(code string, p []byte) {
+	if rec == nil || len(p) == 0 {
+		return
+	}
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if rec.f == nil {
+		return
+	}
+	// 多字节字符可能被拆在两次读取之间，留到下次拼接，避免写出替换字符
+	data := append(rec.pending[code], p...)
+	cut := len(data)
+	for i := 1; i < utf8.UTFMax && i <= len(data); i++ {
+		if c := data[len(data)-i]; utf8.RuneStart(c) {
+			if !utf8.FullRune(data[len(data)-i:]) {
+				cut = len(data) - i
+			}
+			break
+		}
+	}
+	rec.pending[code] = slices.Clone(data[cut:])
+	rec.write(code, data[:cut])
+}
+
+func (rec *Recorder) write(code string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	t := time.Since(rec.start).Round(time.Microsecond).Seconds()
+	line, _ := json.Marshal([]any{t, code, string(data)})
+	// 写入失败（如磁盘已满）不影响会话本身
+	_, _ = rec.f.Write(append(line, '\n'))
+}
+
+// List 返回全部录像，connID 非空时只返回该连接的录像，按开始时间从新到旧
+func (r *RecordingStore) List(connID string) ([]proto.RecordingInfo, error) {
+	if r == nil {
+		return []proto.RecordingInfo{}, nil
+	}
+	entries, err := os.ReadDir(r.Dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return []proto.RecordingInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := []proto.RecordingInfo{}
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".cast")
+		if !ok || e.IsDir() {
+			continue
+		}
+		info, err := r.info(id)
+		if err != nil {
+			// 损坏或非本程序写入的文件不影响其余录像
+			continue
+		}
+		if connID == "" || info.ConnID == connID {
+			out = append(out, info)
+		}
+	}
+	slices.SortFunc(out, func(a, b proto.RecordingInfo) int { return b.StartedAt.Compare(a.StartedAt) })
+	return out, nil
+}
+
+// Read 从 req.Offset 起读取录像文件的一段
+func (r *RecordingStore) Read(req proto.GetRecordingRequest) (proto.GetRecordingResponse, error) {
+	if r == nil {
+		return proto.GetRecordingResponse{}, fmt.Errorf("%w: recording is not enabled", ErrInvalid)
+	}
+	if !recordingID.MatchString(req.ID) || req.Offset < 0 {
+		return proto.GetRecordingResponse{}, fmt.Errorf("%w: invalid recording id or offset", ErrInvalid)
+	}
+	limit := req.Limit
+	switch {
+	case limit <= 0:
+		limit = proto.DefaultRecordingChunk
+	case limit > proto.MaxRecordingChunk:
+		limit = proto.MaxRecordingChunk
+	}
+	info, err := r.info(req.ID)
+	if errors.Is(err, fs.ErrNotExist) {
+		return proto.GetRecordingResponse{}, fmt.Errorf("%w: recording %q not found", ErrInvalid, req.ID)
+	}
+	if err != nil {
+		return proto.GetRecordingResponse{}, err
+	}
+	f, err := os.Open(r.path(req.ID))
+	if err != nil {
+		return proto.GetRecordingResponse{}, err
+	}
+	defer f.Close()
+	buf := make([]byte, limit)
+	n, err := f.ReadAt(buf, req.Offset)
+	if err != nil && err != io.EOF {
+		return proto.GetRecordingResponse{}, err
+	}
+	return proto.GetRecordingResponse{
+		Recording: info, Offset: req.Offset, Data: buf[:n],
+		EOF: req.Offset+int64(n) >= info.Size,
+	}, nil
+}
+
+func (r *RecordingStore) path(id string) string {
+	return filepath.Join(r.Dir, id+".cast")
+}
+
+// info 解析头部并取最后一个事件的时间作为时长
+func (r *RecordingStore) info(id string) (proto.RecordingInfo, error) {
+	f, err := os.Open(r.path(id))
+	if err != nil {
+		return proto.RecordingInfo{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return proto.RecordingInfo{}, err
+	}
+	first, err := bufio.NewReader(f).ReadBytes('\n')
+	if err != nil {
+		return proto.RecordingInfo{}, fmt.Errorf("read header: %w", err)
+	}
+	var h castHeader
+	if err := json.Unmarshal(first, &h); err != nil || h.Version != 2 {
+		return proto.RecordingInfo{}, fmt.Errorf("%s: not an asciicast v2 file", id)
+	}
+	r.mu.Lock()
+	active := r.active[id]
+	r.mu.Unlock()
+	return proto.RecordingInfo{
+		ID: id, SessionID: h.SessionID, ConnID: h.ConnID, Host: h.Title,
+		Width: h.Width, Height: h.Height, StartedAt: time.Unix(h.Timestamp, 0).UTC(),
+		Duration: lastEventTime(f, st.Size()), Size: st.Size(), Input: h.Input, Active: active,
+	}, nil
+}
+
+// lastEventTime 从文件末尾向前查找最后一个完整事件行并返回其时间
+func lastEventTime(f *os.File, size int64) float64 {
+	const tail = 64 * 1024
+	off := max(size-tail, 0)
+	buf := make([]byte, size-off)
+	n, _ := f.ReadAt(buf, off)
+	lines := bytes.Split(bytes.TrimRight(buf[:n], "\n"), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var ev []any
+		if json.Unmarshal(lines[i], &ev) != nil || len(ev) < 1 {
+			continue
+		}
+		if t, ok := ev[0].(float64); ok {
+			return t
+		}
+	}
+	return 0
+}
+
+// safeName 将会话 ID 中文件名不安全的字符替换为 _
+func safeName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', strings.ContainsRune("._-", r):
+			return r
+		}
+		return '_'
+	}, s)
+}
@@ -0,0 +1,409 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// sessionLogTime 日志每行开头的时间格式
+const sessionLogTime = "2006-01-02 15:04:05.000"
+
+// 会话日志轮转的默认值
+const (
+	DefaultSessionLogMaxSize = 10 << 20
+	DefaultSessionLogMaxAge  = 30 * 24 * time.Hour
+)
+
+// SessionLogStore 管理会话文本日志：启用了 SessionLog 的连接配置各自一个目录 <Dir>/<配置 ID>，
+// 当前文件为 session.log，超过 MaxSize 或跨天时改名为 session-<该文件第一行的时间>.log；
+// 修改时间早于 MaxAge 的旧文件在轮转时删除。同一配置的多个会话写入同一文件，以会话 ID 区分。
+type SessionLogStore struct {
+	Dir     string
+	MaxSize int64
+	MaxAge  time.Duration
+
+	mu    sync.Mutex
+	files map[string]*logFile
+}
+
+type logFile struct {
+	dir   string
+	f     *os.File
+	size  int64
+	start time.Time // 当前文件第一行的时间，跨天后轮转，轮转后的文件名取该时间
+	refs  int
+}
+
+// NewSessionLogStore 以 dir 为会话日志目录；maxSize、maxAge 为 0 时使用默认值
+func NewSessionLogStore(dir string, maxSize int64, maxAge time.Duration) *SessionLogStore {
+	if maxSize <= 0 {
+		maxSize = DefaultSessionLogMaxSize
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultSessionLogMaxAge
+	}
+	return &SessionLogStore{Dir: dir, MaxSize: maxSize, MaxAge: maxAge, files: make(map[string]*logFile)}
+}
+
+// SessionLog 将一个 shell 会话的输出去除 ANSI 控制序列后按行写入日志，每行带时间戳；
+// nil 表示不记录，各方法均可安全调用
+type SessionLog struct {
+	store     *SessionLogStore
+	profileID string
+	sessionID string
+
+	mu    sync.Mutex
+	strip ansiStripper
+	line  []rune
+	col   int // 光标在当前行中的位置，\r 与退格会回退
+}
+
+// Open 为会话打开配置 profileID 的日志
+func (s *SessionLogStore) Open(profileID, sessionID, host string) (*SessionLog, error) {
+	if s == nil {
+		return nil, nil
+	}
+	s.mu.Lock()
+	lf := s.files[profileID]
+	if lf == nil {
+		lf = &logFile{dir: filepath.Join(s.Dir, safeName(profileID))}
+		if err := s.open(lf); err != nil {
+			s.mu.Unlock()
+			return nil, err
+		}
+		s.files[profileID] = lf
+	}
+	lf.refs++
+	s.mu.Unlock()
+	l := &SessionLog{store: s, profileID: profileID, sessionID: sessionID}
+	l.writeLine(time.Now(), "=== session started: "+host+" ===")
+	return l, nil
+}
+
+// openSessionLog 以配置 ID 为连接 ID 且启用了 SessionLog 时为 sh 打开日志，否则返回 nil
+func (m *Manager) openSessionLog(sh *Shell, host string) (*SessionLog, error) {
+	if m.SessionLogs == nil || m.Profiles == nil {
+		return nil, nil
+	}
+	profiles, err := m.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range profiles {
+		if p.ID == sh.ConnID && p.SessionLog {
+			return m.SessionLogs.Open(p.ID, sh.ID, host)
+		}
+	}
+	return nil, nil
+}
+
+// Write 记录远端输出
+func (l *SessionLog) Write(p []byte) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for _, r := range l.strip.feed(p) {
+		switch r {
+		case '\n':
+			l.writeLine(now, string(l.line))
+			l.line, l.col = l.line[:0], 0
+		case '\r':
+			l.col = 0
+		case '\b':
+			l.col = max(l.col-1, 0)
+		case ansiEraseLine:
+			l.line = l.line[:min(l.col, len(l.line))]
+		default:
+			if l.col < len(l.line) {
+				l.line[l.col] = r
+			} else {
+				l.line = append(l.line, r)
+			}
+			l.col++
+		}
+	}
+}
+
+// Close 写出未结束的行与结束标记，最后一个会话关闭时关闭文件
+func (l *SessionLog) Close(status string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if len(l.line) > 0 {
+		l.writeLine(now, string(l.line))
+	}
+	l.writeLine(now, "=== session ended: "+status+" ===")
+	l.mu.Unlock()
+
+	s := l.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if lf := s.files[l.profileID]; lf != nil {
+		if lf.refs--; lf.refs == 0 {
+			lf.f.Close()
+			delete(s.files, l.profileID)
+		}
+	}
+}
+
+func (l *SessionLog) writeLine(t time.Time, text string) {
+	line := fmt.Sprintf("%s [%s] %s\n", t.Format(sessionLogTime), l.sessionID, strings.TrimRight(text, " "))
+	s := l.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lf := s.files[l.profileID]
+	if lf == nil {
+		return
+	}
+	if lf.size > 0 && (lf.size+int64(len(line)) > s.MaxSize || t.Format(time.DateOnly) != lf.start.Format(time.DateOnly)) {
+		if err := s.rotate(lf); err != nil {
+			// 轮转失败时继续写入当前文件
+			log.Printf("rotate session log %s: %v", lf.dir, err)
+		}
+	}
+	if lf.size == 0 {
+		lf.start = t
+	}
+	n, _ := lf.f.WriteString(line)
+	lf.size += int64(n)
+}
+
+// open 打开 lf 的当前文件；已有文件始于前一天或更早时先轮转
+func (s *SessionLogStore) open(lf *logFile) error {
+	if err := os.MkdirAll(lf.dir, 0o700); err != nil {
+		return err
+	}
+	name := filepath.Join(lf.dir, "session.log")
+	if st, err := os.Stat(name); err == nil && st.Size() > 0 {
+		start := segmentStart(name, st.ModTime())
+		if start.Format(time.DateOnly) != time.Now().Format(time.DateOnly) {
+			if err := os.Rename(name, rotatedName(lf.dir, start)); err != nil {
+				return err
+			}
+		}
+	}
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	lf.f, lf.size, lf.start = f, st.Size(), time.Now()
+	if lf.size > 0 {
+		lf.start = segmentStart(name, st.ModTime())
+	}
+	s.prune(lf.dir)
+	return nil
+}
+
+// segmentStart 返回日志文件第一行的时间戳；无法解析时返回 fallback
+func segmentStart(name string, fallback time.Time) time.Time {
+	f, err := os.Open(name)
+	if err != nil {
+		return fallback
+	}
+	defer f.Close()
+	buf := make([]byte, len(sessionLogTime))
+	if _, err := io.ReadFull(f, buf); err != nil {
+		return fallback
+	}
+	t, err := time.ParseInLocation(sessionLogTime, string(buf), time.Local)
+	if err != nil {
+		return fallback
+	}
+	return t
+}
+
+// rotate 将当前文件按其起始时间改名并重新打开，随后清理过期文件
+func (s *SessionLogStore) rotate(lf *logFile) error {
+	lf.f.Close()
+	name := filepath.Join(lf.dir, "session.log")
+	if err := os.Rename(name, rotatedName(lf.dir, lf.start)); err != nil {
+		// 改名失败时重新打开原文件，避免后续写入失败
+		f, oerr := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if oerr == nil {
+			lf.f = f
+		}
+		return err
+	}
+	return s.open(lf)
+}
+
+// prune 删除修改时间早于 MaxAge 的已轮转文件
+func (s *SessionLogStore) prune(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-s.MaxAge)
+	for _, e := range entries {
+		if !strings.HasPrefix(e.Name(), "session-") || !strings.HasSuffix(e.Name(), ".log") {
+			continue
+		}
+		if info, err := e.Info(); err == nil && info.ModTime().Before(cutoff) {
+			_ = os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// rotatedName 返回轮转后的文件名，同一秒内多次轮转时追加序号
+func rotatedName(dir string, t time.Time) string {
+	base := filepath.Join(dir, "session-"+t.Format("20060102-150405"))
+	name := base + ".log"
+	for i := 1; ; i++ {
+		if _, err := os.Stat(name); errors.Is(err, fs.ErrNotExist) {
+			return name
+		}
+		name = base + "-" + strconv.Itoa(i) + ".log"
+	}
+}
+
+// ansiEraseLine 由 ansiStripper 输出，表示 CSI K（清除到行尾）
+const ansiEraseLine = utf8.MaxRune + 1
+
+// ansiStripper 逐字节去除终端控制序列（CSI、OSC、DCS 等），状态跨调用保留；
+// 输出可见字符以及 \n、\r、\t、\b，CSI K 输出为 ansiEraseLine
+type ansiStripper struct {
+	state   int
+	params  []byte
+	pending []byte // 尚未凑成完整 UTF-8 字符的字节
+}
+
+// maxCSIParams CSI 参数与中间字节的长度上限
+const maxCSIParams = 64
+
+const (
+	ansiGround = iota
+	ansiEsc
+	ansiEscInter
+	ansiCSI
+	ansiCSIDiscard // CSI 参数超出 maxCSIParams，丢弃到结束字节为止
+	ansiOSC
+	ansiString // DCS、SOS、PM、APC，以 ST 结束
+	ansiStringEsc
+)
+
+func (a *ansiStripper) feed(p []byte) []rune {
+	var out []rune
+	data := append(a.pending, p...)
+	a.pending = nil
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch a.state {
+		case ansiGround:
+			switch {
+			case c == 0x1b:
+				a.state = ansiEsc
+			case c == '\n' || c == '\r' || c == '\t' || c == '\b':
+				out = append(out, rune(c))
+			case c < 0x20 || c == 0x7f:
+			case c < utf8.RuneSelf:
+				out = append(out, rune(c))
+			default:
+				if !utf8.FullRune(data[i:]) {
+					a.pending = append(a.pending, data[i:]...)
+					return out
+				}
+				r, n := utf8.DecodeRune(data[i:])
+				out = append(out, r)
+				i += n
+				continue
+			}
+		case ansiEsc:
+			switch {
+			case c == '[':
+				a.state, a.params = ansiCSI, a.params[:0]
+			case c == ']':
+				a.state = ansiOSC
+			case c == 'P' || c == 'X' || c == '^' || c == '_':
+				a.state = ansiString
+			case c >= 0x20 && c <= 0x2f:
+				a.state = ansiEscInter
+			default:
+				a.state = ansiGround
+			}
+		case ansiEscInter:
+			if c < 0x20 || c > 0x2f {
+				a.state = ansiGround
+			}
+		case ansiCSI:
+			switch {
+			case c >= 0x40 && c <= 0x7e:
+				a.state = ansiGround
+				out = append(out, csiRunes(c, string(a.params))...)
+			case c == 0x1b:
+				// 新的转义序列打断未结束的 CSI
+				a.state = ansiEsc
+			case c < 0x20 || c > 0x7e:
+				// 非法字节：放弃该序列
+				a.state = ansiGround
+			case len(a.params) >= maxCSIParams:
+				// 参数过长：丢弃其余字节直到结束字节
+				a.state = ansiCSIDiscard
+			default:
+				a.params = append(a.params, c)
+			}
+		case ansiCSIDiscard:
+			switch {
+			case c >= 0x40 && c <= 0x7e:
+				a.state = ansiGround
+			case c == 0x1b:
+				a.state = ansiEsc
+			case c < 0x20 || c > 0x7e:
+				a.state = ansiGround
+			}
+		case ansiOSC:
+			switch c {
+			case 0x07:
+				a.state = ansiGround
+			case 0x1b:
+				a.state = ansiStringEsc
+			}
+		case ansiString:
+			if c == 0x1b {
+				a.state = ansiStringEsc
+			}
+		case ansiStringEsc:
+			// ESC \ 结束字符串；其他情况同样回到初始状态
+			a.state = ansiGround
+		}
+		i++
+	}
+	return out
+}
+
+// csiRunes 将影响行内容的 CSI 序列转换为等价的输出：K 清除到行尾，
+// D 左移以退格表示；其余（颜色、光标定位等）丢弃
+func csiRunes(final byte, params string) []rune {
+	switch final {
+	case 'K':
+		if params == "" || params == "0" {
+			return []rune{ansiEraseLine}
+		}
+	case 'D':
+		n, err := strconv.Atoi(params)
+		if err != nil || n < 1 {
+			n = 1
+		}
+		return []rune(strings.Repeat("\b", min(n, 1024)))
+	}
+	return nil
+}
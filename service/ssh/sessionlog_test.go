@@ -0,0 +1,70 @@
+package ssh
+
+import (
+	"strings"
+	"testing"
+)
+
+// stripString 按 chunks 分次喂入，ansiEraseLine 显示为 <K>
+func stripString(chunks ...string) string {
+	var a ansiStripper
+	var b strings.Builder
+	for _, c := range chunks {
+		for _, r := range a.feed([]byte(c)) {
+			if r == ansiEraseLine {
+				b.WriteString("<K>")
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func TestAnsiStripper(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain", "hello\r\n", "hello\r\n"},
+		{"sgr", "\x1b[1;31mred\x1b[0m", "red"},
+		{"erase line", "abc\x1b[Kd\x1b[0Ke\x1b[2Kf", "abc<K>d<K>ef"},
+		{"cursor left", "abc\x1b[2Dx\x1b[Dy", "abc\b\bx\by"},
+		{"private mode", "\x1b[?2004hprompt$ \x1b[?2004l", "prompt$ "},
+		{"osc title bel", "\x1b]0;user@host: ~\x07$ ", "$ "},
+		{"osc title st", "\x1b]2;title\x1b\\$ ", "$ "},
+		{"dcs", "\x1bPq#0;2;0;0;0\x1b\\done", "done"},
+		{"charset designation", "\x1b(Bok", "ok"},
+		{"two-byte escape", "\x1b=\x1b>ok", "ok"},
+		{"control bytes dropped", "a\x00\x07\x7fb\tc\bd", "ab\tc\bd"},
+		{"utf8", "héllo 世界", "héllo 世界"},
+		{"esc interrupts csi", "\x1b[12\x1b[31mx", "x"},
+		{"invalid byte ends csi", "\x1b[1\x01x", "x"},
+		{"overlong csi", "\x1b[" + strings.Repeat("1", 64) + "23mok", "ok"},
+		{"overlong csi with intermediates", "\x1b[" + strings.Repeat(";", 200) + " qok", "ok"},
+		{"esc interrupts overlong csi", "\x1b[" + strings.Repeat("1", 100) + "\x1b[31mok", "ok"},
+		{"invalid byte ends overlong csi", "\x1b[" + strings.Repeat("1", 100) + "\x01ok", "ok"},
+		{"csi within limit", "\x1b[" + strings.Repeat("1", 63) + "mok", "ok"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stripString(tt.in); got != tt.want {
+				t.Fatalf("feed(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			// 序列与多字节字符可能在任意位置被拆到两次写入中
+			for i := 1; i < len(tt.in); i++ {
+				if got := stripString(tt.in[:i], tt.in[i:]); got != tt.want {
+					t.Fatalf("split at %d: feed(%q, %q) = %q, want %q", i, tt.in[:i], tt.in[i:], got, tt.want)
+				}
+			}
+			chunks := make([]string, len(tt.in))
+			for i := 0; i < len(tt.in); i++ {
+				chunks[i] = tt.in[i : i+1]
+			}
+			if got := stripString(chunks...); got != tt.want {
+				t.Fatalf("byte by byte: %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
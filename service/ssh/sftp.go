@@ -0,0 +1,417 @@
+package ssh
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-ssh/proto"
+
+	"github.com/pkg/sftp"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sftpConn 缓存在连接上的 SFTP 子系统会话；ssh 用于识别重连后的失效缓存
+type sftpConn struct {
+	ssh    *gossh.Client
+	client *sftp.Client
+}
+
+// SFTP 返回 connID 对应连接上的 SFTP 客户端，首次使用时启动 sftp 子系统并缓存；
+// 底层连接断开或重连后自动重建
+func (m *Manager) SFTP(connID string) (*sftp.Client, error) {
+	client, err := m.Client(connID)
+	if err != nil {
+		return nil, err
+	}
+	if c := m.cachedSFTP(connID, client); c != nil {
+		return c, nil
+	}
+	// 启动子系统需要一次往返，不持锁进行；并发创建时保留先登记的一个
+	c, err := sftp.NewClient(client)
+	if err != nil {
+		return nil, fmt.Errorf("start sftp subsystem: %w", err)
+	}
+	m.mu.Lock()
+	if s, ok := m.sftps[connID]; ok && s.ssh == client {
+		m.mu.Unlock()
+		_ = c.Close()
+		return s.client, nil
+	}
+	s := &sftpConn{ssh: client, client: c}
+	m.sftps[connID] = s
+	m.mu.Unlock()
+
+	untrack := m.track(c)
+	go func() {
+		_ = c.Wait()
+		untrack()
+		m.mu.Lock()
+		if m.sftps[connID] == s {
+			delete(m.sftps, connID)
+		}
+		m.mu.Unlock()
+	}()
+	return c, nil
+}
+
+func (m *Manager) cachedSFTP(connID string, client *gossh.Client) *sftp.Client {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.sftps[connID]; ok && s.ssh == client {
+		return s.client
+	}
+	return nil
+}
+
+// ListDir 列出目录，目录（含指向目录的符号链接）在前，其余按名称排序；
+// 符号链接逐个解析指向，ctx 取消时停止并返回 ctx.Err()
+func (m *Manager) ListDir(ctx context.Context, req proto.SFTPPathRequest) (proto.SFTPListResponse, error) {
+	c, err := m.SFTP(req.ConnID)
+	if err != nil {
+		return proto.SFTPListResponse{}, err
+	}
+	dir, err := remotePath(c, req.Path)
+	if err != nil {
+		return proto.SFTPListResponse{}, err
+	}
+	infos, err := c.ReadDir(dir)
+	if err != nil {
+		return proto.SFTPListResponse{}, fmt.Errorf("list %s: %w", dir, err)
+	}
+	entries := make([]proto.FileEntry, 0, len(infos))
+	for _, fi := range infos {
+		if err := ctx.Err(); err != nil {
+			return proto.SFTPListResponse{}, err
+		}
+		entries = append(entries, fileEntry(c, path.Join(dir, fi.Name()), fi))
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		a, b := strings.ToLower(entries[i].Name), strings.ToLower(entries[j].Name)
+		if a != b {
+			return a < b
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return proto.SFTPListResponse{Path: dir, Entries: entries}, nil
+}
+
+// Stat 返回单个路径的信息；符号链接返回链接本身并附带指向
+func (m *Manager) Stat(req proto.SFTPPathRequest) (proto.FileEntry, error) {
+	c, err := m.SFTP(req.ConnID)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	p, err := remotePath(c, req.Path)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	return lstatEntry(c, p)
+}
+
+// Mkdir 创建目录，Mode 非空时随后设置权限
+func (m *Manager) Mkdir(req proto.SFTPMkdirRequest) (proto.FileEntry, error) {
+	var perm os.FileMode
+	if req.Mode != "" {
+		var err error
+		if perm, err = parseFileMode(req.Mode); err != nil {
+			return proto.FileEntry{}, err
+		}
+	}
+	c, p, err := m.sftpPath(req.ConnID, req.Path)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	if req.Parents {
+		err = c.MkdirAll(p)
+	} else {
+		err = c.Mkdir(p)
+	}
+	if err != nil {
+		return proto.FileEntry{}, fmt.Errorf("mkdir %s: %w", p, err)
+	}
+	if req.Mode != "" {
+		if err := c.Chmod(p, perm); err != nil {
+			return proto.FileEntry{}, fmt.Errorf("chmod %s: %w", p, err)
+		}
+	}
+	return lstatEntry(c, p)
+}
+
+// Rename 重命名或移动；Overwrite 时使用 posix-rename 扩展，目标已存在则被替换
+func (m *Manager) Rename(req proto.SFTPRenameRequest) (proto.FileEntry, error) {
+	c, from, err := m.sftpPath(req.ConnID, req.From)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	to, err := requirePath(c, req.To)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	if req.Overwrite {
+		err = c.PosixRename(from, to)
+	} else {
+		err = c.Rename(from, to)
+	}
+	if err != nil {
+		return proto.FileEntry{}, fmt.Errorf("rename %s -> %s: %w", from, to, err)
+	}
+	return lstatEntry(c, to)
+}
+
+// Remove 删除文件、符号链接或目录；非空目录需 Recursive，符号链接只删除链接本身。
+// 递归删除时 ctx 取消即停止，已删除的条目不会恢复
+func (m *Manager) Remove(ctx context.Context, req proto.SFTPRemoveRequest) error {
+	c, p, err := m.sftpPath(req.ConnID, req.Path)
+	if err != nil {
+		return err
+	}
+	if p == "/" {
+		return fmt.Errorf("%w: refusing to remove /", ErrInvalid)
+	}
+	fi, err := c.Lstat(p)
+	if err != nil {
+		return fmt.Errorf("remove %s: %w", p, err)
+	}
+	switch {
+	case !fi.IsDir():
+		err = c.Remove(p)
+	case req.Recursive:
+		err = removeTree(ctx, c, p, fi)
+	default:
+		err = c.RemoveDirectory(p)
+	}
+	if err != nil {
+		return fmt.Errorf("remove %s: %w", p, err)
+	}
+	return nil
+}
+
+// removeTree 深度优先删除 p 及其内容，不跟随符号链接；每个条目前检查 ctx，
+// 删除期间已被他人移除的条目忽略
+func removeTree(ctx context.Context, c *sftp.Client, p string, fi os.FileInfo) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return ignoreNotExist(c.Remove(p))
+	}
+	infos, err := c.ReadDir(p)
+	if err != nil {
+		return ignoreNotExist(err)
+	}
+	for _, child := range infos {
+		if err := removeTree(ctx, c, path.Join(p, child.Name()), child); err != nil {
+			return err
+		}
+	}
+	return ignoreNotExist(c.RemoveDirectory(p))
+}
+
+func ignoreNotExist(err error) error {
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// Chmod 修改权限位（含 setuid / setgid / sticky）
+func (m *Manager) Chmod(req proto.SFTPChmodRequest) (proto.FileEntry, error) {
+	perm, err := parseFileMode(req.Mode)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	c, p, err := m.sftpPath(req.ConnID, req.Path)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	if err := c.Chmod(p, perm); err != nil {
+		return proto.FileEntry{}, fmt.Errorf("chmod %s: %w", p, err)
+	}
+	return lstatEntry(c, p)
+}
+
+// Symlink 创建符号链接；Target 原样写入（可为相对路径），Link 按远端路径解析
+func (m *Manager) Symlink(req proto.SFTPSymlinkRequest) (proto.FileEntry, error) {
+	if strings.TrimSpace(req.Target) == "" {
+		return proto.FileEntry{}, fmt.Errorf("%w: target is required", ErrInvalid)
+	}
+	c, link, err := m.sftpPath(req.ConnID, req.Link)
+	if err != nil {
+		return proto.FileEntry{}, err
+	}
+	if err := c.Symlink(req.Target, link); err != nil {
+		return proto.FileEntry{}, fmt.Errorf("symlink %s -> %s: %w", link, req.Target, err)
+	}
+	return lstatEntry(c, link)
+}
+
+// sftpPath 取得 SFTP 客户端并解析必填的远端路径
+func (m *Manager) sftpPath(connID, p string) (*sftp.Client, string, error) {
+	if strings.TrimSpace(p) == "" {
+		return nil, "", fmt.Errorf("%w: path is required", ErrInvalid)
+	}
+	c, err := m.SFTP(connID)
+	if err != nil {
+		return nil, "", err
+	}
+	p, err = remotePath(c, p)
+	if err != nil {
+		return nil, "", err
+	}
+	return c, p, nil
+}
+
+func requirePath(c *sftp.Client, p string) (string, error) {
+	if strings.TrimSpace(p) == "" {
+		return "", fmt.Errorf("%w: path is required", ErrInvalid)
+	}
+	return remotePath(c, p)
+}
+
+// remotePath 将路径解析为远端绝对路径：空路径与 ~ 为用户主目录，
+// ~/ 开头与相对路径相对主目录（SFTP 会话的初始工作目录）
+func remotePath(c *sftp.Client, p string) (string, error) {
+	p = strings.TrimSpace(p)
+	if strings.HasPrefix(p, "/") {
+		return path.Clean(p), nil
+	}
+	home, err := c.Getwd()
+	if err != nil {
+		return "", fmt.Errorf("resolve home directory: %w", err)
+	}
+	switch {
+	case p == "" || p == "~":
+		return path.Clean(home), nil
+	case strings.HasPrefix(p, "~/"):
+		p = p[2:]
+	}
+	return path.Join(home, p), nil
+}
+
+// parseFileMode 解析八进制权限字符串，如 "644"、"0755"、"1777"
+func parseFileMode(s string) (os.FileMode, error) {
+	v, err := strconv.ParseUint(strings.TrimSpace(s), 8, 32)
+	if err != nil || v > 0o7777 {
+		return 0, fmt.Errorf("%w: mode %q is not an octal permission", ErrInvalid, s)
+	}
+	mode := os.FileMode(v & 0o777)
+	if v&0o4000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if v&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if v&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+func lstatEntry(c *sftp.Client, p string) (proto.FileEntry, error) {
+	fi, err := c.Lstat(p)
+	if err != nil {
+		return proto.FileEntry{}, fmt.Errorf("stat %s: %w", p, err)
+	}
+	return fileEntry(c, p, fi), nil
+}
+
+// fileEntry 转换文件信息；符号链接额外读取指向并跟随一次以判断是否为目录，
+// 失效链接视为普通文件
+func fileEntry(c *sftp.Client, p string, fi os.FileInfo) proto.FileEntry {
+	mode := fi.Mode()
+	e := proto.FileEntry{
+		Name:    fi.Name(),
+		Path:    p,
+		Size:    fi.Size(),
+		Mode:    modeString(mode),
+		Perm:    fmt.Sprintf("%04o", permBits(mode)),
+		ModTime: fi.ModTime().UTC(),
+	}
+	if p == "/" {
+		e.Name = "/"
+	}
+	switch {
+	case mode.IsDir():
+		e.Type, e.IsDir = proto.FileTypeDir, true
+	case mode&os.ModeSymlink != 0:
+		e.Type = proto.FileTypeSymlink
+		if target, err := c.ReadLink(p); err == nil {
+			e.LinkTarget = target
+		}
+		if st, err := c.Stat(p); err == nil {
+			e.IsDir = st.IsDir()
+		}
+	case mode.IsRegular():
+		e.Type = proto.FileTypeFile
+	default:
+		e.Type = proto.FileTypeOther
+	}
+	return e
+}
+
+// permBits 还原 chmod 使用的 12 位权限
+func permBits(mode os.FileMode) uint32 {
+	v := uint32(mode.Perm())
+	if mode&os.ModeSetuid != 0 {
+		v |= 0o4000
+	}
+	if mode&os.ModeSetgid != 0 {
+		v |= 0o2000
+	}
+	if mode&os.ModeSticky != 0 {
+		v |= 0o1000
+	}
+	return v
+}
+
+// modeString 生成 ls -l 风格的权限串，如 drwxr-xr-x、lrwxrwxrwx、-rwsr-xr-x
+func modeString(mode os.FileMode) string {
+	b := []byte("-rwxrwxrwx")
+	switch {
+	case mode.IsDir():
+		b[0] = 'd'
+	case mode&os.ModeSymlink != 0:
+		b[0] = 'l'
+	case mode&os.ModeNamedPipe != 0:
+		b[0] = 'p'
+	case mode&os.ModeSocket != 0:
+		b[0] = 's'
+	case mode&os.ModeCharDevice != 0:
+		b[0] = 'c'
+	case mode&os.ModeDevice != 0:
+		b[0] = 'b'
+	}
+	for i := 0; i < 9; i++ {
+		if mode&(1<<uint(8-i)) == 0 {
+			b[i+1] = '-'
+		}
+	}
+	special := func(i int, set bool, upper, lower byte) {
+		if !set {
+			return
+		}
+		if b[i] == '-' {
+			b[i] = upper
+		} else {
+			b[i] = lower
+		}
+	}
+	special(3, mode&os.ModeSetuid != 0, 'S', 's')
+	special(6, mode&os.ModeSetgid != 0, 'S', 's')
+	special(9, mode&os.ModeSticky != 0, 'T', 't')
+	return string(b)
+}
+
+// isFileError 判断远端文件操作的错误是否由请求本身引起（路径不存在、已存在或无权限）
+func isFileError(err error) bool {
+	return errors.Is(err, os.ErrNotExist) || errors.Is(err, os.ErrExist) || errors.Is(err, os.ErrPermission)
+}
@@ -0,0 +1,78 @@
+package ssh
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// localSFTP 通过内存管道连接一个服务本地文件系统的 SFTP 服务器
+func localSFTP(t *testing.T) *sftp.Client {
+	t.Helper()
+	cr, sw := io.Pipe()
+	sr, cw := io.Pipe()
+	srv, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{sr, sw})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve() }()
+	c, err := sftp.NewClientPipe(cr, cw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// 先断开服务端的写入端，客户端的接收循环才能退出
+		_ = sw.Close()
+		_ = c.Close()
+	})
+	return c
+}
+
+func TestRemoveTree(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		removed bool
+	}{
+		{"removes everything", context.Background(), true},
+		{"cancelled", cancelled, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeTree(t, dir, "tree/a.txt", "tree/sub/b.txt", "tree/sub/deep/", "outside/keep.txt")
+			// 指向树外的符号链接只删除链接本身
+			if err := os.Symlink(filepath.Join(dir, "outside"), filepath.Join(dir, "tree", "link")); err != nil {
+				t.Fatal(err)
+			}
+			c := localSFTP(t)
+			root := filepath.ToSlash(filepath.Join(dir, "tree"))
+			fi, err := c.Lstat(root)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = removeTree(tt.ctx, c, root, fi)
+			if _, statErr := os.Lstat(filepath.Join(dir, "tree")); os.IsNotExist(statErr) != tt.removed {
+				t.Fatalf("tree removed = %v, want %v (err %v)", !tt.removed, tt.removed, err)
+			}
+			if tt.removed && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.removed && err != context.Canceled {
+				t.Fatalf("err = %v, want context.Canceled", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "outside", "keep.txt")); err != nil {
+				t.Errorf("symlink target touched: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// shellSeq 用于生成进程内唯一的会话 ID
+var shellSeq atomic.Uint64
+
+// Shell 为一个远端交互式 shell 会话（带 PTY）
+type Shell struct {
+	ID     string
+	ConnID string
+	Stdin  io.WriteCloser
+	Stdout io.Reader
+	Stderr io.Reader
+
+	session   *gossh.Session
+	untrack   func()
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// OpenShell 在 req.ConnID 对应的连接上申请 PTY 并启动登录 shell
+func (m *Manager) OpenShell(req proto.OpenShellRequest) (*Shell, error) {
+	client, err := m.Client(req.ConnID)
+	if err != nil {
+		return nil, err
+	}
+	if req.Term == "" {
+		req.Term = "xterm-256color"
+	}
+	if req.Cols <= 0 {
+		req.Cols = 80
+	}
+	if req.Rows <= 0 {
+		req.Rows = 24
+	}
+
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("new session: %w", err)
+	}
+	sh := &Shell{
+		ID:      fmt.Sprintf("%s-%d", req.ConnID, shellSeq.Add(1)),
+		ConnID:  req.ConnID,
+		session: session,
+	}
+	if sh.Stdin, err = session.StdinPipe(); err != nil {
+		session.Close()
+		return nil, err
+	}
+	if sh.Stdout, err = session.StdoutPipe(); err != nil {
+		session.Close()
+		return nil, err
+	}
+	if sh.Stderr, err = session.StderrPipe(); err != nil {
+		session.Close()
+		return nil, err
+	}
+	modes := gossh.TerminalModes{
+		gossh.ECHO:          1,
+		gossh.TTY_OP_ISPEED: 14400,
+		gossh.TTY_OP_OSPEED: 14400,
+	}
+	if err := session.RequestPty(req.Term, req.Rows, req.Cols, modes); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("request pty: %w", err)
+	}
+	if err := session.Shell(); err != nil {
+		session.Close()
+		return nil, fmt.Errorf("start shell: %w", err)
+	}
+	sh.untrack = m.track(sh)
+	return sh, nil
+}
+
+// Resize 通知远端 PTY 尺寸变化
+func (s *Shell) Resize(cols, rows int) error {
+	if cols <= 0 || rows <= 0 {
+		return fmt.Errorf("invalid size %dx%d", cols, rows)
+	}
+	return s.session.WindowChange(rows, cols)
+}
+
+// Wait 阻塞直到远端 shell 退出，并转换为协议退出状态
+func (s *Shell) Wait() proto.ExitStatus {
+	err := s.session.Wait()
+	if err == nil {
+		return proto.ExitStatus{}
+	}
+	var exitErr *gossh.ExitError
+	if errors.As(err, &exitErr) {
+		return proto.ExitStatus{Code: exitErr.ExitStatus(), Signal: exitErr.Signal()}
+	}
+	return proto.ExitStatus{Code: -1, Error: err.Error()}
+}
+
+// Close 先向远端发送 SIGHUP 再关闭会话，远端 shell 随之结束；可重复调用
+func (s *Shell) Close() error {
+	s.closeOnce.Do(func() {
+		_ = s.session.Signal(gossh.SIGHUP)
+		s.closeErr = s.session.Close()
+		if s.untrack != nil {
+			s.untrack()
+		}
+	})
+	return s.closeErr
+}
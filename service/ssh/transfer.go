@@ -0,0 +1,716 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ssh/proto"
+
+	"github.com/pkg/sftp"
+)
+
+// ErrTransferNotFound 表示指定 ID 的传输不存在（或已从历史中移除）
+var ErrTransferNotFound = errors.New("transfer not found")
+
+// transferSeq 用于生成进程内唯一的传输 ID
+var transferSeq atomic.Uint64
+
+const (
+	// DefaultTransferConcurrency 未配置 Manager.TransferConcurrency 时同时进行的传输数
+	DefaultTransferConcurrency = 3
+	// DefaultTransferChunkSize 未配置 Manager.TransferChunkSize 时每次读写的块大小
+	DefaultTransferChunkSize = 256 << 10
+
+	transferProgressInterval = 500 * time.Millisecond // transfer_progress 推送的最小间隔
+	transferHistory          = 50                     // 保留的已结束传输数，超出时移除最早结束的
+)
+
+// Transfer 一个排队的上传或下载任务，源为目录时递归传输其中的文件
+type Transfer struct {
+	m      *Manager
+	req    proto.TransferRequest // 已规范化，RemotePath 为绝对路径
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	info     proto.TransferInfo
+	lastAt   time.Time // 上次推送进度的时间与已传输字节数，用于限频与计算速率
+	lastDone int64
+	untrack  func()
+}
+
+// transferFile 计划中的一个文件，rel 为相对源路径（/ 分隔）
+type transferFile struct {
+	rel, src, dst string
+	size          int64
+}
+
+// StartTransfer 校验请求并加入传输队列，队列按加入顺序执行，同时进行的传输数受 TransferConcurrency 限制
+func (m *Manager) StartTransfer(req proto.TransferRequest) (proto.TransferInfo, error) {
+	if err := normalizeTransfer(&req); err != nil {
+		return proto.TransferInfo{}, err
+	}
+	c, err := m.SFTP(req.ConnID)
+	if err != nil {
+		return proto.TransferInfo{}, err
+	}
+	if req.RemotePath, err = remotePath(c, req.RemotePath); err != nil {
+		return proto.TransferInfo{}, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &Transfer{
+		m:      m,
+		req:    req,
+		ctx:    ctx,
+		cancel: cancel,
+		info: proto.TransferInfo{
+			ID:         fmt.Sprintf("%s-x%d", req.ConnID, transferSeq.Add(1)),
+			ConnID:     req.ConnID,
+			Direction:  req.Direction,
+			LocalPath:  req.LocalPath,
+			RemotePath: req.RemotePath,
+			Resume:     req.Resume,
+			Verify:     req.Verify,
+			State:      proto.TransferQueued,
+			CreatedAt:  time.Now().UTC(),
+		},
+	}
+	m.mu.Lock()
+	m.transfers = append(m.transfers, t)
+	m.mu.Unlock()
+	log.Printf("transfer %s queued: %s %s <-> %s", t.info.ID, req.Direction, req.LocalPath, req.RemotePath)
+	info := t.Info()
+	m.publishTransfer(info)
+	m.runTransfers()
+	return info, nil
+}
+
+// CancelTransfer 取消排队中或进行中的传输；已传输的部分保留在目标处，可用 resume 续传
+func (m *Manager) CancelTransfer(id string) (proto.TransferInfo, error) {
+	t := m.transfer(id)
+	if t == nil {
+		return proto.TransferInfo{}, fmt.Errorf("%w: %s", ErrTransferNotFound, id)
+	}
+	t.cancel()
+	t.mu.Lock()
+	queued := t.info.State == proto.TransferQueued
+	t.mu.Unlock()
+	if queued {
+		// 尚未开始的传输直接结束；进行中的传输在当前块写完后结束并推送 canceled
+		t.finish(context.Canceled)
+	}
+	return t.Info(), nil
+}
+
+// Transfers 返回传输列表（connID 非空时只含该连接上的），按创建顺序排序
+func (m *Manager) Transfers(connID string) []proto.TransferInfo {
+	m.mu.Lock()
+	ts := make([]*Transfer, 0, len(m.transfers))
+	for _, t := range m.transfers {
+		if connID == "" || t.info.ConnID == connID {
+			ts = append(ts, t)
+		}
+	}
+	m.mu.Unlock()
+	out := make([]proto.TransferInfo, 0, len(ts))
+	for _, t := range ts {
+		out = append(out, t.Info())
+	}
+	return out
+}
+
+// Info 返回传输的快照
+func (t *Transfer) Info() proto.TransferInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.info
+}
+
+// Close 取消传输，供 CloseAll 在退出时中止进行中的传输
+func (t *Transfer) Close() error {
+	t.cancel()
+	return nil
+}
+
+func (m *Manager) transfer(id string) *Transfer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range m.transfers {
+		if t.info.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// runTransfers 按加入顺序启动排队中的传输，直到进行中的数量达到上限
+func (m *Manager) runTransfers() {
+	limit := m.TransferConcurrency
+	if limit <= 0 {
+		limit = DefaultTransferConcurrency
+	}
+	var started []*Transfer
+	m.mu.Lock()
+	running := 0
+	for _, t := range m.transfers {
+		t.mu.Lock()
+		if t.info.State == proto.TransferRunning {
+			running++
+		}
+		t.mu.Unlock()
+	}
+	for _, t := range m.transfers {
+		if running >= limit {
+			break
+		}
+		t.mu.Lock()
+		if t.info.State == proto.TransferQueued {
+			now := time.Now().UTC()
+			t.info.State = proto.TransferRunning
+			t.info.StartedAt = &now
+			t.lastAt = time.Now()
+			running++
+			started = append(started, t)
+		}
+		t.mu.Unlock()
+	}
+	m.mu.Unlock()
+
+	for _, t := range started {
+		untrack := m.track(t)
+		t.mu.Lock()
+		t.untrack = untrack
+		t.mu.Unlock()
+		m.publishTransfer(t.Info())
+		go func() {
+			t.finish(t.run())
+			m.runTransfers()
+		}()
+	}
+}
+
+// finish 记录传输结果并推送；取消优先于执行中产生的错误。可重复调用，只生效一次
+func (t *Transfer) finish(err error) {
+	t.mu.Lock()
+	if t.info.FinishedAt != nil {
+		t.mu.Unlock()
+		return
+	}
+	now := time.Now().UTC()
+	t.info.FinishedAt = &now
+	t.info.Current = ""
+	t.info.BytesPerSec = 0
+	switch {
+	case t.ctx.Err() != nil:
+		t.info.State = proto.TransferCanceled
+	case err != nil:
+		t.info.State = proto.TransferFailed
+		t.info.Error = err.Error()
+	default:
+		t.info.State = proto.TransferCompleted
+	}
+	untrack := t.untrack
+	info := t.info
+	t.mu.Unlock()
+	t.cancel()
+	if untrack != nil {
+		untrack()
+	}
+	log.Printf("transfer %s %s: %d/%d files, %d bytes %s", info.ID, info.State, info.FilesDone, info.Files, info.Done, info.Error)
+	t.m.publishTransfer(info)
+	t.m.trimTransfers()
+}
+
+// trimTransfers 只保留最近 transferHistory 个已结束的传输
+func (m *Manager) trimTransfers() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	finished := 0
+	for _, t := range m.transfers {
+		if t.Info().FinishedAt != nil {
+			finished++
+		}
+	}
+	kept := m.transfers[:0]
+	for _, t := range m.transfers {
+		if finished > transferHistory && t.Info().FinishedAt != nil {
+			finished--
+			continue
+		}
+		kept = append(kept, t)
+	}
+	m.transfers = kept
+}
+
+func (m *Manager) publishTransfer(info proto.TransferInfo) {
+	if m.Publish != nil {
+		m.Publish(proto.EventTransferProgress, info)
+	}
+}
+
+// run 扫描源路径生成文件清单，再逐个分块传输并按需校验
+func (t *Transfer) run() error {
+	c, err := t.m.SFTP(t.req.ConnID)
+	if err != nil {
+		return err
+	}
+	src, dst := fileSystem(localFS{}), fileSystem(remoteFS{c})
+	srcRoot, dstRoot := t.req.LocalPath, t.req.RemotePath
+	if t.req.Direction == proto.TransferDownload {
+		src, dst = dst, src
+		srcRoot, dstRoot = dstRoot, srcRoot
+	}
+	dirs, files, err := t.plan(src, dst, srcRoot, dstRoot)
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+	t.mu.Lock()
+	t.info.Files = len(files)
+	t.info.Bytes = total
+	t.mu.Unlock()
+	t.m.publishTransfer(t.Info())
+
+	made := make(map[string]bool)
+	mkdir := func(dir string) error {
+		if made[dir] {
+			return nil
+		}
+		if err := dst.MkdirAll(dir); err != nil {
+			return pathError("mkdir", dir, err)
+		}
+		made[dir] = true
+		return nil
+	}
+	for _, d := range dirs {
+		if err := mkdir(d); err != nil {
+			return err
+		}
+	}
+	chunk := t.m.TransferChunkSize
+	if chunk <= 0 {
+		chunk = DefaultTransferChunkSize
+	}
+	buf := make([]byte, chunk)
+	for _, f := range files {
+		if err := t.ctx.Err(); err != nil {
+			return err
+		}
+		if err := mkdir(dst.Dir(f.dst)); err != nil {
+			return err
+		}
+		t.mu.Lock()
+		t.info.Current = f.rel
+		t.mu.Unlock()
+		if err := t.copyFile(src, dst, f, buf); err != nil {
+			return fmt.Errorf("%s: %w", f.rel, err)
+		}
+		if t.req.Verify {
+			sum, err := t.verify(c, f)
+			if err != nil {
+				return fmt.Errorf("%s: %w", f.rel, err)
+			}
+			if len(files) == 1 {
+				t.mu.Lock()
+				t.info.Checksum = sum
+				t.mu.Unlock()
+			}
+		}
+		t.mu.Lock()
+		t.info.FilesDone++
+		t.mu.Unlock()
+		t.progress(0, true)
+	}
+	return nil
+}
+
+// plan 生成需创建的目录与待传输的文件。源为文件时目标为已存在的目录（或以 / 结尾）则放入其中；
+// 源为目录时按过滤规则遍历，未设置 Include 时同时复制空目录
+func (t *Transfer) plan(src, dst fileSystem, srcRoot, dstRoot string) ([]string, []transferFile, error) {
+	st, err := src.Stat(srcRoot)
+	if err != nil {
+		return nil, nil, pathError("stat", srcRoot, err)
+	}
+	if !st.IsDir() {
+		if !st.Mode().IsRegular() {
+			return nil, nil, fmt.Errorf("%w: %s is not a regular file", ErrInvalid, srcRoot)
+		}
+		target := dstRoot
+		if dst.IsDirHint(dstRoot) {
+			target = dst.Join(dstRoot, st.Name())
+		} else if dt, err := dst.Stat(dstRoot); err == nil && dt.IsDir() {
+			target = dst.Join(dstRoot, st.Name())
+		}
+		return nil, []transferFile{{rel: st.Name(), src: srcRoot, dst: target, size: st.Size()}}, nil
+	}
+
+	dirs := []string{dstRoot}
+	var files []transferFile
+	err = src.Walk(srcRoot, func(p string, fi os.FileInfo) (skip bool, err error) {
+		if err := t.ctx.Err(); err != nil {
+			return false, err
+		}
+		rel := src.Rel(srcRoot, p)
+		if rel == "." {
+			return false, nil
+		}
+		if matchAny(t.req.Exclude, rel) {
+			return fi.IsDir(), nil
+		}
+		switch {
+		case fi.IsDir():
+			if len(t.req.Include) == 0 {
+				dirs = append(dirs, dst.Join(dstRoot, rel))
+			}
+		case fi.Mode().IsRegular():
+			if len(t.req.Include) == 0 || matchAny(t.req.Include, rel) {
+				files = append(files, transferFile{rel: rel, src: p, dst: dst.Join(dstRoot, rel), size: fi.Size()})
+			}
+		}
+		// 符号链接、设备等特殊文件不传输
+		return false, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return dirs, files, nil
+}
+
+// copyFile 分块复制一个文件；Resume 时目标不长于源则从其末尾续传，否则从头覆盖
+func (t *Transfer) copyFile(src, dst fileSystem, f transferFile, buf []byte) error {
+	var offset int64
+	if t.req.Resume {
+		if st, err := dst.Stat(f.dst); err == nil && st.Mode().IsRegular() && st.Size() <= f.size {
+			offset = st.Size()
+		}
+	}
+	if offset > 0 {
+		t.mu.Lock()
+		t.info.Resumed += offset
+		t.info.Done += offset
+		t.mu.Unlock()
+		if offset == f.size {
+			return nil
+		}
+	}
+	r, err := src.Open(f.src)
+	if err != nil {
+		return pathError("open", f.src, err)
+	}
+	defer r.Close()
+	if offset > 0 {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return pathError("seek", f.src, err)
+		}
+	}
+	w, err := dst.Create(f.dst, offset)
+	if err != nil {
+		return pathError("create", f.dst, err)
+	}
+	for {
+		if err := t.ctx.Err(); err != nil {
+			_ = w.Close()
+			return err
+		}
+		n, rerr := r.Read(buf)
+		if n > 0 {
+			if _, err := w.Write(buf[:n]); err != nil {
+				_ = w.Close()
+				return pathError("write", f.dst, err)
+			}
+			t.progress(int64(n), false)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			_ = w.Close()
+			return pathError("read", f.src, rerr)
+		}
+	}
+	if err := w.Close(); err != nil {
+		return pathError("close", f.dst, err)
+	}
+	return nil
+}
+
+// verify 比对两端的 SHA-256，返回校验和
+func (t *Transfer) verify(c *sftp.Client, f transferFile) (string, error) {
+	local, remote := f.src, f.dst
+	if t.req.Direction == proto.TransferDownload {
+		local, remote = remote, local
+	}
+	ls, err := localFS{}.Sum(t.ctx, local)
+	if err != nil {
+		return "", fmt.Errorf("checksum %s: %w", local, err)
+	}
+	rs, err := t.m.remoteSum(t.ctx, t.req.ConnID, c, remote)
+	if err != nil {
+		return "", fmt.Errorf("checksum %s: %w", remote, err)
+	}
+	if ls != rs {
+		return "", fmt.Errorf("checksum mismatch: local %s, remote %s", ls, rs)
+	}
+	return ls, nil
+}
+
+// remoteSum 优先在远端执行 sha256sum，远端没有该命令时经 SFTP 读回计算
+func (m *Manager) remoteSum(ctx context.Context, connID string, c *sftp.Client, p string) (string, error) {
+	if client, err := m.Client(connID); err == nil {
+		if session, err := client.NewSession(); err == nil {
+			untrack := m.track(session)
+			stop := context.AfterFunc(ctx, func() { session.Close() })
+			out, err := session.Output("sha256sum -- " + shellQuote(p))
+			stop()
+			session.Close()
+			untrack()
+			if fields := bytes.Fields(out); err == nil && len(fields) > 0 && len(fields[0]) == sha256.Size*2 {
+				return string(fields[0]), nil
+			}
+		}
+	}
+	return remoteFS{c}.Sum(ctx, p)
+}
+
+// progress 累加已传输字节数，距上次推送超过 transferProgressInterval 或 force 时推送进度
+func (t *Transfer) progress(n int64, force bool) {
+	t.mu.Lock()
+	t.info.Done += n
+	now := time.Now()
+	elapsed := now.Sub(t.lastAt)
+	if !force && elapsed < transferProgressInterval {
+		t.mu.Unlock()
+		return
+	}
+	if elapsed > 0 {
+		t.info.BytesPerSec = int64(float64(t.info.Done-t.lastDone) / elapsed.Seconds())
+	}
+	t.lastAt, t.lastDone = now, t.info.Done
+	info := t.info
+	t.mu.Unlock()
+	t.m.publishTransfer(info)
+}
+
+// normalizeTransfer 校验方向、路径与过滤规则，本机路径展开 ~ 并要求为绝对路径
+func normalizeTransfer(req *proto.TransferRequest) error {
+	if req.Direction != proto.TransferUpload && req.Direction != proto.TransferDownload {
+		return fmt.Errorf("%w: direction must be %q or %q", ErrInvalid, proto.TransferUpload, proto.TransferDownload)
+	}
+	req.LocalPath = strings.TrimSpace(req.LocalPath)
+	req.RemotePath = strings.TrimSpace(req.RemotePath)
+	if req.LocalPath == "" {
+		return fmt.Errorf("%w: localPath is required", ErrInvalid)
+	}
+	if req.Direction == proto.TransferDownload && req.RemotePath == "" {
+		return fmt.Errorf("%w: remotePath is required", ErrInvalid)
+	}
+	if req.LocalPath == "~" || strings.HasPrefix(req.LocalPath, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return fmt.Errorf("%w: expand ~: %v", ErrInvalid, err)
+		}
+		req.LocalPath = filepath.Join(home, req.LocalPath[1:])
+	}
+	if !filepath.IsAbs(req.LocalPath) {
+		return fmt.Errorf("%w: localPath must be absolute", ErrInvalid)
+	}
+	for _, p := range append(append([]string(nil), req.Include...), req.Exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("%w: bad glob %q", ErrInvalid, p)
+		}
+	}
+	return nil
+}
+
+// matchAny 判断相对路径或其文件名是否匹配任一 glob
+func matchAny(patterns []string, rel string) bool {
+	base := path.Base(rel)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fileSystem 传输两端的文件操作，本机为 os，远端为 SFTP
+type fileSystem interface {
+	Stat(p string) (os.FileInfo, error)
+	Open(p string) (io.ReadSeekCloser, error)
+	// Create 打开目标文件写入：offset 为 0 时创建或截断，否则从 offset 处续写
+	Create(p string, offset int64) (io.WriteCloser, error)
+	MkdirAll(p string) error
+	// Walk 先序遍历目录树，fn 返回 skip 时跳过该目录
+	Walk(root string, fn func(p string, fi os.FileInfo) (skip bool, err error)) error
+	Sum(ctx context.Context, p string) (string, error)
+	Join(elem ...string) string
+	Dir(p string) string
+	Rel(root, p string) string // 相对 root 的路径，/ 分隔
+	IsDirHint(p string) bool   // 路径以分隔符结尾，表示目录
+}
+
+type localFS struct{}
+
+func (localFS) Stat(p string) (os.FileInfo, error)       { return os.Stat(p) }
+func (localFS) Open(p string) (io.ReadSeekCloser, error) { return os.Open(p) }
+func (localFS) MkdirAll(p string) error                  { return os.MkdirAll(p, 0o755) }
+func (localFS) Join(elem ...string) string               { return filepath.Join(elem...) }
+func (localFS) Dir(p string) string                      { return filepath.Dir(p) }
+
+func (localFS) Create(p string, offset int64) (io.WriteCloser, error) {
+	if offset == 0 {
+		return os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	}
+	f, err := os.OpenFile(p, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (localFS) Walk(root string, fn func(string, os.FileInfo) (bool, error)) error {
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		skip, err := fn(p, fi)
+		if err == nil && skip {
+			return filepath.SkipDir
+		}
+		return err
+	})
+}
+
+func (localFS) Sum(ctx context.Context, p string) (string, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return sumReader(ctx, f)
+}
+
+func (localFS) Rel(root, p string) string {
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return filepath.Base(p)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (localFS) IsDirHint(p string) bool {
+	return strings.HasSuffix(p, string(filepath.Separator)) || strings.HasSuffix(p, "/")
+}
+
+type remoteFS struct{ c *sftp.Client }
+
+func (r remoteFS) Stat(p string) (os.FileInfo, error)       { return r.c.Stat(p) }
+func (r remoteFS) Open(p string) (io.ReadSeekCloser, error) { return r.c.Open(p) }
+func (r remoteFS) MkdirAll(p string) error                  { return r.c.MkdirAll(p) }
+func (remoteFS) Join(elem ...string) string                 { return path.Join(elem...) }
+func (remoteFS) Dir(p string) string                        { return path.Dir(p) }
+func (remoteFS) IsDirHint(p string) bool                    { return strings.HasSuffix(p, "/") }
+
+func (r remoteFS) Create(p string, offset int64) (io.WriteCloser, error) {
+	if offset == 0 {
+		return r.c.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	}
+	f, err := r.c.OpenFile(p, os.O_WRONLY)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+func (r remoteFS) Walk(root string, fn func(string, os.FileInfo) (bool, error)) error {
+	w := r.c.Walk(root)
+	for w.Step() {
+		if err := w.Err(); err != nil {
+			return err
+		}
+		skip, err := fn(w.Path(), w.Stat())
+		if err != nil {
+			return err
+		}
+		if skip {
+			w.SkipDir()
+		}
+	}
+	return nil
+}
+
+func (r remoteFS) Sum(ctx context.Context, p string) (string, error) {
+	f, err := r.c.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return sumReader(ctx, f)
+}
+
+func (remoteFS) Rel(root, p string) string {
+	if p == root {
+		return "."
+	}
+	return strings.TrimPrefix(p, strings.TrimSuffix(root, "/")+"/")
+}
+
+// pathError 为错误补充操作与路径；本机文件错误（*fs.PathError）已含路径，原样返回
+func pathError(op, p string, err error) error {
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return err
+	}
+	return fmt.Errorf("%s %s: %w", op, p, err)
+}
+
+// sumReader 计算 SHA-256，ctx 取消时中止
+func sumReader(ctx context.Context, r io.Reader) (string, error) {
+	h := sha256.New()
+	buf := make([]byte, DefaultTransferChunkSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		n, err := r.Read(buf)
+		h.Write(buf[:n])
+		if err == io.EOF {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+		if err != nil {
+			return "", err
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package ssh
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-ssh/proto"
+)
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		rel      string
+		want     bool
+	}{
+		{[]string{"*.log"}, "app.log", true},
+		{[]string{"*.log"}, "logs/app.log", true}, // 按文件名匹配
+		{[]string{"*.log"}, "app.log.1", false},
+		{[]string{"logs/*"}, "logs/app.log", true},
+		{[]string{"logs/*"}, "logs/old/app.log", false}, // * 不跨越 /
+		{[]string{"logs/*"}, "other/logs", false},
+		{[]string{"node_modules"}, "web/node_modules", true},
+		{[]string{"*.tmp", "*.bak"}, "a/b.bak", true},
+		{[]string{"?.go"}, "a.go", true},
+		{[]string{"[ab].txt"}, "c.txt", false},
+		{nil, "a", false},
+	}
+	for _, tt := range tests {
+		if got := matchAny(tt.patterns, tt.rel); got != tt.want {
+			t.Errorf("matchAny(%q, %q) = %v, want %v", tt.patterns, tt.rel, got, tt.want)
+		}
+	}
+}
+
+// writeTree 在 root 下创建文件（内容为其相对路径）与空目录（以 / 结尾）
+func writeTree(t *testing.T, root string, paths ...string) {
+	t.Helper()
+	for _, p := range paths {
+		full := filepath.Join(root, filepath.FromSlash(p))
+		if strings.HasSuffix(p, "/") {
+			if err := os.MkdirAll(full, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte(p), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	src := t.TempDir()
+	writeTree(t, src, "a.txt", "b.log", "logs/app.log", "logs/old/x.log", "web/node_modules/m.js", "web/index.html", "empty/")
+	dst := t.TempDir()
+	writeTree(t, dst, "existing/")
+
+	tests := []struct {
+		name      string
+		src, dst  string
+		include   []string
+		exclude   []string
+		wantDirs  []string // 相对 dst
+		wantFiles []string // rel -> 相对 dst 的目标，以 = 连接
+	}{
+		{
+			name: "file into existing directory",
+			src:  "a.txt", dst: "existing",
+			wantFiles: []string{"a.txt=existing/a.txt"},
+		},
+		{
+			name: "file into directory hint",
+			src:  "a.txt", dst: "new/",
+			wantFiles: []string{"a.txt=new/a.txt"},
+		},
+		{
+			name: "file renamed",
+			src:  "a.txt", dst: "renamed.txt",
+			wantFiles: []string{"a.txt=renamed.txt"},
+		},
+		{
+			name: "directory with empty dirs",
+			src:  ".", dst: "out",
+			wantDirs: []string{"out", "out/empty", "out/logs", "out/logs/old", "out/web", "out/web/node_modules"},
+			wantFiles: []string{
+				"a.txt=out/a.txt", "b.log=out/b.log", "logs/app.log=out/logs/app.log",
+				"logs/old/x.log=out/logs/old/x.log", "web/index.html=out/web/index.html",
+				"web/node_modules/m.js=out/web/node_modules/m.js",
+			},
+		},
+		{
+			name: "exclude prunes directories",
+			src:  ".", dst: "out",
+			exclude:  []string{"node_modules", "old"},
+			wantDirs: []string{"out", "out/empty", "out/logs", "out/web"},
+			wantFiles: []string{
+				"a.txt=out/a.txt", "b.log=out/b.log", "logs/app.log=out/logs/app.log",
+				"web/index.html=out/web/index.html",
+			},
+		},
+		{
+			name: "include keeps matching files only",
+			src:  ".", dst: "out",
+			include:   []string{"*.log"},
+			exclude:   []string{"old"},
+			wantDirs:  []string{"out"},
+			wantFiles: []string{"b.log=out/b.log", "logs/app.log=out/logs/app.log"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Transfer{m: NewManager(), ctx: context.Background(), req: proto.TransferRequest{Include: tt.include, Exclude: tt.exclude}}
+			dstPath := filepath.Join(dst, tt.dst)
+			if strings.HasSuffix(tt.dst, "/") {
+				dstPath += string(filepath.Separator)
+			}
+			dirs, files, err := tr.plan(localFS{}, localFS{}, filepath.Join(src, tt.src), dstPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var gotDirs, gotFiles []string
+			for _, d := range dirs {
+				gotDirs = append(gotDirs, localFS{}.Rel(dst, d))
+			}
+			for _, f := range files {
+				gotFiles = append(gotFiles, f.rel+"="+localFS{}.Rel(dst, f.dst))
+				if f.size != int64(len(f.rel)) {
+					t.Errorf("%s: size %d, want %d", f.rel, f.size, len(f.rel))
+				}
+			}
+			slices.Sort(gotDirs)
+			slices.Sort(gotFiles)
+			if !slices.Equal(gotDirs, tt.wantDirs) {
+				t.Errorf("dirs = %q, want %q", gotDirs, tt.wantDirs)
+			}
+			if !slices.Equal(gotFiles, tt.wantFiles) {
+				t.Errorf("files = %q, want %q", gotFiles, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestCopyFileResume(t *testing.T) {
+	const content = "0123456789abcdef"
+	tests := []struct {
+		name        string
+		resume      bool
+		existing    *string // 目标已有内容，nil 表示不存在
+		wantResumed int64
+	}{
+		{"fresh", true, nil, 0},
+		{"partial", true, ptr("0123456"), 7},
+		{"complete", true, ptr(content), int64(len(content))},
+		{"longer target restarts", true, ptr(content + "junk"), 0},
+		{"no resume overwrites", false, ptr("0123456"), 0},
+		// 续传只比较长度：已有前缀与源不同时保留原字节，由 verify 发现
+		{"diverged prefix", true, ptr("XYZ"), 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src, dst := filepath.Join(dir, "src"), filepath.Join(dir, "dst")
+			if err := os.WriteFile(src, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if tt.existing != nil {
+				if err := os.WriteFile(dst, []byte(*tt.existing), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			tr := &Transfer{m: NewManager(), ctx: context.Background(), req: proto.TransferRequest{Resume: tt.resume}}
+			f := transferFile{rel: "src", src: src, dst: dst, size: int64(len(content))}
+			if err := tr.copyFile(localFS{}, localFS{}, f, make([]byte, 4)); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := content
+			if tt.existing != nil && tt.wantResumed > 0 {
+				want = *tt.existing + content[tt.wantResumed:]
+			}
+			if string(got) != want {
+				t.Errorf("target = %q, want %q", got, want)
+			}
+			if tr.info.Resumed != tt.wantResumed || tr.info.Done != int64(len(content)) {
+				t.Errorf("resumed %d done %d, want %d and %d", tr.info.Resumed, tr.info.Done, tt.wantResumed, len(content))
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
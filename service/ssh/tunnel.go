@@ -0,0 +1,453 @@
+package ssh
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-ssh/proto"
+
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// ErrTunnelNotFound 表示指定 ID 的转发不存在
+var ErrTunnelNotFound = errors.New("tunnel not found")
+
+// tunnelSeq 用于生成进程内唯一的转发 ID
+var tunnelSeq atomic.Uint64
+
+// tunnelStatsInterval 流量统计推送的最小间隔
+const tunnelStatsInterval = time.Second
+
+// Tunnel 一个 -L / -R / -D 端口转发。监听器由本机（local / dynamic）或远端（remote）持有，
+// 每个接入连接经 SSH 连接与目标双向转发。
+type Tunnel struct {
+	info   proto.TunnelInfo // 配置字段，创建后不变
+	m      *Manager
+	client *gossh.Client
+	ln     net.Listener
+
+	active   atomic.Int32
+	total    atomic.Int64
+	failed   atomic.Int64
+	bytesOut atomic.Int64
+	bytesIn  atomic.Int64
+	dirty    atomic.Bool // 统计有变化，等待下一次推送
+
+	mu      sync.Mutex
+	conns   map[net.Conn]struct{} // 转发中的连接，关闭转发时一并关闭
+	err     string
+	closed  chan struct{}
+	once    sync.Once
+	untrack func()
+}
+
+// OpenTunnel 在 req.ConnID 对应的连接上创建端口转发并开始接受连接
+func (m *Manager) OpenTunnel(req proto.TunnelRequest) (proto.TunnelInfo, error) {
+	if err := normalizeTunnel(&req); err != nil {
+		return proto.TunnelInfo{}, err
+	}
+	client, err := m.Client(req.ConnID)
+	if err != nil {
+		return proto.TunnelInfo{}, err
+	}
+	bind := net.JoinHostPort(req.BindHost, strconv.Itoa(req.BindPort))
+	var ln net.Listener
+	if req.Kind == proto.TunnelRemote {
+		ln, err = client.Listen("tcp", bind)
+	} else {
+		ln, err = net.Listen("tcp", bind)
+	}
+	if err != nil {
+		return proto.TunnelInfo{}, fmt.Errorf("listen %s (%s): %w", bind, req.Kind, err)
+	}
+	if a, ok := ln.Addr().(*net.TCPAddr); ok {
+		req.BindPort = a.Port
+	}
+
+	t := &Tunnel{
+		info: proto.TunnelInfo{
+			ID:         fmt.Sprintf("%s-t%d", req.ConnID, tunnelSeq.Add(1)),
+			ConnID:     req.ConnID,
+			Kind:       req.Kind,
+			BindHost:   req.BindHost,
+			BindPort:   req.BindPort,
+			TargetHost: req.TargetHost,
+			TargetPort: req.TargetPort,
+			CreatedAt:  time.Now().UTC(),
+		},
+		m:      m,
+		client: client,
+		ln:     ln,
+		conns:  make(map[net.Conn]struct{}),
+		closed: make(chan struct{}),
+	}
+	t.untrack = m.track(t)
+	m.mu.Lock()
+	m.tunnels[t.info.ID] = t
+	m.mu.Unlock()
+
+	go t.serve()
+	go t.report()
+	go func() {
+		// 底层连接断开（断开、重连或服务端关闭）时转发随之失效
+		_ = client.Wait()
+		t.close("connection closed")
+	}()
+	log.Printf("tunnel %s opened: %s %s -> %s", t.info.ID, t.info.Kind, bind, t.target())
+	info := t.Info()
+	m.publishTunnel(info)
+	return info, nil
+}
+
+// CloseTunnel 关闭并移除转发，返回关闭时的统计
+func (m *Manager) CloseTunnel(id string) (proto.TunnelInfo, error) {
+	m.mu.Lock()
+	t, ok := m.tunnels[id]
+	m.mu.Unlock()
+	if !ok {
+		return proto.TunnelInfo{}, fmt.Errorf("%w: %s", ErrTunnelNotFound, id)
+	}
+	t.close("")
+	return t.Info(), nil
+}
+
+// Tunnels 返回转发列表（connID 非空时只含该连接上的），按创建顺序排序
+func (m *Manager) Tunnels(connID string) []proto.TunnelInfo {
+	m.mu.Lock()
+	var ts []*Tunnel
+	for _, t := range m.tunnels {
+		if connID == "" || t.info.ConnID == connID {
+			ts = append(ts, t)
+		}
+	}
+	m.mu.Unlock()
+	out := make([]proto.TunnelInfo, 0, len(ts))
+	for _, t := range ts {
+		out = append(out, t.Info())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+func (m *Manager) publishTunnel(info proto.TunnelInfo) {
+	if m.Publish != nil {
+		m.Publish(proto.EventTunnelStats, info)
+	}
+}
+
+// normalizeTunnel 校验转发参数并填充默认监听地址
+func normalizeTunnel(req *proto.TunnelRequest) error {
+	switch req.Kind {
+	case proto.TunnelLocal, proto.TunnelRemote:
+		if req.TargetHost == "" || req.TargetPort <= 0 || req.TargetPort > 65535 {
+			return fmt.Errorf("%w: %s forward requires targetHost and targetPort", ErrInvalid, req.Kind)
+		}
+	case proto.TunnelDynamic:
+		req.TargetHost, req.TargetPort = "", 0
+	default:
+		return fmt.Errorf("%w: unknown tunnel kind %q", ErrInvalid, req.Kind)
+	}
+	if req.ConnID == "" {
+		return fmt.Errorf("%w: connId is required", ErrInvalid)
+	}
+	if req.BindPort < 0 || req.BindPort > 65535 {
+		return fmt.Errorf("%w: bindPort %d out of range", ErrInvalid, req.BindPort)
+	}
+	if req.BindHost == "" {
+		req.BindHost = "127.0.0.1"
+	}
+	return nil
+}
+
+// Info 返回转发当前的配置与统计
+func (t *Tunnel) Info() proto.TunnelInfo {
+	info := t.info
+	info.Active = int(t.active.Load())
+	info.Total = t.total.Load()
+	info.Failed = t.failed.Load()
+	info.BytesOut = t.bytesOut.Load()
+	info.BytesIn = t.bytesIn.Load()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info.State = proto.TunnelActive
+	select {
+	case <-t.closed:
+		info.State = proto.TunnelClosed
+		info.Error = t.err
+	default:
+	}
+	return info
+}
+
+// Close 关闭转发，实现 io.Closer 以便随 Manager.CloseAll 释放
+func (t *Tunnel) Close() error {
+	t.close("")
+	return nil
+}
+
+// close 停止监听并断开全部转发中的连接；reason 非空表示非主动关闭
+func (t *Tunnel) close(reason string) {
+	t.once.Do(func() {
+		_ = t.ln.Close()
+		t.mu.Lock()
+		t.err = reason
+		close(t.closed)
+		conns := t.conns
+		t.conns = nil
+		t.mu.Unlock()
+		for c := range conns {
+			_ = c.Close()
+		}
+		t.untrack()
+		t.m.mu.Lock()
+		delete(t.m.tunnels, t.info.ID)
+		t.m.mu.Unlock()
+		if reason != "" {
+			log.Printf("tunnel %s closed: %s", t.info.ID, reason)
+		} else {
+			log.Printf("tunnel %s closed", t.info.ID)
+		}
+		t.m.publishTunnel(t.Info())
+	})
+}
+
+// serve 接受连接直到监听器关闭
+func (t *Tunnel) serve() {
+	for {
+		c, err := t.ln.Accept()
+		if err != nil {
+			reason := fmt.Sprintf("accept: %v", err)
+			if errors.Is(err, io.EOF) {
+				// remote 监听器随 SSH 连接关闭而结束
+				reason = "connection closed"
+			}
+			t.close(reason)
+			return
+		}
+		go t.handle(c)
+	}
+}
+
+// report 统计有变化时按 tunnelStatsInterval 推送 tunnel_stats
+func (t *Tunnel) report() {
+	tick := time.NewTicker(tunnelStatsInterval)
+	defer tick.Stop()
+	for {
+		select {
+		case <-t.closed:
+			return
+		case <-tick.C:
+			if t.dirty.Swap(false) {
+				t.m.publishTunnel(t.Info())
+			}
+		}
+	}
+}
+
+// handle 为一个接入连接拨号目标并双向转发
+func (t *Tunnel) handle(c net.Conn) {
+	if !t.hold(c) {
+		_ = c.Close()
+		return
+	}
+	defer t.release(c)
+	t.total.Add(1)
+	t.active.Add(1)
+	t.dirty.Store(true)
+	defer func() {
+		t.active.Add(-1)
+		t.dirty.Store(true)
+	}()
+
+	target := t.target()
+	if t.info.Kind == proto.TunnelDynamic {
+		var err error
+		if target, err = socks5Accept(c); err != nil {
+			log.Printf("tunnel %s: socks5: %v", t.info.ID, err)
+			t.failed.Add(1)
+			return
+		}
+	}
+	var dst net.Conn
+	var err error
+	if t.info.Kind == proto.TunnelRemote {
+		dst, err = net.DialTimeout("tcp", target, 10*time.Second)
+	} else {
+		dst, err = t.client.Dial("tcp", target)
+	}
+	if t.info.Kind == proto.TunnelDynamic {
+		code := byte(socksSucceeded)
+		if err != nil {
+			code = socksHostUnreachable
+		}
+		if rerr := socks5Reply(c, code); rerr != nil && err == nil {
+			err = rerr
+		}
+	}
+	if err != nil {
+		log.Printf("tunnel %s: dial %s: %v", t.info.ID, target, err)
+		t.failed.Add(1)
+		if dst != nil {
+			_ = dst.Close()
+		}
+		return
+	}
+	if !t.hold(dst) {
+		_ = dst.Close()
+		return
+	}
+	defer t.release(dst)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(&countWriter{w: dst, n: &t.bytesOut, dirty: &t.dirty}, c)
+		closeWrite(dst)
+	}()
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(&countWriter{w: c, n: &t.bytesIn, dirty: &t.dirty}, dst)
+		closeWrite(c)
+	}()
+	wg.Wait()
+}
+
+// hold 登记转发中的连接；转发已关闭时返回 false
+func (t *Tunnel) hold(c net.Conn) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.conns == nil {
+		return false
+	}
+	t.conns[c] = struct{}{}
+	return true
+}
+
+func (t *Tunnel) release(c net.Conn) {
+	_ = c.Close()
+	t.mu.Lock()
+	delete(t.conns, c)
+	t.mu.Unlock()
+}
+
+func (t *Tunnel) target() string {
+	if t.info.Kind == proto.TunnelDynamic {
+		return "socks5"
+	}
+	return net.JoinHostPort(t.info.TargetHost, strconv.Itoa(t.info.TargetPort))
+}
+
+// closeWrite 半关闭写方向，让对端读到 EOF；不支持时直接关闭
+func closeWrite(c net.Conn) {
+	if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		_ = cw.CloseWrite()
+		return
+	}
+	_ = c.Close()
+}
+
+// countWriter 累计写入的字节数
+type countWriter struct {
+	w     io.Writer
+	n     *atomic.Int64
+	dirty *atomic.Bool
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if n > 0 {
+		w.n.Add(int64(n))
+		w.dirty.Store(true)
+	}
+	return n, err
+}
+
+// SOCKS5 应答码（RFC 1928）
+const (
+	socksSucceeded          = 0x00
+	socksHostUnreachable    = 0x04
+	socksCommandUnsupported = 0x07
+	socksAddrUnsupported    = 0x08
+)
+
+// socks5Accept 完成 SOCKS5 无认证握手并读取 CONNECT 请求的目标地址；
+// 不支持的请求已向客户端应答错误
+func socks5Accept(c net.Conn) (string, error) {
+	_ = c.SetDeadline(time.Now().Add(10 * time.Second))
+	defer c.SetDeadline(time.Time{})
+
+	hdr := make([]byte, 2)
+	if _, err := io.ReadFull(c, hdr); err != nil {
+		return "", err
+	}
+	if hdr[0] != 5 {
+		return "", fmt.Errorf("unsupported version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(c, methods); err != nil {
+		return "", err
+	}
+	if !bytes.Contains(methods, []byte{0}) {
+		_, _ = c.Write([]byte{5, 0xff})
+		return "", errors.New("no acceptable auth method")
+	}
+	if _, err := c.Write([]byte{5, 0}); err != nil {
+		return "", err
+	}
+
+	req := make([]byte, 4) // VER CMD RSV ATYP
+	if _, err := io.ReadFull(c, req); err != nil {
+		return "", err
+	}
+	if req[1] != 1 {
+		_ = socks5Reply(c, socksCommandUnsupported)
+		return "", fmt.Errorf("unsupported command %d", req[1])
+	}
+	var host string
+	switch req[3] {
+	case 1, 4:
+		ip := make([]byte, 4)
+		if req[3] == 4 {
+			ip = make([]byte, 16)
+		}
+		if _, err := io.ReadFull(c, ip); err != nil {
+			return "", err
+		}
+		host = net.IP(ip).String()
+	case 3:
+		n := make([]byte, 1)
+		if _, err := io.ReadFull(c, n); err != nil {
+			return "", err
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(c, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		_ = socks5Reply(c, socksAddrUnsupported)
+		return "", fmt.Errorf("unsupported address type %d", req[3])
+	}
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(c, port); err != nil {
+		return "", err
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// socks5Reply 应答 CONNECT 请求；绑定地址固定为 0.0.0.0:0
+func socks5Reply(c net.Conn, code byte) error {
+	_, err := c.Write([]byte{5, code, 0, 1, 0, 0, 0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,61 @@
+package ssh
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestSocks5Accept(t *testing.T) {
+	greeting := []byte{5, 1, 0} // VER NMETHODS 无认证
+	connect := func(atyp byte, addr ...byte) []byte {
+		return append([]byte{5, 1, 0, atyp}, addr...)
+	}
+	refused := func(code byte) []byte {
+		return []byte{5, 0, 5, code, 0, 1, 0, 0, 0, 0, 0, 0}
+	}
+	tests := []struct {
+		name      string
+		in        []byte
+		want      string // 为空表示应返回错误
+		wantReply []byte
+	}{
+		{"ipv4", append(greeting, connect(1, 127, 0, 0, 1, 0, 80)...), "127.0.0.1:80", []byte{5, 0}},
+		{"domain", append([]byte{5, 2, 2, 0}, connect(3, append([]byte{11}, append([]byte("example.com"), 1, 187)...)...)...), "example.com:443", []byte{5, 0}},
+		{"ipv6", append(greeting, connect(4, append(net.IPv6loopback, 0, 22)...)...), "[::1]:22", []byte{5, 0}},
+		{"wrong version", []byte{4, 1, 0}, "", nil},
+		{"no acceptable method", []byte{5, 1, 2}, "", []byte{5, 0xff}},
+		{"bind command", append(greeting, 5, 2, 0, 1, 127, 0, 0, 1, 0, 80), "", refused(socksCommandUnsupported)},
+		{"udp associate", append(greeting, 5, 3, 0, 1, 127, 0, 0, 1, 0, 80), "", refused(socksCommandUnsupported)},
+		{"unknown address type", append(greeting, connect(9)...), "", refused(socksAddrUnsupported)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := net.Pipe()
+			defer client.Close()
+			// net.Pipe 没有缓冲：写与读分别在各自的协程中进行，服务端返回后关闭连接结束读取
+			go func() { _, _ = client.Write(tt.in) }()
+			replies := make(chan []byte, 1)
+			go func() {
+				b, _ := io.ReadAll(client)
+				replies <- b
+			}()
+
+			got, err := socks5Accept(server)
+			server.Close()
+			reply := <-replies
+
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("socks5Accept = %q, want error", got)
+				}
+			} else if err != nil || got != tt.want {
+				t.Fatalf("socks5Accept = %q, %v; want %q", got, err, tt.want)
+			}
+			if !bytes.Equal(reply, tt.wantReply) {
+				t.Fatalf("reply = %v, want %v", reply, tt.wantReply)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package vault
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"go-ssh/proto"
+	"go-ssh/service/server"
+)
+
+// Register 注册凭据库的状态、初始化、解锁、锁定与修改主密码消息处理器
+func (v *Vault) Register(r *server.Router) {
+	r.Handle("vault_status", v.handleStatus)
+	r.Handle("vault_init", v.handleInit)
+	r.Handle("vault_unlock", v.handleUnlock)
+	r.Handle("vault_lock", v.handleLock)
+	r.Handle("vault_change_master", v.handleChangeMaster)
+}
+
+func (v *Vault) handleStatus(context.Context, proto.Message) (proto.Response, error) {
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: v.Status()}, nil
+}
+
+func (v *Vault) handleInit(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.VaultPasswordRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	st, err := v.Init(req.Password)
+	if err != nil {
+		return errorResponse(err)
+	}
+	log.Printf("vault initialized")
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: st}, nil
+}
+
+func (v *Vault) handleUnlock(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.VaultPasswordRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	st, err := v.Unlock(req.Password)
+	if err != nil {
+		log.Printf("vault unlock failed: %v", err)
+		return errorResponse(err)
+	}
+	log.Printf("vault unlocked")
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: st}, nil
+}
+
+func (v *Vault) handleLock(context.Context, proto.Message) (proto.Response, error) {
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: v.Lock()}, nil
+}
+
+func (v *Vault) handleChangeMaster(_ context.Context, msg proto.Message) (proto.Response, error) {
+	var req proto.VaultChangeMasterRequest
+	if err := server.Decode(msg, &req); err != nil {
+		return server.BadRequest(err), nil
+	}
+	st, err := v.ChangeMaster(req.Old, req.New)
+	if err != nil {
+		return errorResponse(err)
+	}
+	log.Printf("vault master password changed")
+	return proto.Response{Ok: true, Code: proto.CodeOK, Data: st}, nil
+}
+
+// errorResponse 将凭据库错误映射为响应码，其它错误交由路由按 500 处理
+func errorResponse(err error) (proto.Response, error) {
+	switch {
+	case errors.Is(err, ErrInvalid):
+		return server.BadRequest(err), nil
+	case errors.Is(err, ErrWrongPassword):
+		return proto.Response{Ok: false, Code: proto.CodeForbidden, Message: err.Error()}, nil
+	case errors.Is(err, ErrLocked):
+		return LockedResponse(err), nil
+	}
+	return proto.Response{}, err
+}
+
+// LockedResponse 凭据库锁定时的统一响应，供其它子系统复用
+func LockedResponse(err error) proto.Response {
+	return proto.Response{Ok: false, Code: proto.CodeLocked, Message: err.Error()}
+}
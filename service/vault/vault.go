@@ -0,0 +1,459 @@
+package vault
+
+// Vault 以主密码加密保存凭据（连接密码、私钥口令）：主密码经 Argon2id 派生密钥，
+// 用于包裹随机生成的数据密钥；每条凭据以数据密钥 AES-256-GCM 加密，凭据 ID 作为附加数据。
+// 修改主密码只需重新包裹数据密钥。解锁后数据密钥仅保存在内存中，空闲超时后自动锁定。
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go-ssh/proto"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// FileVersion 为 vault.json 的格式版本
+const FileVersion = 1
+
+// ErrLocked 表示凭据库未初始化或已锁定
+var ErrLocked = errors.New("vault is locked")
+
+// ErrWrongPassword 表示主密码错误
+var ErrWrongPassword = errors.New("wrong master password")
+
+// ErrInvalid 表示请求参数不合法
+var ErrInvalid = errors.New("invalid request")
+
+// ErrNotFound 表示凭据不存在
+var ErrNotFound = errors.New("secret not found")
+
+// keyAAD 包裹数据密钥时的附加数据
+var keyAAD = []byte("go-ssh vault data key")
+
+// kdfParams Argon2id 参数，随文件保存以便日后调整
+type kdfParams struct {
+	Salt    []byte `json:"salt"`
+	Time    uint32 `json:"time"`
+	Memory  uint32 `json:"memory"` // KiB
+	Threads uint8  `json:"threads"`
+}
+
+// sealed 一段 AES-GCM 密文
+type sealed struct {
+	Nonce []byte `json:"nonce"`
+	Data  []byte `json:"data"`
+}
+
+type entry struct {
+	Label string `json:"label,omitempty"`
+	sealed
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// fileData 为 vault.json 的磁盘格式
+type fileData struct {
+	Version int              `json:"version"`
+	KDF     kdfParams        `json:"kdf"`
+	Key     sealed           `json:"key"` // 以主密码派生密钥包裹的数据密钥
+	Secrets map[string]entry `json:"secrets"`
+}
+
+// Vault 管理数据目录下的 vault.json
+type Vault struct {
+	// IdleTimeout 解锁后空闲多久自动锁定，0 表示不自动锁定
+	IdleTimeout time.Duration
+	// Publish 状态变化时回调（通常接入事件总线），可为空
+	Publish func(event string, data any)
+	// OnUnlock 每次解锁（含初始化）后在新协程中调用，可为空
+	OnUnlock func()
+
+	path     string
+	mu       sync.Mutex
+	data     *fileData // 未初始化时为 nil
+	key      []byte    // 数据密钥，锁定时为 nil
+	lastUsed time.Time
+	timer    *time.Timer
+}
+
+// Open 打开（不存在时不创建）dir 下的凭据库，初始状态为锁定
+func Open(dir string) (*Vault, error) {
+	v := &Vault{path: filepath.Join(dir, "vault.json"), IdleTimeout: 15 * time.Minute}
+	b, err := os.ReadFile(v.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return v, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var d fileData
+	if err := json.Unmarshal(b, &d); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", v.path, err)
+	}
+	if d.Version > FileVersion {
+		return nil, fmt.Errorf("unsupported vault version %d (max %d)", d.Version, FileVersion)
+	}
+	if d.Secrets == nil {
+		d.Secrets = make(map[string]entry)
+	}
+	v.data = &d
+	return v, nil
+}
+
+// Status 返回当前状态
+func (v *Vault) Status() proto.VaultStatus {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.status("")
+}
+
+// Init 以主密码创建凭据库并保持解锁
+func (v *Vault) Init(password string) (proto.VaultStatus, error) {
+	if password == "" {
+		return proto.VaultStatus{}, fmt.Errorf("%w: password is required", ErrInvalid)
+	}
+	v.mu.Lock()
+	if v.data != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, fmt.Errorf("%w: vault already initialized", ErrInvalid)
+	}
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	d := &fileData{Version: FileVersion, Secrets: make(map[string]entry)}
+	if err := d.wrap(password, key); err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	if err := v.write(d); err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	v.data = d
+	v.unlocked(key)
+	st := v.status("init")
+	v.mu.Unlock()
+	v.changed(st)
+	return st, nil
+}
+
+// Unlock 以主密码解开数据密钥
+func (v *Vault) Unlock(password string) (proto.VaultStatus, error) {
+	v.mu.Lock()
+	if v.data == nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, fmt.Errorf("%w: not initialized", ErrLocked)
+	}
+	key, err := v.data.unwrap(password)
+	if err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	v.unlocked(key)
+	st := v.status("unlock")
+	v.mu.Unlock()
+	v.changed(st)
+	return st, nil
+}
+
+// Lock 立即锁定并清除内存中的数据密钥
+func (v *Vault) Lock() proto.VaultStatus {
+	return v.lock("lock")
+}
+
+// ChangeMaster 校验旧主密码后以新主密码重新包裹数据密钥
+func (v *Vault) ChangeMaster(oldPassword, newPassword string) (proto.VaultStatus, error) {
+	if newPassword == "" {
+		return proto.VaultStatus{}, fmt.Errorf("%w: new password is required", ErrInvalid)
+	}
+	v.mu.Lock()
+	if v.data == nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, fmt.Errorf("%w: not initialized", ErrLocked)
+	}
+	key, err := v.data.unwrap(oldPassword)
+	if err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	d := *v.data
+	if err := d.wrap(newPassword, key); err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	if err := v.write(&d); err != nil {
+		v.mu.Unlock()
+		return proto.VaultStatus{}, err
+	}
+	v.data = &d
+	v.unlocked(key)
+	st := v.status("change_master")
+	v.mu.Unlock()
+	v.changed(st)
+	return st, nil
+}
+
+// Get 解密并返回凭据
+func (v *Vault) Get(id string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", v.lockedErr()
+	}
+	e, ok := v.data.Secrets[id]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	plain, err := unseal(v.key, e.sealed, []byte(id))
+	if err != nil {
+		return "", fmt.Errorf("decrypt secret %s: %w", id, err)
+	}
+	v.lastUsed = time.Now()
+	return string(plain), nil
+}
+
+// Put 加密保存凭据：id 为空时新建，否则覆盖；返回凭据 ID
+func (v *Vault) Put(id, label, value string) (string, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.key == nil {
+		return "", v.lockedErr()
+	}
+	if id == "" {
+		id = newID()
+	}
+	s, err := seal(v.key, []byte(value), []byte(id))
+	if err != nil {
+		return "", err
+	}
+	d := *v.data
+	d.Secrets = make(map[string]entry, len(v.data.Secrets)+1)
+	for k, e := range v.data.Secrets {
+		d.Secrets[k] = e
+	}
+	d.Secrets[id] = entry{Label: label, sealed: s, UpdatedAt: time.Now().UTC()}
+	if err := v.write(&d); err != nil {
+		return "", err
+	}
+	v.data = &d
+	v.lastUsed = time.Now()
+	return id, nil
+}
+
+// Delete 删除凭据，锁定时同样可用；不存在时忽略
+func (v *Vault) Delete(ids ...string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.data == nil {
+		return nil
+	}
+	d := *v.data
+	d.Secrets = make(map[string]entry, len(v.data.Secrets))
+	for k, e := range v.data.Secrets {
+		d.Secrets[k] = e
+	}
+	n := len(d.Secrets)
+	for _, id := range ids {
+		delete(d.Secrets, id)
+	}
+	if len(d.Secrets) == n {
+		return nil
+	}
+	if err := v.write(&d); err != nil {
+		return err
+	}
+	v.data = &d
+	return nil
+}
+
+// Close 锁定凭据库，用于进程退出
+func (v *Vault) Close() error {
+	v.lock("lock")
+	return nil
+}
+
+// lockedErr 区分未初始化与已锁定，调用方持有 v.mu
+func (v *Vault) lockedErr() error {
+	if v.data == nil {
+		return fmt.Errorf("%w: not initialized", ErrLocked)
+	}
+	return ErrLocked
+}
+
+// lock 清除数据密钥并停止空闲计时；原本已锁定时不发布事件
+func (v *Vault) lock(reason string) proto.VaultStatus {
+	v.mu.Lock()
+	wasUnlocked := v.key != nil
+	clear(v.key)
+	v.key = nil
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+	st := v.status(reason)
+	v.mu.Unlock()
+	if wasUnlocked {
+		v.changed(st)
+	}
+	return st
+}
+
+// unlocked 保存数据密钥并启动空闲计时，调用方持有 v.mu
+func (v *Vault) unlocked(key []byte) {
+	if v.key != nil {
+		clear(v.key)
+	}
+	v.key = key
+	v.lastUsed = time.Now()
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+	}
+	if v.IdleTimeout > 0 {
+		v.timer = time.AfterFunc(v.IdleTimeout, v.idleCheck)
+	}
+	if v.OnUnlock != nil {
+		go v.OnUnlock()
+	}
+}
+
+// idleCheck 空闲超时则锁定，否则按剩余时间重新计时
+func (v *Vault) idleCheck() {
+	v.mu.Lock()
+	if v.key == nil || v.timer == nil {
+		v.mu.Unlock()
+		return
+	}
+	if remain := v.IdleTimeout - time.Since(v.lastUsed); remain > 0 {
+		v.timer.Reset(remain)
+		v.mu.Unlock()
+		return
+	}
+	v.mu.Unlock()
+	v.lock("idle")
+}
+
+// status 生成状态快照，调用方持有 v.mu
+func (v *Vault) status(reason string) proto.VaultStatus {
+	st := proto.VaultStatus{
+		Initialized: v.data != nil,
+		Locked:      v.key == nil,
+		IdleSeconds: int(v.IdleTimeout / time.Second),
+		Reason:      reason,
+	}
+	if v.data != nil {
+		st.Secrets = len(v.data.Secrets)
+	}
+	return st
+}
+
+func (v *Vault) changed(st proto.VaultStatus) {
+	if v.Publish != nil {
+		v.Publish(proto.EventVaultState, st)
+	}
+}
+
+// write 原子写入：先写同目录临时文件并 fsync，再重命名覆盖
+func (v *Vault) write(d *fileData) error {
+	b, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+	dir := filepath.Dir(v.path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "vault-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), v.path)
+}
+
+// wrap 生成新盐值，以主密码派生的密钥包裹数据密钥
+func (d *fileData) wrap(password string, key []byte) error {
+	d.KDF = kdfParams{Salt: make([]byte, 16), Time: 3, Memory: 64 * 1024, Threads: 4}
+	if _, err := rand.Read(d.KDF.Salt); err != nil {
+		return err
+	}
+	s, err := seal(d.KDF.derive(password), key, keyAAD)
+	if err != nil {
+		return err
+	}
+	d.Key = s
+	return nil
+}
+
+// unwrap 以主密码解开数据密钥，认证失败即主密码错误
+func (d *fileData) unwrap(password string) ([]byte, error) {
+	key, err := unseal(d.KDF.derive(password), d.Key, keyAAD)
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return key, nil
+}
+
+func (p kdfParams) derive(password string) []byte {
+	return argon2.IDKey([]byte(password), p.Salt, p.Time, p.Memory, p.Threads, 32)
+}
+
+func seal(key, plain, aad []byte) (sealed, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return sealed{}, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return sealed{}, err
+	}
+	return sealed{Nonce: nonce, Data: gcm.Seal(nil, nonce, plain, aad)}, nil
+}
+
+func unseal(key []byte, s sealed, aad []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	return gcm.Open(nil, s.Nonce, s.Data, aad)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// newID 生成 16 位十六进制随机 ID
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
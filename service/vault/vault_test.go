@@ -0,0 +1,194 @@
+package vault
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSealUnseal(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	other := bytes.Repeat([]byte{2}, 32)
+	aad := []byte("secret-id")
+
+	tests := []struct {
+		name   string
+		key    []byte
+		aad    []byte
+		tamper func(*sealed)
+		ok     bool
+	}{
+		{"round trip", key, aad, nil, true},
+		{"wrong key", other, aad, nil, false},
+		{"wrong aad", key, []byte("other-id"), nil, false},
+		{"flipped ciphertext byte", key, aad, func(s *sealed) { s.Data[0] ^= 1 }, false},
+		{"flipped tag byte", key, aad, func(s *sealed) { s.Data[len(s.Data)-1] ^= 1 }, false},
+		{"flipped nonce byte", key, aad, func(s *sealed) { s.Nonce[0] ^= 1 }, false},
+		{"truncated", key, aad, func(s *sealed) { s.Data = s.Data[:len(s.Data)-1] }, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := seal(key, []byte("hunter2"), aad)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(&s)
+			}
+			plain, err := unseal(tt.key, s, tt.aad)
+			if tt.ok {
+				if err != nil || string(plain) != "hunter2" {
+					t.Fatalf("unseal = %q, %v; want hunter2", plain, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("unseal succeeded with %q, want authentication failure", plain)
+			}
+		})
+	}
+}
+
+// readFile 与 writeFile 直接读写磁盘上的 vault.json，模拟篡改
+func readFile(t *testing.T, dir string) fileData {
+	t.Helper()
+	b, err := os.ReadFile(filepath.Join(dir, "vault.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d fileData
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func writeFile(t *testing.T, dir string, d fileData) {
+	t.Helper()
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "vault.json"), b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVaultReopen(t *testing.T) {
+	dir := t.TempDir()
+	v, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Init("master"); err != nil {
+		t.Fatal(err)
+	}
+	a, err := v.Put("", "a", "secret-a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := v.Put("", "b", "secret-b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		tamper   func(*fileData)
+		password string
+		unlock   error // Unlock 的预期错误
+		id       string
+		want     string
+		get      bool // Get 是否应成功
+	}{
+		{"round trip", nil, "master", nil, a, "secret-a", true},
+		{"wrong password", nil, "wrong", ErrWrongPassword, a, "", false},
+		{"tampered secret", func(d *fileData) {
+			e := d.Secrets[a]
+			e.Data[0] ^= 1
+			d.Secrets[a] = e
+		}, "master", nil, a, "", false},
+		{"swapped secrets", func(d *fileData) {
+			d.Secrets[a], d.Secrets[b] = d.Secrets[b], d.Secrets[a]
+		}, "master", nil, a, "", false},
+		{"untouched neighbour", func(d *fileData) {
+			e := d.Secrets[a]
+			e.Data[0] ^= 1
+			d.Secrets[a] = e
+		}, "master", nil, b, "secret-b", true},
+		{"tampered data key", func(d *fileData) { d.Key.Data[0] ^= 1 }, "master", ErrWrongPassword, a, "", false},
+	}
+	orig := readFile(t, dir)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := readFile(t, dir)
+			if tt.tamper != nil {
+				tt.tamper(&d)
+			}
+			writeFile(t, dir, d)
+			defer writeFile(t, dir, orig)
+
+			v, err := Open(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := v.Get(tt.id); !errors.Is(err, ErrLocked) {
+				t.Fatalf("Get before unlock = %v, want ErrLocked", err)
+			}
+			if _, err := v.Unlock(tt.password); !errors.Is(err, tt.unlock) {
+				t.Fatalf("Unlock = %v, want %v", err, tt.unlock)
+			}
+			if tt.unlock != nil {
+				return
+			}
+			got, err := v.Get(tt.id)
+			if tt.get {
+				if err != nil || got != tt.want {
+					t.Fatalf("Get = %q, %v; want %q", got, err, tt.want)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Get = %q, want decryption error", got)
+			}
+		})
+	}
+}
+
+func TestChangeMaster(t *testing.T) {
+	dir := t.TempDir()
+	v, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Init("old"); err != nil {
+		t.Fatal(err)
+	}
+	id, err := v.Put("", "", "value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.ChangeMaster("wrong", "new"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("ChangeMaster with wrong password = %v, want ErrWrongPassword", err)
+	}
+	if _, err := v.ChangeMaster("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err = Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Unlock("old"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("Unlock with old password = %v, want ErrWrongPassword", err)
+	}
+	if _, err := v.Unlock("new"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := v.Get(id); err != nil || got != "value" {
+		t.Fatalf("Get = %q, %v; want value", got, err)
+	}
+}